@@ -0,0 +1,96 @@
+// Command dicedump writes a large sample of rolls from the game's dice
+// generator for analysis by external test batteries, and can print the
+// results of the built-in rngtest suite on the same sample.
+//
+//	go run ./cmd/dicedump -n 10000000 -format bytes -out rolls.bin -report
+//
+// Formats:
+//
+//	lines  one face (1-6) per line, die 1 then die 2 of each roll
+//	csv    die1,die2 per line with a header
+//	bytes  one byte (1-6) per face, no separators
+package main
+
+import (
+	"bufio"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/BrunoSena97/dice_game_backend/internal/game"
+	"github.com/BrunoSena97/dice_game_backend/internal/rngtest"
+)
+
+func main() {
+	n := flag.Int("n", 1_000_000, "Number of rolls (pairs of dice) to generate")
+	format := flag.String("format", "lines", "Output format: lines, csv or bytes")
+	outPath := flag.String("out", "-", "Output file, - for stdout")
+	seedHex := flag.String("seed", "", "Optional 64 hex character seed for a reproducible sample")
+	report := flag.Bool("report", false, "Print the rngtest suite results to stderr")
+	flag.Parse()
+
+	if *n <= 0 {
+		log.Fatal("FATAL: -n must be positive")
+	}
+	if *format != "lines" && *format != "csv" && *format != "bytes" {
+		log.Fatalf("FATAL: unknown format %q, want lines, csv or bytes", *format)
+	}
+
+	var gameSvc *game.Service
+	if *seedHex == "" {
+		gameSvc = game.NewService()
+	} else {
+		raw, err := hex.DecodeString(*seedHex)
+		if err != nil || len(raw) != 32 {
+			log.Fatal("FATAL: -seed must be 64 hex characters")
+		}
+		var seed [32]byte
+		copy(seed[:], raw)
+		gameSvc = game.NewSeededService(seed)
+	}
+
+	var out io.Writer = os.Stdout
+	if *outPath != "-" {
+		f, err := os.Create(*outPath)
+		if err != nil {
+			log.Fatalf("FATAL: Failed to create %s: %v", *outPath, err)
+		}
+		defer f.Close()
+		out = f
+	}
+
+	rolls := rngtest.Sample(gameSvc, *n)
+	if err := write(out, *format, rolls); err != nil {
+		log.Fatalf("FATAL: Failed to write rolls: %v", err)
+	}
+
+	if *report {
+		for _, res := range rngtest.Suite(rolls) {
+			fmt.Fprintln(os.Stderr, res)
+		}
+	}
+}
+
+func write(w io.Writer, format string, rolls [][2]int) error {
+	bw := bufio.NewWriter(w)
+	switch format {
+	case "csv":
+		bw.WriteString("die1,die2\n")
+		for _, r := range rolls {
+			fmt.Fprintf(bw, "%d,%d\n", r[0], r[1])
+		}
+	case "bytes":
+		for _, r := range rolls {
+			bw.WriteByte(byte(r[0]))
+			bw.WriteByte(byte(r[1]))
+		}
+	default:
+		for _, r := range rolls {
+			fmt.Fprintf(bw, "%d\n%d\n", r[0], r[1])
+		}
+	}
+	return bw.Flush()
+}
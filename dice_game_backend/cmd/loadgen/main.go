@@ -0,0 +1,299 @@
+// Command loadgen drives the WebSocket protocol at scale. It opens -conns
+// connections to the server, each with its own client ID, sends a mix of
+// get_balance and play messages at a combined target rate, and reports latency
+// percentiles per message type, error codes and connection failures.
+//
+//	docker compose up -d
+//	go run ./cmd/loadgen -url ws://localhost:8080/ws -conns 200 -rate 1000 -duration 60s
+//
+// Each connection keeps one request in flight, so when the server falls behind
+// the achieved rate drops below the target rather than queueing without bound.
+// Latency is measured from send to the matching response: play_result for play,
+// balance_update for get_balance, or an error.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/BrunoSena97/dice_game_backend/internal/constants"
+	"github.com/BrunoSena97/dice_game_backend/internal/handler"
+	"github.com/gorilla/websocket"
+)
+
+type options struct {
+	url         string
+	conns       int
+	rate        float64
+	duration    time.Duration
+	playRatio   float64
+	betAmount   int64
+	betType     string
+	currency    string
+	idPrefix    string
+	ramp        time.Duration
+	respTimeout time.Duration
+}
+
+// stats aggregates results from every connection.
+type stats struct {
+	mu           sync.Mutex
+	latencies    map[string][]time.Duration
+	errorCodes   map[string]int
+	timeouts     map[string]int
+	dialFailures int
+	dropped      int
+	connected    int
+}
+
+func newStats() *stats {
+	return &stats{
+		latencies:  make(map[string][]time.Duration),
+		errorCodes: make(map[string]int),
+		timeouts:   make(map[string]int),
+	}
+}
+
+func main() {
+	var o options
+	flag.StringVar(&o.url, "url", "ws://localhost:8080/ws", "WebSocket endpoint")
+	flag.IntVar(&o.conns, "conns", 50, "Number of concurrent connections")
+	flag.Float64Var(&o.rate, "rate", 200, "Target messages per second across all connections")
+	flag.DurationVar(&o.duration, "duration", 30*time.Second, "How long to send load")
+	flag.Float64Var(&o.playRatio, "play-ratio", 0.5, "Fraction of messages that are play (the rest are get_balance)")
+	flag.Int64Var(&o.betAmount, "bet", 1, "Bet amount for play messages")
+	flag.StringVar(&o.betType, "bet-type", constants.BetTypeLt7, "Bet type for play messages")
+	flag.StringVar(&o.currency, "currency", "", "Currency for play and get_balance (server default when empty)")
+	flag.StringVar(&o.idPrefix, "id-prefix", fmt.Sprintf("loadgen_%d", time.Now().Unix()), "Prefix of generated client IDs")
+	flag.DurationVar(&o.ramp, "ramp", 5*time.Second, "Spread connection start-up over this period")
+	flag.DurationVar(&o.respTimeout, "timeout", 10*time.Second, "How long to wait for a response")
+	flag.Parse()
+
+	if o.conns <= 0 || o.rate <= 0 || o.playRatio < 0 || o.playRatio > 1 {
+		log.Fatal("FATAL: conns and rate must be positive and play-ratio between 0 and 1")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	ctx, cancel := context.WithTimeout(ctx, o.ramp+o.duration)
+	defer cancel()
+
+	log.Printf("Opening %d connections to %s, target %.0f msg/s for %s (play ratio %.2f)", o.conns, o.url, o.rate, o.duration, o.playRatio)
+
+	st := newStats()
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < o.conns; i++ {
+		wg.Add(1)
+		delay := time.Duration(int64(o.ramp) * int64(i) / int64(o.conns))
+		go func(i int) {
+			defer wg.Done()
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return
+			}
+			runConnection(ctx, o, fmt.Sprintf("%s_%d", o.idPrefix, i), st)
+		}(i)
+	}
+	wg.Wait()
+
+	report(os.Stdout, st, time.Since(start))
+}
+
+// runConnection plays one client until ctx ends.
+func runConnection(ctx context.Context, o options, clientID string, st *stats) {
+	dialCtx, dialCancel := context.WithTimeout(ctx, o.respTimeout)
+	conn, _, err := websocket.DefaultDialer.DialContext(dialCtx, o.url, nil)
+	dialCancel()
+	if err != nil {
+		st.mu.Lock()
+		st.dialFailures++
+		st.mu.Unlock()
+		log.Printf("[%s] Dial failed: %v", clientID, err)
+		return
+	}
+	defer conn.Close()
+	st.mu.Lock()
+	st.connected++
+	st.mu.Unlock()
+
+	incoming := make(chan handler.WsMessage, 16)
+	readErr := make(chan error, 1)
+	go func() {
+		for {
+			var msg handler.WsMessage
+			if err := conn.ReadJSON(&msg); err != nil {
+				readErr <- err
+				close(incoming)
+				return
+			}
+			incoming <- msg
+		}
+	}()
+
+	send := func(msgType string, payload interface{}) error {
+		raw, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		conn.SetWriteDeadline(time.Now().Add(o.respTimeout))
+		return conn.WriteJSON(handler.WsMessage{Type: msgType, Payload: raw})
+	}
+
+	hello := handler.HelloPayload{ClientID: clientID, ProtocolVersion: constants.CurrentProtocolVersion}
+	if err := send(constants.MsgTypeHello, hello); err != nil {
+		st.dropConnection(clientID, err)
+		return
+	}
+	if _, ok := await(ctx, incoming, o.respTimeout, constants.MsgTypeHelloAck); !ok {
+		st.dropConnection(clientID, fmt.Errorf("no hello_ack"))
+		return
+	}
+
+	interval := time.Duration(float64(time.Second) * float64(o.conns) / o.rate)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case err := <-readErr:
+			st.dropConnection(clientID, err)
+			return
+		case <-ticker.C:
+		}
+
+		msgType, expected := constants.MsgTypeGetBalance, constants.MsgTypeBalanceUpdate
+		var payload interface{} = handler.GetBalancePayload{ClientID: clientID, Currency: o.currency}
+		if rand.Float64() < o.playRatio {
+			msgType, expected = constants.MsgTypePlay, constants.MsgTypePlayResult
+			payload = handler.PlayPayload{ClientID: clientID, BetAmount: o.betAmount, BetType: o.betType, Currency: o.currency}
+		}
+
+		sentAt := time.Now()
+		if err := send(msgType, payload); err != nil {
+			st.dropConnection(clientID, err)
+			return
+		}
+		resp, ok := await(ctx, incoming, o.respTimeout, expected)
+		if !ok {
+			if ctx.Err() != nil {
+				return
+			}
+			st.mu.Lock()
+			st.timeouts[msgType]++
+			st.mu.Unlock()
+			continue
+		}
+		elapsed := time.Since(sentAt)
+		if resp.Type == constants.MsgTypePlayResult {
+			// A settled play is followed by its balance_update; consume it so it
+			// is not mistaken for the answer to a later get_balance.
+			await(ctx, incoming, o.respTimeout, constants.MsgTypeBalanceUpdate)
+		}
+
+		st.mu.Lock()
+		st.latencies[msgType] = append(st.latencies[msgType], elapsed)
+		if resp.Type == constants.MsgTypeError {
+			var e handler.ErrorPayload
+			if err := json.Unmarshal(resp.Payload, &e); err == nil {
+				st.errorCodes[e.Code]++
+			}
+		}
+		st.mu.Unlock()
+	}
+}
+
+// await returns the next message of the expected type or an error message,
+// skipping unrelated pushes.
+func await(ctx context.Context, incoming <-chan handler.WsMessage, timeout time.Duration, expected string) (handler.WsMessage, bool) {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	for {
+		select {
+		case msg, ok := <-incoming:
+			if !ok {
+				return handler.WsMessage{}, false
+			}
+			if msg.Type == expected || msg.Type == constants.MsgTypeError {
+				return msg, true
+			}
+		case <-deadline.C:
+			return handler.WsMessage{}, false
+		case <-ctx.Done():
+			return handler.WsMessage{}, false
+		}
+	}
+}
+
+func (st *stats) dropConnection(clientID string, err error) {
+	st.mu.Lock()
+	st.dropped++
+	st.mu.Unlock()
+	log.Printf("[%s] Connection lost: %v", clientID, err)
+}
+
+func report(w *os.File, st *stats, elapsed time.Duration) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	fmt.Fprintf(w, "\nConnections: %d opened, %d dial failures, %d dropped\n", st.connected, st.dialFailures, st.dropped)
+	fmt.Fprintf(w, "Elapsed: %s\n\n", elapsed.Round(time.Millisecond))
+
+	fmt.Fprintf(w, "%-12s %8s %9s %9s %9s %9s %9s %9s\n", "type", "count", "msg/s", "p50", "p90", "p99", "max", "timeouts")
+	types := make([]string, 0, len(st.latencies))
+	for t := range st.latencies {
+		types = append(types, t)
+	}
+	for t := range st.timeouts {
+		if _, ok := st.latencies[t]; !ok {
+			types = append(types, t)
+		}
+	}
+	sort.Strings(types)
+	for _, t := range types {
+		lat := st.latencies[t]
+		sort.Slice(lat, func(i, j int) bool { return lat[i] < lat[j] })
+		fmt.Fprintf(w, "%-12s %8d %9.1f %9s %9s %9s %9s %9d\n", t, len(lat), float64(len(lat))/elapsed.Seconds(),
+			percentile(lat, 0.50), percentile(lat, 0.90), percentile(lat, 0.99), percentile(lat, 1), st.timeouts[t])
+	}
+
+	if len(st.errorCodes) > 0 {
+		fmt.Fprintf(w, "\nError codes:\n")
+		codes := make([]string, 0, len(st.errorCodes))
+		for code := range st.errorCodes {
+			codes = append(codes, code)
+		}
+		sort.Strings(codes)
+		for _, code := range codes {
+			fmt.Fprintf(w, "  %-22s %d\n", code, st.errorCodes[code])
+		}
+	}
+}
+
+// percentile returns the p-th quantile of sorted latencies.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(p*float64(len(sorted))) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	return sorted[idx].Round(10 * time.Microsecond)
+}
@@ -0,0 +1,47 @@
+// Command protogen writes the WebSocket protocol schema and TypeScript
+// definitions generated from the handler message structs.
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/BrunoSena97/dice_game_backend/internal/handler"
+	"github.com/BrunoSena97/dice_game_backend/internal/schemagen"
+)
+
+func main() {
+	schemaPath := flag.String("schema", "", "Output path for the JSON Schema document")
+	tsPath := flag.String("ts", "", "Output path for the TypeScript definitions")
+	flag.Parse()
+
+	if *schemaPath == "" && *tsPath == "" {
+		log.Fatal("FATAL: nothing to do, pass -schema and/or -ts")
+	}
+
+	spec := handler.ProtocolSpec()
+
+	if *schemaPath != "" {
+		out, err := schemagen.JSONSchema(spec)
+		if err != nil {
+			log.Fatalf("FATAL: Failed to generate JSON schema: %v", err)
+		}
+		writeFile(*schemaPath, out)
+	}
+
+	if *tsPath != "" {
+		out, err := schemagen.TypeScript(spec)
+		if err != nil {
+			log.Fatalf("FATAL: Failed to generate TypeScript definitions: %v", err)
+		}
+		writeFile(*tsPath, out)
+	}
+}
+
+func writeFile(path string, data []byte) {
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		log.Fatalf("FATAL: Failed to write %s: %v", path, err)
+	}
+	log.Printf("Wrote %s", path)
+}
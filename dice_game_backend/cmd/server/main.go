@@ -2,21 +2,33 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
 	"log"
-	"math/rand"
 	"net/http"
+	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/BrunoSena97/dice_game_backend/internal/admin"
 	"github.com/BrunoSena97/dice_game_backend/internal/config"
-	"github.com/BrunoSena97/dice_game_backend/internal/constants"
+	"github.com/BrunoSena97/dice_game_backend/internal/feed"
+	"github.com/BrunoSena97/dice_game_backend/internal/freerounds"
 	"github.com/BrunoSena97/dice_game_backend/internal/game"
 	"github.com/BrunoSena97/dice_game_backend/internal/handler"
+	"github.com/BrunoSena97/dice_game_backend/internal/jackpot"
+	"github.com/BrunoSena97/dice_game_backend/internal/leaderboard"
+	"github.com/BrunoSena97/dice_game_backend/internal/limits"
+	"github.com/BrunoSena97/dice_game_backend/internal/notify"
 	"github.com/BrunoSena97/dice_game_backend/internal/platform/database"
 	redisPlatform "github.com/BrunoSena97/dice_game_backend/internal/platform/redis"
+	"github.com/BrunoSena97/dice_game_backend/internal/playlock"
+	"github.com/BrunoSena97/dice_game_backend/internal/rounds"
+	"github.com/BrunoSena97/dice_game_backend/internal/settlement"
 	"github.com/BrunoSena97/dice_game_backend/internal/wallet"
 	"github.com/go-redis/redis/v8"
 	"github.com/gorilla/websocket"
@@ -26,6 +38,7 @@ import (
 var upgrader = websocket.Upgrader{
 	ReadBufferSize:  1024,
 	WriteBufferSize: 1024,
+	Subprotocols:    handler.Subprotocols(),
 	CheckOrigin: func(r *http.Request) bool {
 		// TODO: Implement proper origin checking based on config for production
 		// allowedOrigins := cfg.AllowedOrigins
@@ -44,9 +57,6 @@ var upgrader = websocket.Upgrader{
 }
 
 func main() {
-	rand.New(rand.NewSource(time.Now().UnixNano()))
-	log.Println("Global random number generator seeded.")
-
 	cfg, err := config.LoadConfig()
 	if err != nil {
 		log.Fatalf("FATAL: Failed to load configuration: %v", err)
@@ -59,36 +69,141 @@ func main() {
 	mainCtx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
+	if args := flag.Args(); len(args) > 0 {
+		if args[0] != "migrate" {
+			log.Fatalf("FATAL: Unknown command %q. %s", args[0], migrateUsage)
+		}
+		if err := runMigrate(mainCtx, cfg, args[1:]); err != nil {
+			log.Fatalf("FATAL: Migration failed: %v", err)
+		}
+		return
+	}
+
 	dbpool := connectDB(mainCtx, cfg.DB)
 	defer func() {
 		log.Println("Closing database connection pool...")
 		dbpool.Close()
 		log.Println("Database connection pool closed.")
 	}()
+	checkSchema(mainCtx, dbpool, cfg.DB)
+
+	replica := connectReplica(mainCtx, cfg.DB)
+	if replica != nil {
+		defer replica.Close()
+	}
+	dbRouter := database.NewRouter(dbpool, replica, cfg.DB.ReplicaMaxLag)
+	go dbRouter.MonitorReplica(mainCtx, cfg.DB.ReplicaCheckInterval, cfg.App.Timeouts.ShortOp)
+
+	redisClient, redisUp := connectRedis(mainCtx, cfg.Redis)
+	redisHealth := redisPlatform.NewHealth(redisClient, cfg.Redis.HealthCheckInterval, cfg.App.Timeouts.ShortOp, redisUp)
+	go redisHealth.Run(mainCtx)
+
+	go database.MonitorPool(mainCtx, dbpool, cfg.App.PoolStatsInterval)
+	go redisPlatform.MonitorPool(mainCtx, redisClient, cfg.App.PoolStatsInterval)
+
+	balanceHub := notify.NewBalanceHub(redisClient)
+	go balanceHub.Run(mainCtx)
+
+	var balanceCache wallet.BalanceCache
+	if cfg.App.BalanceCacheTTL > 0 {
+		balanceCache = wallet.NewRedisBalanceCache(redisClient, cfg.App.BalanceCacheTTL, redisHealth)
+	} else {
+		log.Println("Balance cache disabled; balances are always read from Postgres.")
+	}
+	var walletSvc wallet.WalletService = wallet.NewNotifyingService(wallet.NewService(dbRouter, cfg.App.InitialBalances(), balanceCache), balanceHub)
+	gameSvc := game.NewService()
+	gameSvc.SetPaytable(cfg.App.Paytable)
+
+	liveFeed := feed.NewFeed(redisClient, cfg.App.FeedBigWinThreshold)
+	go liveFeed.Run(mainCtx)
+
+	roundStore := rounds.NewStore(dbRouter)
+	leaderboardSvc := leaderboard.NewService(redisClient, roundStore)
+
+	limitsSvc := limits.NewService(dbpool, cfg.App.LimitCoolingOff)
+	freeRoundSvc := freerounds.NewService(dbRouter, cfg.App.CurrencyCodes())
+
+	var jackpotSvc *jackpot.Service
+	if cfg.App.Jackpot.Enabled {
+		jackpotSvc = jackpot.NewService(dbRouter, cfg.App.CurrencyCodes(), cfg.App.Jackpot.Config)
+		seedCtx, cancelSeed := context.WithTimeout(mainCtx, cfg.App.Timeouts.ShortOp)
+		err := jackpotSvc.Seed(seedCtx)
+		cancelSeed()
+		if err != nil {
+			log.Fatalf("FATAL: Failed to seed jackpot pools: %v", err)
+		}
+		go jackpotSvc.Run(mainCtx)
+		log.Printf("Jackpot enabled (%g%% of every stake, won on double sixes with a stake of at least %d).",
+			cfg.App.Jackpot.ContributionPercent, cfg.App.Jackpot.MinBetAmount)
+	}
 
-	redisClient := connectRedis(mainCtx, cfg.Redis)
+	var lockFallback playlock.Locker
+	var lockPool *pgxpool.Pool
+	if cfg.App.PlayLockFallback == playlock.FallbackPostgres {
+		lockPool = connectDB(mainCtx, cfg.DB.PlayLocks())
+		defer lockPool.Close()
+		lockFallback = playlock.NewPostgresLocker(lockPool, cfg.App.Timeouts.LockRelease)
+	}
+	playLocker := playlock.NewFailoverLocker(
+		playlock.NewRedisLocker(redisClient, cfg.App.Timeouts.PlayLock, cfg.App.Timeouts.LockRelease),
+		lockFallback, redisHealth)
+
+	// The batcher outlives mainCtx: it is stopped on shutdown only once the
+	// handler has no round left to submit.
+	settleCtx, stopSettling := context.WithCancel(context.Background())
+	defer stopSettling()
+	var settler *settlement.Batcher
+	if cfg.App.Settlement.Mode == settlement.ModeBatch {
+		settler = settlement.NewBatcher(dbpool, balanceCache, balanceHub,
+			cfg.App.Settlement.BatchSize, cfg.App.Settlement.FlushInterval, cfg.App.Timeouts.ShortOp)
+		go settler.Run(settleCtx)
+		log.Printf("Batch settlement enabled (up to %d rounds every %v).", cfg.App.Settlement.BatchSize, cfg.App.Settlement.FlushInterval)
+	}
 
-	var walletSvc wallet.WalletService = wallet.NewService(dbpool)
-	var gameSvc game.GameService = game.NewService()
+	appHandler := handler.NewHandler(walletSvc, playLocker, gameSvc, balanceHub, liveFeed, leaderboardSvc, limitsSvc, freeRoundSvc, jackpotSvc, settler, cfg.App)
 
-	appHandler := handler.NewHandler(walletSvc, redisClient, gameSvc, cfg.App)
+	go reloadOnSIGHUP(mainCtx, cfg, appHandler, gameSvc)
 
 	mux := http.NewServeMux()
 
 	mux.HandleFunc("/ws", wsHandler(appHandler))
+	mux.Handle("GET /leaderboard", leaderboard.NewHTTPHandler(leaderboardSvc, cfg.App.CurrencyCodes(), cfg.App.DefaultCurrency))
+
+	if cfg.Admin.APIToken != "" {
+		admin.NewHandler(walletSvc, leaderboardSvc, freeRoundSvc, cfg.Admin.APIToken, cfg.App.DefaultCurrency, cfg.App.Bonus.WageringMultiplier).Register(mux)
+		log.Println("Admin API enabled under /admin/.")
+	} else {
+		log.Println("Admin API disabled (ADMIN_API_TOKEN not set).")
+	}
 
 	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		fmt.Fprintln(w, "OK")
 	})
+	mux.HandleFunc("GET /health/pools", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		stats := map[string]any{
+			"database": database.Stats(dbpool),
+			"replica":  dbRouter.Status(),
+			"redis":    redisPlatform.Stats(redisClient),
+			// redisAvailable is false while running in degraded mode.
+			"redisAvailable": redisHealth.Healthy(),
+		}
+		if lockPool != nil {
+			stats["playLocks"] = database.Stats(lockPool)
+		}
+		if err := json.NewEncoder(w).Encode(stats); err != nil {
+			log.Printf("Failed to write pool stats: %v", err)
+		}
+	})
 
 	listenAddr := fmt.Sprintf(":%s", cfg.App.ListenPort)
 	server := &http.Server{
 		Addr:         listenAddr,
 		Handler:      mux,
-		ReadTimeout:  cfg.App.ReadTimeout,
-		WriteTimeout: cfg.App.WriteTimeout,
-		IdleTimeout:  cfg.App.IdleTimeout,
+		ReadTimeout:  cfg.App.Timeouts.HTTPRead,
+		WriteTimeout: cfg.App.Timeouts.HTTPWrite,
+		IdleTimeout:  cfg.App.Timeouts.HTTPIdle,
 	}
 
 	go func() {
@@ -104,7 +219,7 @@ func main() {
 
 	log.Println("Shutdown signal received. Initiating graceful shutdown...")
 
-	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), time.Duration(constants.ShutdownTimeout)*time.Second)
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), cfg.App.Timeouts.Shutdown)
 	defer cancelShutdown()
 
 	if err := server.Shutdown(shutdownCtx); err != nil {
@@ -113,6 +228,20 @@ func main() {
 		log.Println("HTTP server gracefully stopped.")
 	}
 
+	// WebSocket connections are hijacked and outlive server.Shutdown, so stop
+	// them starting rounds before the batcher drains.
+	if err := appHandler.Drain(shutdownCtx); err != nil {
+		log.Printf("ERROR: Timed out waiting for rounds in play: %v", err)
+	}
+	stopSettling()
+	if settler != nil {
+		select {
+		case <-settler.Done():
+		case <-shutdownCtx.Done():
+			log.Println("ERROR: Timed out waiting for pending rounds to settle.")
+		}
+	}
+
 	log.Println("Shutdown complete.")
 }
 
@@ -130,7 +259,7 @@ func wsHandler(appHandler *handler.Handler) http.HandlerFunc {
 
 // connectDB helper function with context for cancellation.
 func connectDB(ctx context.Context, cfg database.Config) *pgxpool.Pool {
-	connectCtx, cancel := context.WithTimeout(ctx, time.Duration(constants.DBConnectTimeout)*time.Second)
+	connectCtx, cancel := context.WithTimeout(ctx, cfg.ConnectTimeout)
 	defer cancel()
 
 	dbpool, err := database.Connect(connectCtx, cfg)
@@ -141,15 +270,129 @@ func connectDB(ctx context.Context, cfg database.Config) *pgxpool.Pool {
 	return dbpool
 }
 
-// connectRedis helper function with context for cancellation.
-func connectRedis(ctx context.Context, cfg redisPlatform.Config) *redis.Client {
-	connectCtx, cancel := context.WithTimeout(ctx, time.Duration(constants.RedisConnectTimeout)*time.Second)
+// connectReplica connects to the read replica, if one is configured. An
+// unreachable replica is not fatal: reads stay on the primary.
+func connectReplica(ctx context.Context, cfg database.Config) *pgxpool.Pool {
+	if cfg.ReplicaHost == "" {
+		log.Println("No read replica configured; all reads use the primary.")
+		return nil
+	}
+	connectCtx, cancel := context.WithTimeout(ctx, cfg.ConnectTimeout)
+	defer cancel()
+
+	replica, err := database.Connect(connectCtx, cfg.Replica())
+	if err != nil {
+		log.Printf("WARN: Read replica unavailable, all reads use the primary: %v", err)
+		return nil
+	}
+	log.Printf("Connected to read replica on %s:%d (max lag %v)", cfg.ReplicaHost, cfg.ReplicaPort, cfg.ReplicaMaxLag)
+	return replica
+}
+
+// checkSchema refuses to start against a schema other than the one this build
+// expects. With AutoMigrate, pending migrations are applied first.
+func checkSchema(ctx context.Context, dbpool *pgxpool.Pool, cfg database.Config) {
+	if cfg.AutoMigrate {
+		applied, err := database.MigrateUp(ctx, dbpool)
+		if err != nil {
+			log.Fatalf("FATAL: Failed to apply database migrations: %v", err)
+		}
+		log.Printf("Applied %d pending database migration(s).", len(applied))
+	}
+	version, err := database.CheckSchema(ctx, dbpool)
+	if err != nil {
+		log.Fatalf("FATAL: %v", err)
+	}
+	log.Printf("Database schema at version %d.", version)
+}
+
+// connectRedis connects according to the configured startup mode and reports
+// whether Redis answered. Only in degraded mode can it return an unreachable client.
+func connectRedis(ctx context.Context, cfg redisPlatform.Config) (redis.UniversalClient, bool) {
+	switch cfg.StartupMode {
+	case redisPlatform.StartupWait:
+		backoff := time.Second
+		for {
+			redisClient, err := tryConnectRedis(ctx, cfg)
+			if err == nil {
+				return redisClient, true
+			}
+			log.Printf("WARN: Redis not reachable, retrying in %v: %v", backoff, err)
+			select {
+			case <-ctx.Done():
+				log.Fatalf("FATAL: Gave up waiting for Redis: %v", ctx.Err())
+			case <-time.After(backoff):
+			}
+			backoff = min(2*backoff, 30*time.Second)
+		}
+
+	case redisPlatform.StartupDegraded:
+		redisClient, err := redisPlatform.NewClient(cfg)
+		if err != nil {
+			log.Fatalf("FATAL: Failed to create Redis client: %v", err)
+		}
+		pingCtx, cancel := context.WithTimeout(ctx, cfg.ConnectTimeout)
+		defer cancel()
+		if err := redisClient.Ping(pingCtx).Err(); err != nil {
+			log.Printf("WARN: Redis not reachable, starting in degraded mode: %v", err)
+			return redisClient, false
+		}
+		log.Printf("Connected to Redis (%s) at %s (DB %d)", cfg.Mode, strings.Join(cfg.Addrs, ","), cfg.DB)
+		return redisClient, true
+
+	default:
+		redisClient, err := tryConnectRedis(ctx, cfg)
+		if err != nil {
+			log.Fatalf("FATAL: Failed to connect to Redis: %v", err)
+		}
+		return redisClient, true
+	}
+}
+
+// tryConnectRedis makes one connection attempt bounded by the connect timeout.
+func tryConnectRedis(ctx context.Context, cfg redisPlatform.Config) (redis.UniversalClient, error) {
+	connectCtx, cancel := context.WithTimeout(ctx, cfg.ConnectTimeout)
 	defer cancel()
 
 	redisClient, err := redisPlatform.ConnectRedis(connectCtx, cfg)
 	if err != nil {
-		log.Fatalf("FATAL: Failed to connect to Redis: %v", err)
+		return nil, err
+	}
+	log.Printf("Connected to Redis (%s) at %s (DB %d)", cfg.Mode, strings.Join(cfg.Addrs, ","), cfg.DB)
+	return redisClient, nil
+}
+
+// reloadOnSIGHUP re-reads the configuration on SIGHUP and applies the settings
+// that can change at runtime without dropping connections. An invalid
+// configuration is rejected and the running one kept.
+func reloadOnSIGHUP(ctx context.Context, cfg *config.Config, appHandler *handler.Handler, gameSvc *game.Service) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	current := cfg
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+		}
+
+		log.Println("SIGHUP received. Reloading configuration...")
+		next, ignored, err := config.Reload(current)
+		if err != nil {
+			log.Printf("ERROR: Configuration reload rejected, keeping current settings: %v", err)
+			continue
+		}
+
+		app := next.App
+		gameSvc.SetPaytable(app.Paytable)
+		appHandler.ApplyConfig(app)
+		for _, setting := range ignored {
+			log.Printf("WARN: Change to %s requires a restart and was not applied.", setting)
+		}
+
+		current = next
+		log.Printf("Configuration reloaded: paytable %v, rate limit %.1f/s burst %d.", app.Paytable, app.RateLimit.PerSecond, app.RateLimit.Burst)
 	}
-	log.Printf("Connected to Redis at %s (DB %s)", cfg.Addr, cfg.DB)
-	return redisClient
 }
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/BrunoSena97/dice_game_backend/internal/config"
+	"github.com/BrunoSena97/dice_game_backend/internal/platform/database"
+)
+
+const migrateUsage = "usage: server [-dev] [-config path] migrate up | down [steps] | status"
+
+// runMigrate implements the migrate subcommand. args are the arguments after "migrate".
+func runMigrate(ctx context.Context, cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	dbpool := connectDB(ctx, cfg.DB)
+	defer dbpool.Close()
+
+	switch args[0] {
+	case "up":
+		if len(args) != 1 {
+			return errors.New(migrateUsage)
+		}
+		applied, err := database.MigrateUp(ctx, dbpool)
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Println("Schema is up to date.")
+			return nil
+		}
+		fmt.Printf("Applied %d migration(s), now at version %d.\n", len(applied), applied[len(applied)-1].Version)
+		return nil
+
+	case "down":
+		steps := 1
+		if len(args) == 2 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid number of steps %q: %s", args[1], migrateUsage)
+			}
+			steps = n
+		} else if len(args) > 2 {
+			return errors.New(migrateUsage)
+		}
+		reverted, err := database.MigrateDown(ctx, dbpool, steps)
+		if err != nil {
+			return err
+		}
+		if len(reverted) == 0 {
+			fmt.Println("No migrations to revert.")
+			return nil
+		}
+		fmt.Printf("Reverted %d migration(s), now at version %d.\n", len(reverted), reverted[len(reverted)-1].Version-1)
+		return nil
+
+	case "status":
+		if len(args) != 1 {
+			return errors.New(migrateUsage)
+		}
+		states, err := database.MigrationStatus(ctx, dbpool)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, s := range states {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = s.AppliedAt.UTC().Format("2006-01-02 15:04:05Z")
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", s.Version, s.Name, applied)
+		}
+		return w.Flush()
+
+	default:
+		return fmt.Errorf("unknown migrate command %q: %s", args[0], migrateUsage)
+	}
+}
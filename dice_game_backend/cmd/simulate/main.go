@@ -0,0 +1,210 @@
+// Command simulate plays rounds through game.GameService offline, with no
+// database or Redis, and reports the statistics of a bet type, stake and
+// autoplay strategy. Use it to sanity-check paytable changes before they ship.
+//
+// Rounds are grouped into sessions that each start from -balance. A session
+// busts when its balance can no longer cover the strategy's next stake.
+//
+//	go run ./cmd/simulate -bet-type lt7 -stake 10 -sessions 10000 -rounds 500 -format json
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"strconv"
+
+	"github.com/BrunoSena97/dice_game_backend/internal/autoplay"
+	"github.com/BrunoSena97/dice_game_backend/internal/constants"
+	"github.com/BrunoSena97/dice_game_backend/internal/game"
+)
+
+// Report is the outcome of a simulation run.
+type Report struct {
+	BetType          string  `json:"betType"`
+	PayoutPercent    int64   `json:"payoutPercent"`
+	Stake            int64   `json:"stake"`
+	Strategy         string  `json:"strategy"`
+	MaxBet           int64   `json:"maxBet"`
+	StartBalance     int64   `json:"startBalance"`
+	Sessions         int     `json:"sessions"`
+	RoundsPerSession int     `json:"roundsPerSession"`
+	RoundsPlayed     int64   `json:"roundsPlayed"`
+	TotalWagered     int64   `json:"totalWagered"`
+	TotalReturned    int64   `json:"totalReturned"`
+	RTP              float64 `json:"rtp"`
+	HitFrequency     float64 `json:"hitFrequency"`
+	MeanNet          float64 `json:"meanNetPerRound"`
+	Variance         float64 `json:"variancePerRound"`
+	StdDev           float64 `json:"stdDevPerRound"`
+	MaxDrawdown      int64   `json:"maxDrawdown"`
+	MeanMaxDrawdown  float64 `json:"meanMaxDrawdown"`
+	Busts            int     `json:"busts"`
+	BustProbability  float64 `json:"bustProbability"`
+}
+
+func main() {
+	betType := flag.String("bet-type", constants.BetTypeLt7, "Bet type to simulate")
+	stake := flag.Int64("stake", 10, "Base stake per round")
+	strategy := flag.String("strategy", autoplay.StrategyFlat, "Staking strategy: flat, martingale or dalembert")
+	maxBet := flag.Int64("max-bet", 250, "Cap on progressive stakes")
+	payout := flag.Int64("payout", 100, "Winnings of the bet type as a percentage of the stake (100 pays 1:1)")
+	startBalance := flag.Int64("balance", constants.DefaultInitialBalance, "Starting balance of each session")
+	sessions := flag.Int("sessions", 10000, "Number of independent sessions")
+	rounds := flag.Int("rounds", 100, "Maximum rounds per session")
+	format := flag.String("format", "json", "Output format: json or csv")
+	flag.Parse()
+
+	if *stake <= 0 || *maxBet < *stake {
+		log.Fatalf("FATAL: stake must be positive and no greater than max-bet (stake %d, max-bet %d)", *stake, *maxBet)
+	}
+	if *sessions <= 0 || *rounds <= 0 {
+		log.Fatal("FATAL: sessions and rounds must be positive")
+	}
+	if *format != "json" && *format != "csv" {
+		log.Fatalf("FATAL: unknown format %q, want json or csv", *format)
+	}
+	switch *strategy {
+	case autoplay.StrategyFlat, autoplay.StrategyMartingale, autoplay.StrategyDAlembert:
+	default:
+		log.Fatalf("FATAL: unknown strategy %q", *strategy)
+	}
+
+	cfg := autoplay.Config{BaseBet: *stake, Rounds: *rounds, Strategy: *strategy, MaxBet: *maxBet}
+	if *payout <= 0 {
+		log.Fatal("FATAL: payout must be positive")
+	}
+	gameSvc := game.NewService()
+	paytable := game.DefaultPaytable()
+	paytable[*betType] = *payout
+	gameSvc.SetPaytable(paytable)
+
+	// The game service logs every roll; that would dominate a run of millions.
+	logOutput := log.Writer()
+	log.SetOutput(io.Discard)
+	report, err := simulate(context.Background(), gameSvc, *betType, *payout, cfg, *startBalance, *sessions)
+	log.SetOutput(logOutput)
+	if err != nil {
+		log.Fatalf("FATAL: Simulation failed: %v", err)
+	}
+
+	if err := write(os.Stdout, *format, report); err != nil {
+		log.Fatalf("FATAL: Failed to write report: %v", err)
+	}
+}
+
+func simulate(ctx context.Context, gameSvc game.GameService, betType string, payoutPercent int64, cfg autoplay.Config, startBalance int64, sessions int) (Report, error) {
+	r := Report{
+		BetType:          betType,
+		PayoutPercent:    payoutPercent,
+		Stake:            cfg.BaseBet,
+		Strategy:         cfg.Strategy,
+		MaxBet:           cfg.MaxBet,
+		StartBalance:     startBalance,
+		Sessions:         sessions,
+		RoundsPerSession: cfg.Rounds,
+	}
+
+	var wins int64
+	var mean, m2 float64 // Welford's running mean and sum of squared deviations of the net per round
+	var drawdownSum int64
+
+	for i := 0; i < sessions; i++ {
+		session := autoplay.NewSession(cfg)
+		balance, peak, maxDrawdown := startBalance, startBalance, int64(0)
+
+		for {
+			bet := session.NextBet()
+			if bet > balance {
+				r.Busts++
+				break
+			}
+			result, err := gameSvc.PlayRound(ctx, betType, bet)
+			if err != nil {
+				return Report{}, err
+			}
+
+			won := result.Outcome == constants.OutcomeWin
+			net := -bet
+			if won {
+				wins++
+				net = result.Winnings
+				r.TotalReturned += bet + result.Winnings
+			}
+			r.TotalWagered += bet
+			r.RoundsPlayed++
+			balance += net
+
+			delta := float64(net) - mean
+			mean += delta / float64(r.RoundsPlayed)
+			m2 += delta * (float64(net) - mean)
+
+			if balance > peak {
+				peak = balance
+			}
+			if peak-balance > maxDrawdown {
+				maxDrawdown = peak - balance
+			}
+
+			if _, done := session.Record(won, net); done {
+				break
+			}
+		}
+
+		drawdownSum += maxDrawdown
+		if maxDrawdown > r.MaxDrawdown {
+			r.MaxDrawdown = maxDrawdown
+		}
+	}
+
+	if r.RoundsPlayed > 0 {
+		r.RTP = float64(r.TotalReturned) / float64(r.TotalWagered)
+		r.HitFrequency = float64(wins) / float64(r.RoundsPlayed)
+		r.MeanNet = mean
+		r.Variance = m2 / float64(r.RoundsPlayed)
+		r.StdDev = math.Sqrt(r.Variance)
+	}
+	r.MeanMaxDrawdown = float64(drawdownSum) / float64(sessions)
+	r.BustProbability = float64(r.Busts) / float64(sessions)
+	return r, nil
+}
+
+func write(w io.Writer, format string, r Report) error {
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	}
+
+	cw := csv.NewWriter(w)
+	header := []string{
+		"betType", "payoutPercent", "stake", "strategy", "maxBet", "startBalance", "sessions", "roundsPerSession",
+		"roundsPlayed", "totalWagered", "totalReturned", "rtp", "hitFrequency", "meanNetPerRound",
+		"variancePerRound", "stdDevPerRound", "maxDrawdown", "meanMaxDrawdown", "busts", "bustProbability",
+	}
+	row := []string{
+		r.BetType, i64(r.PayoutPercent), i64(r.Stake), r.Strategy, i64(r.MaxBet), i64(r.StartBalance), strconv.Itoa(r.Sessions), strconv.Itoa(r.RoundsPerSession),
+		i64(r.RoundsPlayed), i64(r.TotalWagered), i64(r.TotalReturned), f64(r.RTP), f64(r.HitFrequency), f64(r.MeanNet),
+		f64(r.Variance), f64(r.StdDev), i64(r.MaxDrawdown), f64(r.MeanMaxDrawdown), strconv.Itoa(r.Busts), f64(r.BustProbability),
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	if err := cw.Write(row); err != nil {
+		return err
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("failed to write CSV: %w", err)
+	}
+	return nil
+}
+
+func i64(v int64) string   { return strconv.FormatInt(v, 10) }
+func f64(v float64) string { return strconv.FormatFloat(v, 'f', 6, 64) }
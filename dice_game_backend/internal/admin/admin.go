@@ -0,0 +1,413 @@
+// Package admin exposes the authenticated HTTP API used by support staff.
+package admin
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BrunoSena97/dice_game_backend/internal/freerounds"
+	"github.com/BrunoSena97/dice_game_backend/internal/leaderboard"
+	"github.com/BrunoSena97/dice_game_backend/internal/platform/database"
+	"github.com/BrunoSena97/dice_game_backend/internal/wallet"
+)
+
+const (
+	headerActor         = "X-Admin-Actor"
+	defaultLedgerLimit  = 50
+	maxLedgerLimit      = 500
+	maxRequestBodyBytes = 1 << 16
+)
+
+// Handler serves the admin API.
+type Handler struct {
+	walletSvc       wallet.WalletService
+	leaderboard     *leaderboard.Service
+	freeRounds      *freerounds.Service
+	token           string
+	defaultCurrency string
+	// wageringMultiplier applies to bonus grants that do not set their own.
+	wageringMultiplier int64
+}
+
+// NewHandler creates an admin handler authenticating requests with the given bearer token.
+func NewHandler(walletSvc wallet.WalletService, leaderboardSvc *leaderboard.Service, freeRoundSvc *freerounds.Service, token, defaultCurrency string, wageringMultiplier int64) *Handler {
+	if walletSvc == nil {
+		log.Fatal("WalletService is nil in admin.NewHandler")
+	}
+	if leaderboardSvc == nil {
+		log.Fatal("LeaderboardService is nil in admin.NewHandler")
+	}
+	if freeRoundSvc == nil {
+		log.Fatal("FreeRoundService is nil in admin.NewHandler")
+	}
+	if token == "" {
+		log.Fatal("Admin API token is empty in admin.NewHandler")
+	}
+	return &Handler{
+		walletSvc:          walletSvc,
+		leaderboard:        leaderboardSvc,
+		freeRounds:         freeRoundSvc,
+		token:              token,
+		defaultCurrency:    defaultCurrency,
+		wageringMultiplier: wageringMultiplier,
+	}
+}
+
+// Register mounts the admin routes on mux.
+func (h *Handler) Register(mux *http.ServeMux) {
+	mux.Handle("GET /admin/wallets/{userID}", h.authenticated(h.handleGetWallets))
+	mux.Handle("GET /admin/wallets/{userID}/ledger", h.authenticated(h.handleGetLedger))
+	mux.Handle("POST /admin/wallets/{userID}/deposit", h.authenticated(h.handleDeposit))
+	mux.Handle("POST /admin/wallets/{userID}/withdraw", h.authenticated(h.handleWithdraw))
+	mux.Handle("POST /admin/wallets/{userID}/adjust", h.authenticated(h.handleAdjust))
+	mux.Handle("POST /admin/wallets/{userID}/bonus", h.authenticated(h.handleGrantBonus))
+	mux.Handle("GET /admin/wallets/{userID}/free-rounds", h.authenticated(h.handleListFreeRounds))
+	mux.Handle("POST /admin/wallets/{userID}/free-rounds", h.authenticated(h.handleGrantFreeRounds))
+	mux.Handle("DELETE /admin/free-rounds/{grantID}", h.authenticated(h.handleRevokeFreeRounds))
+	mux.Handle("POST /admin/leaderboards/rebuild", h.authenticated(h.handleRebuildLeaderboards))
+}
+
+type balanceChangeRequest struct {
+	Currency string `json:"currency"`
+	Amount   int64  `json:"amount"`
+	Reason   string `json:"reason"`
+}
+
+type balanceResponse struct {
+	UserID   string `json:"userId"`
+	Currency string `json:"currency"`
+	Balance  int64  `json:"balance"`
+}
+
+// bonusGrantRequest grants a bonus. WageringMultiplier defaults to the configured one.
+type bonusGrantRequest struct {
+	Currency           string `json:"currency"`
+	Amount             int64  `json:"amount"`
+	WageringMultiplier *int64 `json:"wageringMultiplier"`
+	Reason             string `json:"reason"`
+}
+
+type bonusResponse struct {
+	UserID           string `json:"userId"`
+	Currency         string `json:"currency"`
+	BonusBalance     int64  `json:"bonusBalance"`
+	WageringRequired int64  `json:"wageringRequired"`
+	WageringProgress int64  `json:"wageringProgress"`
+}
+
+// freeRoundGrantRequest grants Rounds free rounds of Stake on BetType, playable until ExpiresAt.
+type freeRoundGrantRequest struct {
+	Currency  string    `json:"currency"`
+	BetType   string    `json:"betType"`
+	Stake     int64     `json:"stake"`
+	Rounds    int       `json:"rounds"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	Reason    string    `json:"reason"`
+}
+
+type freeRoundGrantResponse struct {
+	ID              int64      `json:"id"`
+	UserID          string     `json:"userId"`
+	Currency        string     `json:"currency"`
+	BetType         string     `json:"betType"`
+	Stake           int64      `json:"stake"`
+	RoundsGranted   int        `json:"roundsGranted"`
+	RoundsRemaining int        `json:"roundsRemaining"`
+	ExpiresAt       time.Time  `json:"expiresAt"`
+	Reason          string     `json:"reason"`
+	Actor           string     `json:"actor"`
+	RevokedAt       *time.Time `json:"revokedAt"`
+	CreatedAt       time.Time  `json:"createdAt"`
+}
+
+type freeRoundGrantsResponse struct {
+	UserID string                   `json:"userId"`
+	Grants []freeRoundGrantResponse `json:"grants"`
+}
+
+type walletsResponse struct {
+	UserID   string           `json:"userId"`
+	Balances map[string]int64 `json:"balances"`
+}
+
+type ledgerEntryResponse struct {
+	ID           int64     `json:"id"`
+	EntryType    string    `json:"entryType"`
+	Amount       int64     `json:"amount"`
+	BalanceAfter int64     `json:"balanceAfter"`
+	BonusAfter   int64     `json:"bonusAfter"`
+	Reason       string    `json:"reason"`
+	Actor        string    `json:"actor"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+type ledgerResponse struct {
+	UserID   string                `json:"userId"`
+	Currency string                `json:"currency"`
+	Entries  []ledgerEntryResponse `json:"entries"`
+}
+
+type errorResponse struct {
+	Error   string `json:"error"`
+	Message string `json:"message"`
+}
+
+// authenticated checks the bearer token and the acting operator before calling next.
+func (h *Handler) authenticated(next func(w http.ResponseWriter, r *http.Request, actor string)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) != 1 {
+			log.Printf("ADMIN: Rejected unauthenticated %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+			writeError(w, http.StatusUnauthorized, "UNAUTHORIZED", "Missing or invalid admin token.")
+			return
+		}
+		actor := strings.TrimSpace(r.Header.Get(headerActor))
+		if actor == "" {
+			writeError(w, http.StatusBadRequest, "ACTOR_REQUIRED", "The "+headerActor+" header is required.")
+			return
+		}
+		log.Printf("ADMIN: %s %s by %s", r.Method, r.URL.Path, actor)
+		next(w, r, actor)
+	})
+}
+
+func (h *Handler) handleGetWallets(w http.ResponseWriter, r *http.Request, _ string) {
+	userID := r.PathValue("userID")
+	balances, err := h.walletSvc.GetBalances(r.Context(), userID)
+	if err != nil {
+		writeWalletError(w, err)
+		return
+	}
+	if len(balances) == 0 {
+		writeError(w, http.StatusNotFound, "WALLET_NOT_FOUND", "User has no wallets.")
+		return
+	}
+	writeJSON(w, http.StatusOK, walletsResponse{UserID: userID, Balances: balances})
+}
+
+func (h *Handler) handleGetLedger(w http.ResponseWriter, r *http.Request, _ string) {
+	userID := r.PathValue("userID")
+	currency := h.currencyOrDefault(r.URL.Query().Get("currency"))
+
+	limit := defaultLedgerLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 || parsed > maxLedgerLimit {
+			writeError(w, http.StatusBadRequest, "BAD_REQUEST", "limit must be between 1 and "+strconv.Itoa(maxLedgerLimit)+".")
+			return
+		}
+		limit = parsed
+	}
+
+	// History tolerates replica lag; balances read for adjustments do not.
+	entries, err := h.walletSvc.GetLedger(database.AllowStale(r.Context()), userID, currency, limit)
+	if err != nil {
+		writeWalletError(w, err)
+		return
+	}
+
+	resp := ledgerResponse{UserID: userID, Currency: currency, Entries: make([]ledgerEntryResponse, 0, len(entries))}
+	for _, e := range entries {
+		resp.Entries = append(resp.Entries, ledgerEntryResponse{
+			ID:           e.ID,
+			EntryType:    e.EntryType,
+			Amount:       e.Amount,
+			BalanceAfter: e.BalanceAfter,
+			BonusAfter:   e.BonusAfter,
+			Reason:       e.Reason,
+			Actor:        e.Actor,
+			CreatedAt:    e.CreatedAt,
+		})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *Handler) handleRebuildLeaderboards(w http.ResponseWriter, r *http.Request, _ string) {
+	if err := h.leaderboard.Rebuild(r.Context()); err != nil {
+		log.Printf("ADMIN ERROR: leaderboard rebuild failed: %v", err)
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Leaderboard rebuild failed.")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) handleDeposit(w http.ResponseWriter, r *http.Request, actor string) {
+	h.handleBalanceChange(w, r, func(userID string, req balanceChangeRequest) (int64, error) {
+		return h.walletSvc.Deposit(r.Context(), userID, req.Currency, req.Amount, actor)
+	})
+}
+
+func (h *Handler) handleWithdraw(w http.ResponseWriter, r *http.Request, actor string) {
+	h.handleBalanceChange(w, r, func(userID string, req balanceChangeRequest) (int64, error) {
+		return h.walletSvc.Withdraw(r.Context(), userID, req.Currency, req.Amount, actor)
+	})
+}
+
+func (h *Handler) handleAdjust(w http.ResponseWriter, r *http.Request, actor string) {
+	h.handleBalanceChange(w, r, func(userID string, req balanceChangeRequest) (int64, error) {
+		return h.walletSvc.Adjust(r.Context(), userID, req.Currency, req.Amount, req.Reason, actor)
+	})
+}
+
+func (h *Handler) handleGrantBonus(w http.ResponseWriter, r *http.Request, actor string) {
+	var req bonusGrantRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	req.Currency = h.currencyOrDefault(req.Currency)
+	multiplier := h.wageringMultiplier
+	if req.WageringMultiplier != nil {
+		multiplier = *req.WageringMultiplier
+	}
+	userID := r.PathValue("userID")
+
+	bonus, err := h.walletSvc.GrantBonus(r.Context(), userID, req.Currency, req.Amount, multiplier, req.Reason, actor)
+	if err != nil {
+		writeWalletError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, bonusResponse{
+		UserID:           userID,
+		Currency:         req.Currency,
+		BonusBalance:     bonus.Balance,
+		WageringRequired: bonus.WageringRequired,
+		WageringProgress: bonus.WageringProgress,
+	})
+}
+
+func (h *Handler) handleListFreeRounds(w http.ResponseWriter, r *http.Request, _ string) {
+	userID := r.PathValue("userID")
+	grants, err := h.freeRounds.List(database.AllowStale(r.Context()), userID, false)
+	if err != nil {
+		writeFreeRoundError(w, err)
+		return
+	}
+	resp := freeRoundGrantsResponse{UserID: userID, Grants: make([]freeRoundGrantResponse, 0, len(grants))}
+	for _, g := range grants {
+		resp.Grants = append(resp.Grants, freeRoundGrantResponse(g))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *Handler) handleGrantFreeRounds(w http.ResponseWriter, r *http.Request, actor string) {
+	var req freeRoundGrantRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	grant, err := h.freeRounds.Create(r.Context(), freerounds.Grant{
+		UserID:        r.PathValue("userID"),
+		Currency:      h.currencyOrDefault(req.Currency),
+		BetType:       req.BetType,
+		Stake:         req.Stake,
+		RoundsGranted: req.Rounds,
+		ExpiresAt:     req.ExpiresAt,
+		Reason:        req.Reason,
+		Actor:         actor,
+	})
+	if err != nil {
+		writeFreeRoundError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, freeRoundGrantResponse(grant))
+}
+
+func (h *Handler) handleRevokeFreeRounds(w http.ResponseWriter, r *http.Request, actor string) {
+	grantID, err := strconv.ParseInt(r.PathValue("grantID"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "grantID must be a number.")
+		return
+	}
+	grant, err := h.freeRounds.Revoke(r.Context(), grantID, actor)
+	if err != nil {
+		writeFreeRoundError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, freeRoundGrantResponse(grant))
+}
+
+// handleBalanceChange decodes the request body, runs apply and writes the new balance.
+func (h *Handler) handleBalanceChange(w http.ResponseWriter, r *http.Request, apply func(userID string, req balanceChangeRequest) (int64, error)) {
+	var req balanceChangeRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	req.Currency = h.currencyOrDefault(req.Currency)
+	userID := r.PathValue("userID")
+
+	balance, err := apply(userID, req)
+	if err != nil {
+		writeWalletError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, balanceResponse{UserID: userID, Currency: req.Currency, Balance: balance})
+}
+
+func (h *Handler) currencyOrDefault(currency string) string {
+	if currency == "" {
+		return h.defaultCurrency
+	}
+	return strings.ToUpper(currency)
+}
+
+// decodeJSON reads a size-limited JSON body into v, writing a 400 on failure.
+func decodeJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodyBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "Invalid JSON body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// writeWalletError maps wallet errors to HTTP responses.
+func writeWalletError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, wallet.ErrWalletNotFound):
+		writeError(w, http.StatusNotFound, "WALLET_NOT_FOUND", err.Error())
+	case errors.Is(err, wallet.ErrInsufficientFunds):
+		writeError(w, http.StatusConflict, "INSUFFICIENT_FUNDS", err.Error())
+	case errors.Is(err, wallet.ErrInvalidAmount),
+		errors.Is(err, wallet.ErrReasonRequired),
+		errors.Is(err, wallet.ErrActorRequired),
+		errors.Is(err, wallet.ErrUnsupportedCurrency):
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", err.Error())
+	default:
+		log.Printf("ADMIN ERROR: %v", err)
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Internal error.")
+	}
+}
+
+// writeFreeRoundError maps free round grant errors to HTTP responses.
+func writeFreeRoundError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, freerounds.ErrGrantNotFound):
+		writeError(w, http.StatusNotFound, "GRANT_NOT_FOUND", err.Error())
+	case errors.Is(err, freerounds.ErrInvalidGrant),
+		errors.Is(err, freerounds.ErrReasonRequired),
+		errors.Is(err, freerounds.ErrActorRequired),
+		errors.Is(err, freerounds.ErrUnsupportedCurrency):
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", err.Error())
+	default:
+		log.Printf("ADMIN ERROR: %v", err)
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Internal error.")
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, errorResponse{Error: code, Message: message})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("ADMIN: Failed to write response: %v", err)
+	}
+}
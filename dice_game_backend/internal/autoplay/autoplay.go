@@ -0,0 +1,139 @@
+// Package autoplay holds the betting strategies and stop rules of server-side
+// autoplay sessions. It does not play rounds itself; the handler drives a
+// Session through the normal settlement path.
+package autoplay
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Strategies.
+const (
+	StrategyFlat       = "flat"
+	StrategyMartingale = "martingale"
+	StrategyDAlembert  = "dalembert"
+)
+
+// MaxRounds caps the length of one autoplay session.
+const MaxRounds = 1000
+
+// Reasons a session stops.
+const (
+	StopCompleted = "completed"
+	StopRequested = "stopped"
+	StopOnWin     = "stop_on_win"
+	StopOnLoss    = "stop_on_loss"
+	StopError     = "error"
+)
+
+var (
+	Strategies  = []string{StrategyFlat, StrategyMartingale, StrategyDAlembert}
+	StopReasons = []string{StopCompleted, StopRequested, StopOnWin, StopOnLoss, StopError}
+
+	ErrInvalidStrategy = errors.New("invalid autoplay strategy")
+	ErrInvalidRounds   = errors.New("invalid autoplay round count")
+	ErrInvalidCap      = errors.New("invalid autoplay bet cap")
+	ErrInvalidStop     = errors.New("invalid autoplay stop threshold")
+)
+
+// Config describes one autoplay session.
+type Config struct {
+	BaseBet  int64
+	Rounds   int
+	Strategy string
+	// MaxBet caps progressive strategies.
+	MaxBet int64
+	// StopOnWin and StopOnLoss end the session once the net result reaches
+	// that profit or loss. Zero disables the rule.
+	StopOnWin  int64
+	StopOnLoss int64
+}
+
+// Validate checks a config, defaulting the strategy to flat.
+func (c *Config) Validate() error {
+	if c.Strategy == "" {
+		c.Strategy = StrategyFlat
+	}
+	switch c.Strategy {
+	case StrategyFlat, StrategyMartingale, StrategyDAlembert:
+	default:
+		return fmt.Errorf("%w: %q", ErrInvalidStrategy, c.Strategy)
+	}
+	if c.Rounds <= 0 || c.Rounds > MaxRounds {
+		return fmt.Errorf("%w: %d (1-%d)", ErrInvalidRounds, c.Rounds, MaxRounds)
+	}
+	if c.MaxBet < c.BaseBet {
+		return fmt.Errorf("%w: cap %d is below the base bet %d", ErrInvalidCap, c.MaxBet, c.BaseBet)
+	}
+	if c.StopOnWin < 0 || c.StopOnLoss < 0 {
+		return fmt.Errorf("%w: thresholds must not be negative", ErrInvalidStop)
+	}
+	return nil
+}
+
+// Session tracks the progress of one autoplay run.
+type Session struct {
+	cfg    Config
+	bet    int64
+	played int
+	net    int64
+}
+
+// NewSession starts a session from a validated config.
+func NewSession(cfg Config) *Session {
+	return &Session{cfg: cfg, bet: cfg.BaseBet}
+}
+
+// NextBet is the stake of the next round.
+func (s *Session) NextBet() int64 { return s.bet }
+
+// Played is the number of settled rounds.
+func (s *Session) Played() int { return s.played }
+
+// Net is the player's net result so far.
+func (s *Session) Net() int64 { return s.net }
+
+// Record applies a settled round, where net is the round's net result for the
+// player, and returns the stop reason if the session is over.
+func (s *Session) Record(won bool, net int64) (string, bool) {
+	s.played++
+	s.net += net
+	s.bet = s.progress(won)
+
+	switch {
+	case s.cfg.StopOnWin > 0 && s.net >= s.cfg.StopOnWin:
+		return StopOnWin, true
+	case s.cfg.StopOnLoss > 0 && -s.net >= s.cfg.StopOnLoss:
+		return StopOnLoss, true
+	case s.played >= s.cfg.Rounds:
+		return StopCompleted, true
+	}
+	return "", false
+}
+
+// progress returns the stake following a round under the session's strategy.
+func (s *Session) progress(won bool) int64 {
+	next := s.bet
+	switch s.cfg.Strategy {
+	case StrategyMartingale:
+		if won {
+			next = s.cfg.BaseBet
+		} else {
+			next = s.bet * 2
+		}
+	case StrategyDAlembert:
+		if won {
+			next = s.bet - s.cfg.BaseBet
+		} else {
+			next = s.bet + s.cfg.BaseBet
+		}
+	}
+	if next < s.cfg.BaseBet {
+		next = s.cfg.BaseBet
+	}
+	if next > s.cfg.MaxBet {
+		next = s.cfg.MaxBet
+	}
+	return next
+}
@@ -0,0 +1,120 @@
+package autoplay
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+const (
+	win  = true
+	loss = false
+)
+
+func TestNextBetFollowsStrategy(t *testing.T) {
+	cases := []struct {
+		name     string
+		strategy string
+		maxBet   int64
+		results  []bool
+		// want is the stake after each result.
+		want []int64
+	}{
+		{"flat keeps the base bet", StrategyFlat, 1000, []bool{loss, loss, win}, []int64{10, 10, 10}},
+		{"martingale doubles after a loss", StrategyMartingale, 1000, []bool{loss, loss, loss}, []int64{20, 40, 80}},
+		{"martingale resets after a win", StrategyMartingale, 1000, []bool{loss, loss, win, loss}, []int64{20, 40, 10, 20}},
+		{"martingale is capped at MaxBet", StrategyMartingale, 50, []bool{loss, loss, loss, loss, win}, []int64{20, 40, 50, 50, 10}},
+		{"d'Alembert steps up after a loss", StrategyDAlembert, 1000, []bool{loss, loss, loss}, []int64{20, 30, 40}},
+		{"d'Alembert steps down after a win", StrategyDAlembert, 1000, []bool{loss, loss, win, win}, []int64{20, 30, 20, 10}},
+		{"d'Alembert never goes below the base bet", StrategyDAlembert, 1000, []bool{win, win}, []int64{10, 10}},
+		{"d'Alembert is capped at MaxBet", StrategyDAlembert, 25, []bool{loss, loss, loss, win}, []int64{20, 25, 25, 15}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s := NewSession(Config{BaseBet: 10, Rounds: MaxRounds, Strategy: c.strategy, MaxBet: c.maxBet})
+			if got := s.NextBet(); got != 10 {
+				t.Fatalf("first bet = %d, want the base bet 10", got)
+			}
+			var got []int64
+			for _, won := range c.results {
+				s.Record(won, 0)
+				got = append(got, s.NextBet())
+			}
+			if !slices.Equal(got, c.want) {
+				t.Errorf("bets = %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestRecordStopsSession(t *testing.T) {
+	type round struct {
+		won bool
+		net int64
+	}
+	cases := []struct {
+		name   string
+		cfg    Config
+		rounds []round
+		want   string
+		played int
+	}{
+		{"stops once the loss reaches StopOnLoss", Config{Rounds: 10, StopOnLoss: 25},
+			[]round{{loss, -10}, {loss, -10}, {loss, -10}, {loss, -10}}, StopOnLoss, 3},
+		{"stops on a loss exactly at StopOnLoss", Config{Rounds: 10, StopOnLoss: 20},
+			[]round{{loss, -10}, {loss, -10}, {loss, -10}}, StopOnLoss, 2},
+		{"wins offset losses towards StopOnLoss", Config{Rounds: 10, StopOnLoss: 20},
+			[]round{{loss, -10}, {win, 8}, {loss, -10}, {loss, -10}}, StopOnLoss, 4},
+		{"stops once the profit reaches StopOnWin", Config{Rounds: 10, StopOnWin: 15},
+			[]round{{win, 8}, {win, 8}, {win, 8}}, StopOnWin, 2},
+		{"stops after the configured rounds", Config{Rounds: 3},
+			[]round{{win, 8}, {loss, -10}, {win, 8}, {win, 8}}, StopCompleted, 3},
+		{"profit stop wins over the last round", Config{Rounds: 2, StopOnWin: 16},
+			[]round{{win, 8}, {win, 8}}, StopOnWin, 2},
+		{"loss stop wins over the last round", Config{Rounds: 2, StopOnLoss: 20},
+			[]round{{loss, -10}, {loss, -10}}, StopOnLoss, 2},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			c.cfg.BaseBet, c.cfg.MaxBet = 10, 10
+			s := NewSession(c.cfg)
+			for _, r := range c.rounds {
+				reason, stopped := s.Record(r.won, r.net)
+				if !stopped {
+					continue
+				}
+				if reason != c.want || s.Played() != c.played {
+					t.Fatalf("stopped with %q after %d rounds, want %q after %d", reason, s.Played(), c.want, c.played)
+				}
+				return
+			}
+			t.Fatalf("session never stopped, want %q after %d rounds", c.want, c.played)
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		name string
+		cfg  Config
+		want error
+	}{
+		{"valid", Config{BaseBet: 10, Rounds: 5, MaxBet: 100}, nil},
+		{"unknown strategy", Config{BaseBet: 10, Rounds: 5, MaxBet: 100, Strategy: "labouchere"}, ErrInvalidStrategy},
+		{"no rounds", Config{BaseBet: 10, MaxBet: 100}, ErrInvalidRounds},
+		{"too many rounds", Config{BaseBet: 10, Rounds: MaxRounds + 1, MaxBet: 100}, ErrInvalidRounds},
+		{"cap below base bet", Config{BaseBet: 10, Rounds: 5, MaxBet: 5}, ErrInvalidCap},
+		{"negative stop", Config{BaseBet: 10, Rounds: 5, MaxBet: 100, StopOnLoss: -1}, ErrInvalidStop},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := c.cfg.Validate()
+			if !errors.Is(err, c.want) || (err == nil) != (c.want == nil) {
+				t.Fatalf("Validate() = %v, want %v", err, c.want)
+			}
+			if err == nil && c.cfg.Strategy != StrategyFlat {
+				t.Errorf("strategy defaulted to %q, want %q", c.cfg.Strategy, StrategyFlat)
+			}
+		})
+	}
+}
@@ -1,21 +1,33 @@
 package config
 
 import (
+	"errors"
 	"flag"
+	"fmt"
 	"log"
+	"math"
 	"os"
+	"regexp"
+	"slices"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/BrunoSena97/dice_game_backend/internal/constants"
+	"github.com/BrunoSena97/dice_game_backend/internal/jackpot"
 	"github.com/BrunoSena97/dice_game_backend/internal/platform/database"
 	redisPlatform "github.com/BrunoSena97/dice_game_backend/internal/platform/redis"
+	"github.com/BrunoSena97/dice_game_backend/internal/playlock"
+	"github.com/BrunoSena97/dice_game_backend/internal/settlement"
+	"github.com/BrunoSena97/dice_game_backend/internal/wallet"
 	"github.com/joho/godotenv"
 )
 
 // Environment Variable Keys
 const (
 	envDevMode      = "dev"
+	envConfigFile   = "CONFIG_FILE"
 	envDBHostDev    = "DB_HOST_DEV"
 	envDBPortDev    = "DB_PORT_DEV"
 	envDBHost       = "DB_HOST"
@@ -28,27 +40,213 @@ const (
 	envRedisAddr    = "REDIS_ADDR"
 	envRedisPass    = "REDIS_PASSWORD"
 	envRedisDB      = "REDIS_DB"
-	envListenPort   = "LISTEN_PORT"
-	envMaxBet       = "MAX_BET_AMOUNT"
+	// REDIS_ADDR is a comma separated seed list in sentinel and cluster modes.
+	envRedisMode             = "REDIS_MODE"
+	envRedisMasterName       = "REDIS_MASTER_NAME"
+	envRedisUsername         = "REDIS_USERNAME"
+	envRedisSentinelUsername = "REDIS_SENTINEL_USERNAME"
+	envRedisSentinelPass     = "REDIS_SENTINEL_PASSWORD"
+	envRedisTLS              = "REDIS_TLS"
+	envRedisTLSCAFile        = "REDIS_TLS_CA_FILE"
+	envRedisTLSServerName    = "REDIS_TLS_SERVER_NAME"
+	// Degraded mode.
+	envRedisStartupMode         = "REDIS_STARTUP_MODE"
+	envRedisHealthCheckInterval = "REDIS_HEALTH_CHECK_INTERVAL"
+	envPlayLockFallback         = "PLAY_LOCK_FALLBACK"
+	envListenPort               = "LISTEN_PORT"
+	envMaxBet                   = "MAX_BET_AMOUNT"
+	envCurrencies               = "CURRENCIES"
+	envDefaultCur               = "DEFAULT_CURRENCY"
+	// Per-currency overrides are suffixed with the currency code, ex: MAX_BET_AMOUNT_PTS.
+	envMinBetPrefix         = "MIN_BET_AMOUNT_"
+	envMaxBetPrefix         = "MAX_BET_AMOUNT_"
+	envInitialBalancePrefix = "INITIAL_BALANCE_"
+	// Per-bet-type payouts are suffixed with the bet type, ex: PAYOUT_PERCENT_LT7.
+	envPayoutPrefix         = "PAYOUT_PERCENT_"
+	envAdminAPIToken        = "ADMIN_API_TOKEN"
+	envFeedBigWinThreshold  = "FEED_BIG_WIN_THRESHOLD"
+	envLimitCoolingOffHours = "LIMIT_COOLING_OFF_HOURS"
+	envRateLimitPerSecond   = "RATE_LIMIT_PER_SECOND"
+	envRateLimitBurst       = "RATE_LIMIT_BURST"
+	// Connection pools. Durations use Go syntax, ex: 30s, 5m.
+	envDBMaxConns          = "DB_MAX_CONNS"
+	envDBMinConns          = "DB_MIN_CONNS"
+	envDBMaxConnLifetime   = "DB_MAX_CONN_LIFETIME"
+	envDBMaxConnIdleTime   = "DB_MAX_CONN_IDLE_TIME"
+	envDBHealthCheckPeriod = "DB_HEALTH_CHECK_PERIOD"
+	envDBConnectTimeout    = "DB_CONNECT_TIMEOUT"
+	envDBAutoMigrate       = "DB_AUTO_MIGRATE"
+	envDBPlayLockConns     = "DB_PLAY_LOCK_CONNS"
+	envDBReplicaHost       = "DB_REPLICA_HOST"
+	envDBReplicaPort       = "DB_REPLICA_PORT"
+	envDBReplicaMaxLag     = "DB_REPLICA_MAX_LAG"
+	envDBReplicaCheck      = "DB_REPLICA_CHECK_INTERVAL"
+	envRedisPoolSize       = "REDIS_POOL_SIZE"
+	envRedisMinIdleConns   = "REDIS_MIN_IDLE_CONNS"
+	envRedisDialTimeout    = "REDIS_DIAL_TIMEOUT"
+	envRedisReadTimeout    = "REDIS_READ_TIMEOUT"
+	envRedisWriteTimeout   = "REDIS_WRITE_TIMEOUT"
+	envRedisPoolTimeout    = "REDIS_POOL_TIMEOUT"
+	envRedisConnectTimeout = "REDIS_CONNECT_TIMEOUT"
+	envPoolStatsInterval   = "POOL_STATS_INTERVAL"
+	envBalanceCacheTTL     = "BALANCE_CACHE_TTL"
+	// Round settlement.
+	envSettlementMode          = "SETTLEMENT_MODE"
+	envSettlementBatchSize     = "SETTLEMENT_BATCH_SIZE"
+	envSettlementFlushInterval = "SETTLEMENT_FLUSH_INTERVAL"
+	// Bonus balances.
+	envBonusStakeOrder         = "BONUS_STAKE_ORDER"
+	envBonusWageringMultiplier = "BONUS_WAGERING_MULTIPLIER"
+	// Jackpot.
+	envJackpotEnabled             = "JACKPOT_ENABLED"
+	envJackpotContributionPercent = "JACKPOT_CONTRIBUTION_PERCENT"
+	envJackpotMinBetAmount        = "JACKPOT_MIN_BET_AMOUNT"
+	envJackpotSeed                = "JACKPOT_SEED"
+	envJackpotBroadcastInterval   = "JACKPOT_BROADCAST_INTERVAL"
+	// Server timeouts.
+	envHTTPReadTimeout    = "HTTP_READ_TIMEOUT"
+	envHTTPWriteTimeout   = "HTTP_WRITE_TIMEOUT"
+	envHTTPIdleTimeout    = "HTTP_IDLE_TIMEOUT"
+	envShutdownTimeout    = "SHUTDOWN_TIMEOUT"
+	envHandlerOpTimeout   = "HANDLER_OP_TIMEOUT"
+	envShortOpTimeout     = "SHORT_OP_TIMEOUT"
+	envPlayLockTTL        = "PLAY_LOCK_TTL"
+	envLockReleaseTimeout = "LOCK_RELEASE_TIMEOUT"
+	envWSWriteTimeout     = "WS_WRITE_TIMEOUT"
 )
 
+// secretEnvKeys are never echoed to the log.
+var secretEnvKeys = map[string]bool{
+	envDBPassword:        true,
+	envRedisPass:         true,
+	envRedisSentinelPass: true,
+	envAdminAPIToken:     true,
+}
+
+var currencyCodePattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
 type Config struct {
 	DB        database.Config
 	Redis     redisPlatform.Config
 	App       AppConfig
+	Admin     AdminConfig
 	IsDevMode bool
+	// Path is the config file the configuration was read from, if any.
+	Path string
+}
+
+// AdminConfig configures the support HTTP API. It is disabled when APIToken is empty.
+type AdminConfig struct {
+	APIToken string
 }
 
 type AppConfig struct {
-	ListenPort   string
-	MaxBetAmount int64
-	ReadTimeout  time.Duration
-	WriteTimeout time.Duration
-	IdleTimeout  time.Duration
+	ListenPort      string
+	DefaultCurrency string
+	Currencies      map[string]CurrencyConfig
+	// Paytable maps each bet type to its winnings as a percentage of the stake.
+	Paytable map[string]int64
+	// RateLimit throttles the messages each WebSocket connection may send.
+	RateLimit RateLimitConfig
+	// FeedBigWinThreshold is the minimum net win flagged as a big win in the live feed.
+	FeedBigWinThreshold int64
+	// LimitCoolingOff is how long a loosened responsible gaming limit stays pending.
+	LimitCoolingOff time.Duration
+	Timeouts        TimeoutConfig
+	// PoolStatsInterval is how often database and Redis pool saturation is checked.
+	PoolStatsInterval time.Duration
+	// PlayLockFallback is where play locks are taken while Redis is down, one of playlock.Fallbacks.
+	PlayLockFallback string
+	// BalanceCacheTTL is how long a balance stays in the Redis balance cache after
+	// its last change. Zero disables the cache.
+	BalanceCacheTTL time.Duration
+	Settlement      SettlementConfig
+	Bonus           BonusConfig
+	Jackpot         JackpotConfig
+}
+
+// JackpotConfig turns on the jackpot pools, funded and won as set out in
+// jackpot.Config.
+type JackpotConfig struct {
+	Enabled bool
+	jackpot.Config
+}
+
+// BonusConfig sets how bonus balances are played. StakeOrder is one of
+// wallet.StakeOrders; a granted bonus must be wagered WageringMultiplier times
+// its amount, unless the grant sets its own multiplier.
+type BonusConfig struct {
+	StakeOrder         string
+	WageringMultiplier int64
+}
+
+// SettlementConfig chooses how rounds are written. In batch mode up to
+// BatchSize rounds are settled together, at most FlushInterval after the
+// first of them was played.
+type SettlementConfig struct {
+	// Mode is one of settlement.Modes.
+	Mode          string
+	BatchSize     int
+	FlushInterval time.Duration
+}
+
+// TimeoutConfig bounds the HTTP server and the work done per WebSocket message.
+type TimeoutConfig struct {
+	HTTPRead  time.Duration
+	HTTPWrite time.Duration
+	HTTPIdle  time.Duration
+	Shutdown  time.Duration
+	// HandlerOp bounds a whole play; ShortOp single reads and writes.
+	HandlerOp time.Duration
+	ShortOp   time.Duration
+	// PlayLock is the TTL of the per-player play lock. It must outlive HandlerOp
+	// so that a slow play never loses its lock midway.
+	PlayLock    time.Duration
+	LockRelease time.Duration
+	WSWrite     time.Duration
+}
+
+// CurrencyConfig holds the wallet and betting limits for one currency.
+type CurrencyConfig struct {
+	MinBetAmount   int64
+	MaxBetAmount   int64
+	InitialBalance int64
+}
+
+// RateLimitConfig is a token bucket: PerSecond tokens are added each second up
+// to Burst. A PerSecond of zero disables rate limiting.
+type RateLimitConfig struct {
+	PerSecond float64
+	Burst     int
 }
 
+// CurrencyCodes returns the configured currency codes, default currency first.
+func (a AppConfig) CurrencyCodes() []string {
+	codes := []string{a.DefaultCurrency}
+	for code := range a.Currencies {
+		if code != a.DefaultCurrency {
+			codes = append(codes, code)
+		}
+	}
+	sort.Strings(codes[1:])
+	return codes
+}
+
+// InitialBalances maps each currency to the balance new wallets start with.
+func (a AppConfig) InitialBalances() map[string]int64 {
+	balances := make(map[string]int64, len(a.Currencies))
+	for code, cur := range a.Currencies {
+		balances[code] = cur.InitialBalance
+	}
+	return balances
+}
+
+// LoadConfig parses the command line flags and loads the configuration.
+// A config file can be given with -config or CONFIG_FILE; environment variables
+// override values from the file.
 func LoadConfig() (*Config, error) {
 	devModePtr := flag.Bool(envDevMode, false, "Enable development mode defaults")
+	configPathPtr := flag.String("config", "", "Path to a YAML config file (overrides CONFIG_FILE)")
 	flag.Parse()
 	isDev := *devModePtr
 
@@ -59,73 +257,503 @@ func LoadConfig() (*Config, error) {
 		}
 	}
 
-	// Database configuration
-	var dbHost string
-	var dbPort int
+	path := *configPathPtr
+	if path == "" {
+		path = strings.TrimSpace(os.Getenv(envConfigFile))
+	}
+	return Load(path, isDev)
+}
+
+// Load builds the configuration from defaults, the optional YAML file at path
+// and environment variables, in increasing order of precedence. Every invalid
+// value is reported; nothing silently falls back.
+func Load(path string, isDev bool) (*Config, error) {
+	var file fileConfig
+	if path != "" {
+		var err error
+		if file, err = readFile(path); err != nil {
+			return nil, err
+		}
+		log.Printf("Loaded config file %s", path)
+	}
+
+	l := &loader{}
+
+	// Dev mode runs the server on the host against the Compose containers, so
+	// it reads the host-side variables and defaults to the published ports.
+	// The config file still overrides those defaults.
+	dbHostKey, dbHostDefault := envDBHost, "db"
+	dbPortKey, dbPortDefault := envDBPort, 5432
+	redisAddrKey, redisAddrDefault := envRedisAddr, "redis:6379"
 	if isDev {
-		dbHost = getEnv(envDBHostDev, "localhost")
-		dbPort = parseEnvInt(envDBPortDev, 5433)
-	} else {
-		dbHost = getEnv(envDBHost, "db")
-		dbPort = parseEnvInt(envDBPort, 5432)
+		dbHostKey, dbHostDefault = envDBHostDev, "localhost"
+		dbPortKey, dbPortDefault = envDBPortDev, 5433
+		redisAddrKey, redisAddrDefault = envRedisAddrDev, "localhost:6380"
 	}
 
+	// Database configuration
 	dbCfg := database.Config{
-		Host:     dbHost,
-		Port:     dbPort,
-		User:     getEnv(envDBUser, "postgres"),
-		Password: getEnv(envDBPassword, ""),
-		DBName:   getEnv(envDBName, "postgres"),
-		SSLMode:  getEnv(envDBSSLMode, "disable"),
+		Host:     l.str(dbHostKey, file.Database.Host, dbHostDefault),
+		Port:     l.int(dbPortKey, file.Database.Port, dbPortDefault),
+		User:     l.str(envDBUser, file.Database.User, "postgres"),
+		Password: l.str(envDBPassword, file.Database.Password, ""),
+		DBName:   l.str(envDBName, file.Database.Name, "postgres"),
+		SSLMode:  l.str(envDBSSLMode, file.Database.SSLMode, "disable"),
 	}
+	l.check(dbCfg.Port > 0 && dbCfg.Port <= 65535, "database port %d is out of range", dbCfg.Port)
+	l.dbPool(&dbCfg, file)
+	dbCfg.AutoMigrate = l.bool(envDBAutoMigrate, file.Database.AutoMigrate, false)
+	l.dbReplica(&dbCfg, file)
 
 	// Redis configuration
-	redisCfg := redisPlatform.Config{
-		Addr:     getEnv(envRedisAddr, "redis:6379"),
-		Password: getEnv(envRedisPass, ""),
-		DB:       getEnv(envRedisDB, "0"),
+	redisCfg := l.redis(l.str(redisAddrKey, file.Redis.Addr, redisAddrDefault), file)
+	l.redisPool(&redisCfg, file)
+
+	// Currency configuration
+	defaultCurrency, currencies := l.currencies(file)
+
+	// Game configuration
+	paytable := l.paytable(file)
+
+	coolingOffHours := l.int(envLimitCoolingOffHours, file.LimitCoolingOffHours, 24)
+	l.check(coolingOffHours >= 0, "invalid %s: %d must not be negative", envLimitCoolingOffHours, coolingOffHours)
+
+	rateLimit := RateLimitConfig{
+		PerSecond: l.float(envRateLimitPerSecond, file.RateLimit.PerSecond, 20),
+		Burst:     l.int(envRateLimitBurst, file.RateLimit.Burst, 40),
 	}
-	if isDev {
-		redisCfg.Addr = getEnv(envRedisAddrDev, "localhost:6380")
+	l.check(rateLimit.PerSecond >= 0, "invalid %s: %g must not be negative", envRateLimitPerSecond, rateLimit.PerSecond)
+	l.check(rateLimit.PerSecond == 0 || rateLimit.Burst >= 1, "invalid %s: %d must be at least 1", envRateLimitBurst, rateLimit.Burst)
+
+	listenPort := l.str(envListenPort, file.ListenPort, "8080")
+	if port, err := strconv.Atoi(listenPort); err != nil || port <= 0 || port > 65535 {
+		l.fail("invalid %s %q: must be a port number", envListenPort, listenPort)
 	}
 
 	// Application configuration
 	appCfg := AppConfig{
-		ListenPort:   getEnv(envListenPort, "8080"),
-		MaxBetAmount: int64(parseEnvInt(envMaxBet, 250)),
-		ReadTimeout:  time.Duration(constants.DefaultReadTimeout) * time.Second,
-		WriteTimeout: time.Duration(constants.DefaultWriteTimeout) * time.Second,
-		IdleTimeout:  time.Duration(constants.DefaultIdleTimeout) * time.Second,
+		ListenPort:          listenPort,
+		DefaultCurrency:     defaultCurrency,
+		Currencies:          currencies,
+		Paytable:            paytable,
+		RateLimit:           rateLimit,
+		FeedBigWinThreshold: int64(l.int(envFeedBigWinThreshold, file.FeedBigWinThreshold, 100)),
+		LimitCoolingOff:     time.Duration(coolingOffHours) * time.Hour,
+		Timeouts:            l.timeouts(file),
+		PoolStatsInterval:   l.duration(envPoolStatsInterval, file.PoolStatsInterval, time.Minute),
+		PlayLockFallback:    strings.ToLower(l.str(envPlayLockFallback, file.PlayLockFallback, playlock.FallbackPostgres)),
+		BalanceCacheTTL:     l.duration(envBalanceCacheTTL, file.BalanceCacheTTL, 5*time.Minute),
+	}
+	appCfg.Settlement = l.settlement(file, appCfg.Timeouts)
+	appCfg.Bonus = l.bonus(file)
+	appCfg.Jackpot = l.jackpot(file)
+	l.check(slices.Contains(playlock.Fallbacks, appCfg.PlayLockFallback),
+		"invalid %s %q: must be one of %s", envPlayLockFallback, appCfg.PlayLockFallback, strings.Join(playlock.Fallbacks, ", "))
+	l.check(appCfg.FeedBigWinThreshold >= 0, "invalid %s: %d must not be negative", envFeedBigWinThreshold, appCfg.FeedBigWinThreshold)
+	l.check(appCfg.PoolStatsInterval > 0, "invalid %s: %v must be positive", envPoolStatsInterval, appCfg.PoolStatsInterval)
+	l.check(appCfg.BalanceCacheTTL == 0 || appCfg.BalanceCacheTTL >= time.Millisecond,
+		"invalid %s: %v must be 0 (disabled) or at least 1ms", envBalanceCacheTTL, appCfg.BalanceCacheTTL)
+
+	if err := l.err(); err != nil {
+		return nil, err
 	}
 
 	cfg := &Config{
 		DB:        dbCfg,
 		Redis:     redisCfg,
 		App:       appCfg,
+		Admin:     AdminConfig{APIToken: l.str(envAdminAPIToken, file.Admin.APIToken, "")},
 		IsDevMode: isDev,
+		Path:      path,
 	}
 
 	return cfg, nil
 }
 
-// getEnv retrieves an environment variable or returns a fallback value
-func getEnv(key, fallback string) string {
-	if value, exists := os.LookupEnv(key); exists && value != "" {
+// currencies reads the enabled currencies and their per-currency limits.
+// MAX_BET_AMOUNT remains the max bet of the default currency for backwards compatibility.
+func (l *loader) currencies(file fileConfig) (string, map[string]CurrencyConfig) {
+	fileCodes := make([]string, 0, len(file.Currencies))
+	for code := range file.Currencies {
+		fileCodes = append(fileCodes, code)
+	}
+	sort.Strings(fileCodes)
+
+	defaultCurrency := strings.ToUpper(l.str(envDefaultCur, file.DefaultCurrency, constants.DefaultCurrency))
+	codeList := strings.Join(fileCodes, ",")
+	if codeList == "" {
+		codeList = defaultCurrency
+	}
+	codes := strings.Split(strings.ToUpper(l.str(envCurrencies, "", codeList)), ",")
+
+	currencies := make(map[string]CurrencyConfig, len(codes))
+	for _, code := range codes {
+		code = strings.TrimSpace(code)
+		if code == "" {
+			continue
+		}
+		if !currencyCodePattern.MatchString(code) {
+			l.fail("invalid currency code %q in %s: must be 3 uppercase letters", code, envCurrencies)
+			continue
+		}
+
+		fc := file.Currencies[code]
+		maxBetKey := envMaxBetPrefix + code
+		if _, set := lookupEnv(maxBetKey); !set && code == defaultCurrency {
+			if _, legacySet := lookupEnv(envMaxBet); legacySet {
+				maxBetKey = envMaxBet
+			}
+		}
+		maxBet := l.int(maxBetKey, fc.MaxBetAmount, 250)
+		cur := CurrencyConfig{
+			MinBetAmount:   int64(l.int(envMinBetPrefix+code, fc.MinBetAmount, 1)),
+			MaxBetAmount:   int64(maxBet),
+			InitialBalance: int64(l.int(envInitialBalancePrefix+code, fc.InitialBalance, constants.DefaultInitialBalance)),
+		}
+		l.check(cur.MinBetAmount > 0 && cur.MaxBetAmount >= cur.MinBetAmount,
+			"invalid bet limits for %s: min %d, max %d", code, cur.MinBetAmount, cur.MaxBetAmount)
+		l.check(cur.InitialBalance >= 0, "invalid initial balance for %s: %d", code, cur.InitialBalance)
+		currencies[code] = cur
+	}
+
+	for _, code := range fileCodes {
+		if _, ok := currencies[code]; !ok {
+			l.fail("currency %s is configured in the config file but not enabled in %s", code, envCurrencies)
+		}
+	}
+	if _, ok := currencies[defaultCurrency]; !ok {
+		l.fail("default currency %s is not listed in %s", defaultCurrency, envCurrencies)
+	}
+	return defaultCurrency, currencies
+}
+
+// dbPool reads the database pool size and connection lifetimes.
+func (l *loader) dbPool(cfg *database.Config, file fileConfig) {
+	fp := file.Database
+	maxConns := l.int(envDBMaxConns, fp.MaxConns, 10)
+	minConns := l.int(envDBMinConns, fp.MinConns, 0)
+	l.check(maxConns >= 1 && maxConns <= math.MaxInt32, "invalid %s: %d must be at least 1", envDBMaxConns, maxConns)
+	l.check(minConns >= 0 && minConns <= maxConns, "invalid %s: %d must be between 0 and %s (%d)", envDBMinConns, minConns, envDBMaxConns, maxConns)
+	cfg.MaxConns = int32(maxConns)
+	cfg.MinConns = int32(minConns)
+	playLockConns := l.int(envDBPlayLockConns, fp.PlayLockConns, 5)
+	l.check(playLockConns >= 1 && playLockConns <= math.MaxInt32, "invalid %s: %d must be at least 1", envDBPlayLockConns, playLockConns)
+	cfg.PlayLockConns = int32(playLockConns)
+
+	cfg.MaxConnLifetime = l.duration(envDBMaxConnLifetime, fp.MaxConnLifetime, time.Hour)
+	cfg.MaxConnIdleTime = l.duration(envDBMaxConnIdleTime, fp.MaxConnIdleTime, 30*time.Minute)
+	cfg.HealthCheckPeriod = l.duration(envDBHealthCheckPeriod, fp.HealthCheckPeriod, time.Minute)
+	cfg.ConnectTimeout = l.duration(envDBConnectTimeout, fp.ConnectTimeout, time.Duration(constants.DBConnectTimeout)*time.Second)
+	l.positive(envDBMaxConnLifetime, cfg.MaxConnLifetime)
+	l.positive(envDBMaxConnIdleTime, cfg.MaxConnIdleTime)
+	l.positive(envDBHealthCheckPeriod, cfg.HealthCheckPeriod)
+	l.positive(envDBConnectTimeout, cfg.ConnectTimeout)
+}
+
+// dbReplica reads the optional read replica. It defaults to the primary's port.
+func (l *loader) dbReplica(cfg *database.Config, file fileConfig) {
+	fr := file.Database.Replica
+	cfg.ReplicaHost = l.str(envDBReplicaHost, fr.Host, "")
+	cfg.ReplicaPort = l.int(envDBReplicaPort, fr.Port, cfg.Port)
+	cfg.ReplicaMaxLag = l.duration(envDBReplicaMaxLag, fr.MaxLag, 5*time.Second)
+	cfg.ReplicaCheckInterval = l.duration(envDBReplicaCheck, fr.CheckInterval, time.Second)
+	l.check(cfg.ReplicaPort > 0 && cfg.ReplicaPort <= 65535, "invalid %s: %d is out of range", envDBReplicaPort, cfg.ReplicaPort)
+	l.positive(envDBReplicaMaxLag, cfg.ReplicaMaxLag)
+	l.positive(envDBReplicaCheck, cfg.ReplicaCheckInterval)
+}
+
+// redis reads the Redis deployment mode, addresses, credentials and TLS settings.
+func (l *loader) redis(addr string, file fileConfig) redisPlatform.Config {
+	fr := file.Redis
+	cfg := redisPlatform.Config{
+		Mode:             strings.ToLower(l.str(envRedisMode, fr.Mode, redisPlatform.ModeStandalone)),
+		MasterName:       l.str(envRedisMasterName, fr.MasterName, ""),
+		Username:         l.str(envRedisUsername, fr.Username, ""),
+		Password:         l.str(envRedisPass, fr.Password, ""),
+		SentinelUsername: l.str(envRedisSentinelUsername, fr.SentinelUsername, ""),
+		SentinelPassword: l.str(envRedisSentinelPass, fr.SentinelPassword, ""),
+		DB:               l.int(envRedisDB, fr.DB, 0),
+		TLS: redisPlatform.TLSConfig{
+			Enabled:    l.bool(envRedisTLS, fr.TLS.Enabled, false),
+			CAFile:     l.str(envRedisTLSCAFile, fr.TLS.CAFile, ""),
+			ServerName: l.str(envRedisTLSServerName, fr.TLS.ServerName, ""),
+		},
+	}
+	for _, a := range strings.Split(addr, ",") {
+		if a = strings.TrimSpace(a); a != "" {
+			cfg.Addrs = append(cfg.Addrs, a)
+		}
+	}
+
+	switch cfg.Mode {
+	case redisPlatform.ModeStandalone:
+		l.check(len(cfg.Addrs) == 1, "invalid %s %q: standalone mode takes exactly one address", envRedisAddr, addr)
+	case redisPlatform.ModeSentinel:
+		l.check(len(cfg.Addrs) >= 1, "invalid %s: sentinel mode needs at least one Sentinel address", envRedisAddr)
+		l.check(cfg.MasterName != "", "%s is required in sentinel mode", envRedisMasterName)
+	case redisPlatform.ModeCluster:
+		l.check(len(cfg.Addrs) >= 1, "invalid %s: cluster mode needs at least one node address", envRedisAddr)
+		l.check(cfg.DB == 0, "invalid %s: %d, Redis Cluster only supports database 0", envRedisDB, cfg.DB)
+	default:
+		l.fail("invalid %s %q: must be one of %s", envRedisMode, cfg.Mode, strings.Join(redisPlatform.Modes, ", "))
+	}
+	l.check(cfg.MasterName == "" || cfg.Mode == redisPlatform.ModeSentinel, "%s is only used in sentinel mode", envRedisMasterName)
+	l.check(cfg.DB >= 0, "invalid %s: %d must not be negative", envRedisDB, cfg.DB)
+
+	cfg.StartupMode = strings.ToLower(l.str(envRedisStartupMode, fr.StartupMode, redisPlatform.StartupDegraded))
+	l.check(slices.Contains(redisPlatform.StartupModes, cfg.StartupMode),
+		"invalid %s %q: must be one of %s", envRedisStartupMode, cfg.StartupMode, strings.Join(redisPlatform.StartupModes, ", "))
+	cfg.HealthCheckInterval = l.duration(envRedisHealthCheckInterval, fr.HealthCheckInterval, 5*time.Second)
+	l.positive(envRedisHealthCheckInterval, cfg.HealthCheckInterval)
+
+	if cfg.TLS.CAFile != "" {
+		l.check(cfg.TLS.Enabled, "%s is set but %s is not enabled", envRedisTLSCAFile, envRedisTLS)
+		if _, err := os.Stat(cfg.TLS.CAFile); err != nil {
+			l.fail("invalid %s: %v", envRedisTLSCAFile, err)
+		}
+	}
+	return cfg
+}
+
+// redisPool reads the Redis pool size and command timeouts.
+func (l *loader) redisPool(cfg *redisPlatform.Config, file fileConfig) {
+	fp := file.Redis
+	cfg.PoolSize = l.int(envRedisPoolSize, fp.PoolSize, 10)
+	cfg.MinIdleConns = l.int(envRedisMinIdleConns, fp.MinIdleConns, 0)
+	l.check(cfg.PoolSize >= 1, "invalid %s: %d must be at least 1", envRedisPoolSize, cfg.PoolSize)
+	l.check(cfg.MinIdleConns >= 0 && cfg.MinIdleConns <= cfg.PoolSize,
+		"invalid %s: %d must be between 0 and %s (%d)", envRedisMinIdleConns, cfg.MinIdleConns, envRedisPoolSize, cfg.PoolSize)
+
+	cfg.DialTimeout = l.duration(envRedisDialTimeout, fp.DialTimeout, 5*time.Second)
+	cfg.ReadTimeout = l.duration(envRedisReadTimeout, fp.ReadTimeout, 3*time.Second)
+	cfg.WriteTimeout = l.duration(envRedisWriteTimeout, fp.WriteTimeout, 3*time.Second)
+	cfg.PoolTimeout = l.duration(envRedisPoolTimeout, fp.PoolTimeout, 4*time.Second)
+	cfg.ConnectTimeout = l.duration(envRedisConnectTimeout, fp.ConnectTimeout, time.Duration(constants.RedisConnectTimeout)*time.Second)
+	l.positive(envRedisDialTimeout, cfg.DialTimeout)
+	l.positive(envRedisReadTimeout, cfg.ReadTimeout)
+	l.positive(envRedisWriteTimeout, cfg.WriteTimeout)
+	l.positive(envRedisPoolTimeout, cfg.PoolTimeout)
+	l.positive(envRedisConnectTimeout, cfg.ConnectTimeout)
+}
+
+// timeouts reads the server timeouts and checks they nest: single operations
+// fit inside a play, and a play fits inside its lock.
+func (l *loader) timeouts(file fileConfig) TimeoutConfig {
+	ft := file.Timeouts
+	seconds := func(n int) time.Duration { return time.Duration(n) * time.Second }
+	t := TimeoutConfig{
+		HTTPRead:    l.duration(envHTTPReadTimeout, ft.HTTPRead, seconds(constants.DefaultReadTimeout)),
+		HTTPWrite:   l.duration(envHTTPWriteTimeout, ft.HTTPWrite, seconds(constants.DefaultWriteTimeout)),
+		HTTPIdle:    l.duration(envHTTPIdleTimeout, ft.HTTPIdle, seconds(constants.DefaultIdleTimeout)),
+		Shutdown:    l.duration(envShutdownTimeout, ft.Shutdown, seconds(constants.ShutdownTimeout)),
+		HandlerOp:   l.duration(envHandlerOpTimeout, ft.HandlerOp, seconds(constants.HandlerOpTimeout)),
+		ShortOp:     l.duration(envShortOpTimeout, ft.ShortOp, seconds(constants.ShortOpTimeout)),
+		PlayLock:    l.duration(envPlayLockTTL, ft.PlayLock, seconds(constants.RedisLockTimeout)),
+		LockRelease: l.duration(envLockReleaseTimeout, ft.LockRelease, seconds(constants.RedisDelTimeout)),
+		WSWrite:     l.duration(envWSWriteTimeout, ft.WSWrite, seconds(constants.WSWriteTimeout)),
+	}
+	l.positive(envHTTPReadTimeout, t.HTTPRead)
+	l.positive(envHTTPWriteTimeout, t.HTTPWrite)
+	l.positive(envHTTPIdleTimeout, t.HTTPIdle)
+	l.positive(envShutdownTimeout, t.Shutdown)
+	l.positive(envHandlerOpTimeout, t.HandlerOp)
+	l.positive(envShortOpTimeout, t.ShortOp)
+	l.positive(envLockReleaseTimeout, t.LockRelease)
+	l.positive(envWSWriteTimeout, t.WSWrite)
+	l.check(t.ShortOp <= t.HandlerOp, "invalid %s: %v must not exceed %s (%v)", envShortOpTimeout, t.ShortOp, envHandlerOpTimeout, t.HandlerOp)
+	l.check(t.PlayLock > t.HandlerOp, "invalid %s: %v must exceed %s (%v)", envPlayLockTTL, t.PlayLock, envHandlerOpTimeout, t.HandlerOp)
+	return t
+}
+
+// settlement reads the round settlement mode. A batch must be flushed well
+// within a play's timeout, and a play that times out while its batch is being
+// written keeps its lock for up to one more ShortOp.
+func (l *loader) settlement(file fileConfig, timeouts TimeoutConfig) SettlementConfig {
+	fs := file.Settlement
+	s := SettlementConfig{
+		Mode:          strings.ToLower(l.str(envSettlementMode, fs.Mode, settlement.ModeImmediate)),
+		BatchSize:     l.int(envSettlementBatchSize, fs.BatchSize, 100),
+		FlushInterval: l.duration(envSettlementFlushInterval, fs.FlushInterval, 5*time.Millisecond),
+	}
+	l.check(slices.Contains(settlement.Modes, s.Mode),
+		"invalid %s %q: must be one of %s", envSettlementMode, s.Mode, strings.Join(settlement.Modes, ", "))
+	l.check(s.BatchSize >= 1, "invalid %s: %d must be at least 1", envSettlementBatchSize, s.BatchSize)
+	l.positive(envSettlementFlushInterval, s.FlushInterval)
+	l.check(s.FlushInterval < timeouts.ShortOp, "invalid %s: %v must be below %s (%v)",
+		envSettlementFlushInterval, s.FlushInterval, envShortOpTimeout, timeouts.ShortOp)
+	l.check(s.Mode != settlement.ModeBatch || timeouts.PlayLock > timeouts.HandlerOp+timeouts.ShortOp,
+		"invalid %s: %v must exceed %s plus %s (%v) in %s settlement mode",
+		envPlayLockTTL, timeouts.PlayLock, envHandlerOpTimeout, envShortOpTimeout, timeouts.HandlerOp+timeouts.ShortOp, settlement.ModeBatch)
+	return s
+}
+
+// bonus reads how stakes draw on bonus balances and the default wagering requirement.
+func (l *loader) bonus(file fileConfig) BonusConfig {
+	fb := file.Bonus
+	b := BonusConfig{
+		StakeOrder:         strings.ToLower(l.str(envBonusStakeOrder, fb.StakeOrder, wallet.StakeCashFirst)),
+		WageringMultiplier: int64(l.int(envBonusWageringMultiplier, fb.WageringMultiplier, 10)),
+	}
+	l.check(slices.Contains(wallet.StakeOrders, b.StakeOrder),
+		"invalid %s %q: must be one of %s", envBonusStakeOrder, b.StakeOrder, strings.Join(wallet.StakeOrders, ", "))
+	l.check(b.WageringMultiplier >= 0, "invalid %s: %d must not be negative", envBonusWageringMultiplier, b.WageringMultiplier)
+	return b
+}
+
+// jackpot reads how the jackpot pools are funded, won and broadcast.
+func (l *loader) jackpot(file fileConfig) JackpotConfig {
+	fj := file.Jackpot
+	j := JackpotConfig{
+		Enabled: l.bool(envJackpotEnabled, fj.Enabled, false),
+		Config: jackpot.Config{
+			ContributionPercent: l.float(envJackpotContributionPercent, fj.ContributionPercent, 1),
+			MinBetAmount:        int64(l.int(envJackpotMinBetAmount, fj.MinBetAmount, 10)),
+			Seed:                int64(l.int(envJackpotSeed, fj.Seed, 1000)),
+			BroadcastInterval:   l.duration(envJackpotBroadcastInterval, fj.BroadcastInterval, time.Second),
+		},
+	}
+	l.check(j.ContributionPercent >= 0 && j.ContributionPercent < 100,
+		"invalid %s: %g must be at least 0 and below 100", envJackpotContributionPercent, j.ContributionPercent)
+	l.check(j.MinBetAmount >= 0, "invalid %s: %d must not be negative", envJackpotMinBetAmount, j.MinBetAmount)
+	l.check(j.Seed >= 0, "invalid %s: %d must not be negative", envJackpotSeed, j.Seed)
+	l.positive(envJackpotBroadcastInterval, j.BroadcastInterval)
+	return j
+}
+
+// paytable reads the winnings percentage of every bet type. 100 pays 1:1.
+func (l *loader) paytable(file fileConfig) map[string]int64 {
+	for betType := range file.Paytable {
+		if betType != constants.BetTypeLt7 && betType != constants.BetTypeGt7 {
+			l.fail("unknown bet type %q in paytable", betType)
+		}
+	}
+	paytable := make(map[string]int64, 2)
+	for _, betType := range []string{constants.BetTypeLt7, constants.BetTypeGt7} {
+		var fromFile *int
+		if v, ok := file.Paytable[betType]; ok {
+			fromFile = &v
+		}
+		percent := l.int(envPayoutPrefix+strings.ToUpper(betType), fromFile, 100)
+		l.check(percent > 0 && percent <= 10000, "invalid payout for %s: %d%% must be between 1 and 10000", betType, percent)
+		paytable[betType] = int64(percent)
+	}
+	return paytable
+}
+
+// loader resolves settings from the environment, the config file and defaults,
+// collecting every validation error instead of stopping at the first.
+type loader struct {
+	errs []error
+}
+
+func (l *loader) fail(format string, args ...any) {
+	l.errs = append(l.errs, fmt.Errorf(format, args...))
+}
+
+func (l *loader) check(ok bool, format string, args ...any) {
+	if !ok {
+		l.fail(format, args...)
+	}
+}
+
+func (l *loader) err() error {
+	if len(l.errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("invalid configuration:\n%w", errors.Join(l.errs...))
+}
+
+// str returns the environment variable key, else fileValue, else fallback.
+func (l *loader) str(key, fileValue, fallback string) string {
+	if value, ok := lookupEnv(key); ok {
 		return value
 	}
-	if key != "DB_PASSWORD" && key != "REDIS_PASSWORD" {
+	if fileValue != "" {
+		return fileValue
+	}
+	if !secretEnvKeys[key] {
 		log.Printf("Using fallback for environment variable %s: %s", key, fallback)
 	}
 	return fallback
 }
 
-// parseEnvInt parses an environment variable as an integer or returns a fallback value
-func parseEnvInt(key string, fallback int) int {
-	valueStr := getEnv(key, strconv.Itoa(fallback))
-	value, err := strconv.Atoi(valueStr)
+// int is str for integers. Unparsable environment values are errors.
+func (l *loader) int(key string, fileValue *int, fallback int) int {
+	if value, ok := lookupEnv(key); ok {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			l.fail("invalid %s %q: must be an integer", key, value)
+			return fallback
+		}
+		return n
+	}
+	if fileValue != nil {
+		return *fileValue
+	}
+	log.Printf("Using fallback for environment variable %s: %d", key, fallback)
+	return fallback
+}
+
+// float is str for decimal numbers. Unparsable environment values are errors.
+func (l *loader) float(key string, fileValue *float64, fallback float64) float64 {
+	if value, ok := lookupEnv(key); ok {
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			l.fail("invalid %s %q: must be a number", key, value)
+			return fallback
+		}
+		return f
+	}
+	if fileValue != nil {
+		return *fileValue
+	}
+	log.Printf("Using fallback for environment variable %s: %g", key, fallback)
+	return fallback
+}
+
+// bool is str for booleans such as true, false, 1 or 0. Unparsable values are errors.
+func (l *loader) bool(key string, fileValue *bool, fallback bool) bool {
+	if value, ok := lookupEnv(key); ok {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			l.fail("invalid %s %q: must be true or false", key, value)
+			return fallback
+		}
+		return b
+	}
+	if fileValue != nil {
+		return *fileValue
+	}
+	log.Printf("Using fallback for environment variable %s: %t", key, fallback)
+	return fallback
+}
+
+// duration is str for durations such as "30s". Unparsable values are errors.
+func (l *loader) duration(key, fileValue string, fallback time.Duration) time.Duration {
+	value, ok := lookupEnv(key)
+	source := key
+	if !ok {
+		if fileValue == "" {
+			log.Printf("Using fallback for environment variable %s: %v", key, fallback)
+			return fallback
+		}
+		value, source = fileValue, "config file value for "+key
+	}
+	d, err := time.ParseDuration(value)
 	if err != nil {
-		log.Printf("Invalid integer value for %s: %s. Using fallback: %d", key, valueStr, fallback)
+		l.fail("invalid %s %q: must be a duration such as 30s", source, value)
 		return fallback
 	}
-	return value
+	return d
+}
+
+// positive reports a non-positive duration.
+func (l *loader) positive(key string, d time.Duration) {
+	l.check(d > 0, "invalid %s: %v must be positive", key, d)
+}
+
+// lookupEnv returns a trimmed, non-empty environment variable.
+func lookupEnv(key string) (string, bool) {
+	value := strings.TrimSpace(os.Getenv(key))
+	return value, value != ""
 }
@@ -0,0 +1,314 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// isolate hides the caller's environment from Load, which treats empty
+// variables as unset.
+func isolate(t *testing.T) {
+	for _, kv := range os.Environ() {
+		if key, _, ok := strings.Cut(kv, "="); ok && key != "" {
+			t.Setenv(key, "")
+		}
+	}
+}
+
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	isolate(t)
+	path := writeConfigFile(t, `
+listenPort: "9090"
+rateLimit: {perSecond: 5, burst: 10}
+settlement: {batchSize: 50}
+timeouts: {shortOp: 2s}
+currencies:
+  PTS: {minBetAmount: 2, maxBetAmount: 100}
+`)
+	t.Setenv(envListenPort, "7070")
+	t.Setenv(envRateLimitBurst, "15")
+	t.Setenv(envMinBetPrefix+"PTS", "5")
+
+	cfg, err := Load(path, false)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	app := cfg.App
+
+	// The environment overrides the file.
+	if app.ListenPort != "7070" {
+		t.Errorf("ListenPort = %q, want the environment's 7070", app.ListenPort)
+	}
+	if app.RateLimit.Burst != 15 {
+		t.Errorf("RateLimit.Burst = %d, want the environment's 15", app.RateLimit.Burst)
+	}
+	if got := app.Currencies["PTS"].MinBetAmount; got != 5 {
+		t.Errorf("PTS MinBetAmount = %d, want the environment's 5", got)
+	}
+	// The file overrides the defaults.
+	if app.RateLimit.PerSecond != 5 {
+		t.Errorf("RateLimit.PerSecond = %g, want the file's 5", app.RateLimit.PerSecond)
+	}
+	if app.Settlement.BatchSize != 50 {
+		t.Errorf("Settlement.BatchSize = %d, want the file's 50", app.Settlement.BatchSize)
+	}
+	if app.Timeouts.ShortOp != 2*time.Second {
+		t.Errorf("Timeouts.ShortOp = %v, want the file's 2s", app.Timeouts.ShortOp)
+	}
+	if got := app.Currencies["PTS"].MaxBetAmount; got != 100 {
+		t.Errorf("PTS MaxBetAmount = %d, want the file's 100", got)
+	}
+	// Anything set nowhere keeps its default.
+	if app.FeedBigWinThreshold != 100 {
+		t.Errorf("FeedBigWinThreshold = %d, want the default 100", app.FeedBigWinThreshold)
+	}
+	if app.Settlement.FlushInterval != 5*time.Millisecond {
+		t.Errorf("Settlement.FlushInterval = %v, want the default 5ms", app.Settlement.FlushInterval)
+	}
+}
+
+func TestLoadDevModePrecedence(t *testing.T) {
+	isolate(t)
+	path := writeConfigFile(t, `
+database: {host: files-db}
+redis: {addr: "files-redis:6379"}
+`)
+	// Dev mode reads the host-side variables, not the container ones.
+	t.Setenv(envDBHost, "db")
+	t.Setenv(envDBPortDev, "6543")
+
+	cfg, err := Load(path, true)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.DB.Host != "files-db" {
+		t.Errorf("DB.Host = %q, want the file's files-db", cfg.DB.Host)
+	}
+	if cfg.DB.Port != 6543 {
+		t.Errorf("DB.Port = %d, want the environment's 6543", cfg.DB.Port)
+	}
+	if got := cfg.Redis.Addrs; !slices.Equal(got, []string{"files-redis:6379"}) {
+		t.Errorf("Redis.Addrs = %v, want the file's files-redis:6379", got)
+	}
+
+	// Set nowhere, they default to the ports Docker Compose publishes.
+	cfg, err = Load("", true)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.DB.Host != "localhost" || cfg.DB.Port != 6543 {
+		t.Errorf("DB = %s:%d, want localhost and the environment's 6543", cfg.DB.Host, cfg.DB.Port)
+	}
+	if got := cfg.Redis.Addrs; !slices.Equal(got, []string{"localhost:6380"}) {
+		t.Errorf("Redis.Addrs = %v, want the default localhost:6380", got)
+	}
+}
+
+func TestLoadRejectsInvalidValues(t *testing.T) {
+	type env map[string]string
+	cases := []struct {
+		name string
+		env  env
+		file string
+		want string
+	}{
+		{"database port", env{envDBPort: "0"}, "", "database port 0 is out of range"},
+		{"unparsable integer", env{envDBPort: "abc"}, "", `invalid DB_PORT "abc"`},
+		{"database max conns", env{envDBMaxConns: "0"}, "", envDBMaxConns},
+		{"database min conns above max", env{envDBMinConns: "11"}, "", envDBMinConns},
+		{"database conn lifetime", env{envDBMaxConnLifetime: "0s"}, "", envDBMaxConnLifetime},
+		{"database conn idle time", env{envDBMaxConnIdleTime: "-1s"}, "", envDBMaxConnIdleTime},
+		{"database health check", env{envDBHealthCheckPeriod: "0s"}, "", envDBHealthCheckPeriod},
+		{"database connect timeout", env{envDBConnectTimeout: "0s"}, "", envDBConnectTimeout},
+		{"unparsable duration", env{envDBConnectTimeout: "soon"}, "", `invalid DB_CONNECT_TIMEOUT "soon"`},
+		{"unparsable boolean", env{envDBAutoMigrate: "maybe"}, "", `invalid DB_AUTO_MIGRATE "maybe"`},
+		{"play lock pool", env{envDBPlayLockConns: "0"}, "", envDBPlayLockConns},
+		{"replica port", env{envDBReplicaPort: "70000"}, "", envDBReplicaPort},
+		{"replica max lag", env{envDBReplicaMaxLag: "0s"}, "", envDBReplicaMaxLag},
+		{"replica check interval", env{envDBReplicaCheck: "0s"}, "", envDBReplicaCheck},
+
+		{"redis mode", env{envRedisMode: "bogus"}, "", envRedisMode},
+		{"standalone with two addresses", env{envRedisAddr: "a:1,b:2"}, "", "standalone mode takes exactly one address"},
+		{"sentinel without master", env{envRedisMode: "sentinel"}, "", envRedisMasterName + " is required"},
+		{"cluster on another database", env{envRedisMode: "cluster", envRedisDB: "1"}, "", "Redis Cluster only supports database 0"},
+		{"master name outside sentinel", env{envRedisMasterName: "mymaster"}, "", "only used in sentinel mode"},
+		{"redis database", env{envRedisDB: "-1"}, "", envRedisDB},
+		{"redis startup mode", env{envRedisStartupMode: "bogus"}, "", envRedisStartupMode},
+		{"redis health check", env{envRedisHealthCheckInterval: "0s"}, "", envRedisHealthCheckInterval},
+		{"redis TLS flag", env{envRedisTLS: "maybe"}, "", envRedisTLS},
+		{"redis CA without TLS", env{envRedisTLSCAFile: "ca.pem"}, "", "is set but REDIS_TLS is not enabled"},
+		{"redis CA missing", env{envRedisTLS: "true", envRedisTLSCAFile: "/nonexistent/ca.pem"}, "", "invalid " + envRedisTLSCAFile},
+		{"redis pool size", env{envRedisPoolSize: "0"}, "", envRedisPoolSize},
+		{"redis min idle conns above pool", env{envRedisMinIdleConns: "11"}, "", envRedisMinIdleConns},
+		{"redis dial timeout", env{envRedisDialTimeout: "0s"}, "", envRedisDialTimeout},
+		{"redis read timeout", env{envRedisReadTimeout: "0s"}, "", envRedisReadTimeout},
+		{"redis write timeout", env{envRedisWriteTimeout: "0s"}, "", envRedisWriteTimeout},
+		{"redis pool timeout", env{envRedisPoolTimeout: "0s"}, "", envRedisPoolTimeout},
+		{"redis connect timeout", env{envRedisConnectTimeout: "0s"}, "", envRedisConnectTimeout},
+
+		{"currency code", env{envCurrencies: "PT1"}, "", `invalid currency code "PT1"`},
+		{"default currency not enabled", env{envCurrencies: "PTS", envDefaultCur: "EUR"}, "", "default currency EUR is not listed"},
+		{"file currency not enabled", env{envCurrencies: "PTS"}, "currencies: {PTS: {}, EUR: {}}", "currency EUR is configured in the config file"},
+		{"min bet", env{envMinBetPrefix + "PTS": "0"}, "", "invalid bet limits for PTS"},
+		{"max bet below min", env{envMinBetPrefix + "PTS": "10", envMaxBetPrefix + "PTS": "5"}, "", "invalid bet limits for PTS"},
+		{"legacy max bet", env{envMaxBet: "0"}, "", "invalid bet limits for PTS"},
+		{"initial balance", env{envInitialBalancePrefix + "PTS": "-1"}, "", "invalid initial balance for PTS"},
+
+		{"payout too low", env{envPayoutPrefix + "LT7": "0"}, "", "invalid payout for lt7"},
+		{"payout too high", env{envPayoutPrefix + "GT7": "10001"}, "", "invalid payout for gt7"},
+		{"paytable bet type", nil, "paytable: {even: 100}", `unknown bet type "even"`},
+
+		{"limit cooling-off", env{envLimitCoolingOffHours: "-1"}, "", envLimitCoolingOffHours},
+		{"rate limit", env{envRateLimitPerSecond: "-1"}, "", envRateLimitPerSecond},
+		{"unparsable number", env{envRateLimitPerSecond: "fast"}, "", `invalid RATE_LIMIT_PER_SECOND "fast"`},
+		{"rate limit burst", env{envRateLimitBurst: "0"}, "", envRateLimitBurst},
+		{"listen port", env{envListenPort: "http"}, "", envListenPort},
+		{"listen port range", env{envListenPort: "70000"}, "", envListenPort},
+		{"feed big win threshold", env{envFeedBigWinThreshold: "-1"}, "", envFeedBigWinThreshold},
+		{"pool stats interval", env{envPoolStatsInterval: "0s"}, "", envPoolStatsInterval},
+		{"play lock fallback", env{envPlayLockFallback: "bogus"}, "", envPlayLockFallback},
+		{"balance cache TTL", env{envBalanceCacheTTL: "1us"}, "", envBalanceCacheTTL},
+
+		{"HTTP read timeout", env{envHTTPReadTimeout: "0s"}, "", envHTTPReadTimeout},
+		{"HTTP write timeout", env{envHTTPWriteTimeout: "0s"}, "", envHTTPWriteTimeout},
+		{"HTTP idle timeout", env{envHTTPIdleTimeout: "0s"}, "", envHTTPIdleTimeout},
+		{"shutdown timeout", env{envShutdownTimeout: "0s"}, "", envShutdownTimeout},
+		{"handler timeout", env{envHandlerOpTimeout: "0s"}, "", envHandlerOpTimeout},
+		{"short op timeout", env{envShortOpTimeout: "0s"}, "", envShortOpTimeout},
+		{"lock release timeout", env{envLockReleaseTimeout: "0s"}, "", envLockReleaseTimeout},
+		{"websocket write timeout", env{envWSWriteTimeout: "0s"}, "", envWSWriteTimeout},
+		{"short op above handler", env{envShortOpTimeout: "20s"}, "", "must not exceed " + envHandlerOpTimeout},
+		{"play lock within handler", env{envPlayLockTTL: "5s"}, "", "must exceed " + envHandlerOpTimeout},
+		{"play lock within a batch flush", env{envSettlementMode: "batch", envPlayLockTTL: "12s"}, "", "in batch settlement mode"},
+		{"unparsable file duration", nil, "timeouts: {shortOp: soon}", "config file value for " + envShortOpTimeout},
+
+		{"settlement mode", env{envSettlementMode: "bogus"}, "", envSettlementMode},
+		{"settlement batch size", env{envSettlementBatchSize: "0"}, "", envSettlementBatchSize},
+		{"settlement flush interval", env{envSettlementFlushInterval: "0s"}, "", envSettlementFlushInterval},
+		{"settlement flush above short op", env{envSettlementFlushInterval: "3s"}, "", "must be below " + envShortOpTimeout},
+
+		{"bonus stake order", env{envBonusStakeOrder: "bogus"}, "", envBonusStakeOrder},
+		{"bonus wagering multiplier", env{envBonusWageringMultiplier: "-1"}, "", envBonusWageringMultiplier},
+
+		{"jackpot flag", env{envJackpotEnabled: "maybe"}, "", envJackpotEnabled},
+		{"jackpot contribution too high", env{envJackpotContributionPercent: "100"}, "", envJackpotContributionPercent},
+		{"jackpot contribution negative", env{envJackpotContributionPercent: "-1"}, "", envJackpotContributionPercent},
+		{"jackpot min bet", env{envJackpotMinBetAmount: "-1"}, "", envJackpotMinBetAmount},
+		{"jackpot seed", env{envJackpotSeed: "-1"}, "", envJackpotSeed},
+		{"jackpot broadcast interval", env{envJackpotBroadcastInterval: "0s"}, "", envJackpotBroadcastInterval},
+
+		{"unknown file key", nil, "colour: blue", "field colour not found"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			isolate(t)
+			for key, value := range c.env {
+				t.Setenv(key, value)
+			}
+			path := ""
+			if c.file != "" {
+				path = writeConfigFile(t, c.file)
+			}
+			cfg, err := Load(path, false)
+			if err == nil {
+				t.Fatalf("Load accepted the configuration: %+v", cfg.App)
+			}
+			if !strings.Contains(err.Error(), c.want) {
+				t.Fatalf("Load error does not mention %q:\n%v", c.want, err)
+			}
+		})
+	}
+}
+
+// Load reports every invalid value, not just the first.
+func TestLoadReportsEveryError(t *testing.T) {
+	isolate(t)
+	t.Setenv(envDBMaxConns, "0")
+	t.Setenv(envSettlementMode, "bogus")
+	t.Setenv(envJackpotSeed, "-1")
+
+	_, err := Load("", false)
+	if err == nil {
+		t.Fatal("Load accepted the configuration")
+	}
+	for _, key := range []string{envDBMaxConns, envSettlementMode, envJackpotSeed} {
+		if !strings.Contains(err.Error(), key) {
+			t.Errorf("Load error does not mention %s:\n%v", key, err)
+		}
+	}
+}
+
+func TestReload(t *testing.T) {
+	isolate(t)
+	path := writeConfigFile(t, `
+listenPort: "8080"
+paytable: {lt7: 100, gt7: 100}
+rateLimit: {perSecond: 20, burst: 40}
+`)
+	current, err := Load(path, false)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	t.Run("keeps the running config on a validation error", func(t *testing.T) {
+		if err := os.WriteFile(path, []byte(`
+listenPort: "8080"
+paytable: {lt7: 150, gt7: 0}
+rateLimit: {perSecond: 5, burst: 10}
+`), 0o600); err != nil {
+			t.Fatalf("failed to rewrite config file: %v", err)
+		}
+		next, ignored, err := Reload(current)
+		if err == nil {
+			t.Fatal("Reload accepted an invalid payout")
+		}
+		if next != current || ignored != nil {
+			t.Fatalf("Reload returned %p with %v, want the running config %p unchanged", next, ignored, current)
+		}
+		if current.App.Paytable["lt7"] != 100 || current.App.RateLimit.Burst != 40 {
+			t.Errorf("running config changed: paytable %v, rate limit %+v", current.App.Paytable, current.App.RateLimit)
+		}
+	})
+
+	t.Run("applies reloadable settings and reports the rest", func(t *testing.T) {
+		if err := os.WriteFile(path, []byte(`
+listenPort: "9090"
+paytable: {lt7: 150, gt7: 90}
+rateLimit: {perSecond: 5, burst: 10}
+`), 0o600); err != nil {
+			t.Fatalf("failed to rewrite config file: %v", err)
+		}
+		next, ignored, err := Reload(current)
+		if err != nil {
+			t.Fatalf("Reload: %v", err)
+		}
+		if next.App.Paytable["lt7"] != 150 || next.App.Paytable["gt7"] != 90 {
+			t.Errorf("paytable = %v, want the reloaded lt7 150, gt7 90", next.App.Paytable)
+		}
+		if next.App.RateLimit != (RateLimitConfig{PerSecond: 5, Burst: 10}) {
+			t.Errorf("rate limit = %+v, want the reloaded 5/s burst 10", next.App.RateLimit)
+		}
+		if next.App.ListenPort != "8080" {
+			t.Errorf("listen port = %q, want the running 8080 until a restart", next.App.ListenPort)
+		}
+		if !slices.Contains(ignored, "listen port") {
+			t.Errorf("ignored = %v, want the listen port change reported", ignored)
+		}
+		if current.App.Paytable["lt7"] != 100 {
+			t.Errorf("Reload changed the running config's paytable to %v", current.App.Paytable)
+		}
+	})
+}
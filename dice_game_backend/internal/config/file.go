@@ -0,0 +1,146 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// fileConfig is the YAML config file layout. Every setting is optional;
+// pointers distinguish an explicit zero from an absent value.
+//
+//	listenPort: "8080"
+//	database: {host: db, port: 5432, user: postgres, password: secret, name: wallet_db, sslMode: disable, maxConns: 10}
+//	redis: {mode: standalone, addr: "redis:6379", db: 0, poolSize: 10, readTimeout: 3s, tls: {enabled: false}}
+//	timeouts: {handlerOp: 10s, shortOp: 3s, playLock: 15s}
+//	poolStatsInterval: 1m
+//	settlement: {mode: batch, batchSize: 100, flushInterval: 5ms}
+//	bonus: {stakeOrder: cash_first, wageringMultiplier: 10}
+//	jackpot: {enabled: true, contributionPercent: 1, minBetAmount: 10, seed: 1000, broadcastInterval: 1s}
+//	defaultCurrency: PTS
+//	currencies:
+//	  PTS: {minBetAmount: 1, maxBetAmount: 250, initialBalance: 500}
+//	paytable: {lt7: 100, gt7: 100}
+//	rateLimit: {perSecond: 20, burst: 40}
+//	feedBigWinThreshold: 100
+//	limitCoolingOffHours: 24
+//	admin: {apiToken: secret}
+type fileConfig struct {
+	ListenPort string `yaml:"listenPort"`
+	Database   struct {
+		Host     string `yaml:"host"`
+		Port     *int   `yaml:"port"`
+		User     string `yaml:"user"`
+		Password string `yaml:"password"`
+		Name     string `yaml:"name"`
+		SSLMode  string `yaml:"sslMode"`
+
+		MaxConns          *int   `yaml:"maxConns"`
+		MinConns          *int   `yaml:"minConns"`
+		PlayLockConns     *int   `yaml:"playLockConns"`
+		MaxConnLifetime   string `yaml:"maxConnLifetime"`
+		MaxConnIdleTime   string `yaml:"maxConnIdleTime"`
+		HealthCheckPeriod string `yaml:"healthCheckPeriod"`
+		ConnectTimeout    string `yaml:"connectTimeout"`
+		AutoMigrate       *bool  `yaml:"autoMigrate"`
+		Replica           struct {
+			Host          string `yaml:"host"`
+			Port          *int   `yaml:"port"`
+			MaxLag        string `yaml:"maxLag"`
+			CheckInterval string `yaml:"checkInterval"`
+		} `yaml:"replica"`
+	} `yaml:"database"`
+	Redis struct {
+		Mode             string `yaml:"mode"`
+		Addr             string `yaml:"addr"`
+		MasterName       string `yaml:"masterName"`
+		Username         string `yaml:"username"`
+		Password         string `yaml:"password"`
+		SentinelUsername string `yaml:"sentinelUsername"`
+		SentinelPassword string `yaml:"sentinelPassword"`
+		DB               *int   `yaml:"db"`
+		TLS              struct {
+			Enabled    *bool  `yaml:"enabled"`
+			CAFile     string `yaml:"caFile"`
+			ServerName string `yaml:"serverName"`
+		} `yaml:"tls"`
+
+		PoolSize       *int   `yaml:"poolSize"`
+		MinIdleConns   *int   `yaml:"minIdleConns"`
+		DialTimeout    string `yaml:"dialTimeout"`
+		ReadTimeout    string `yaml:"readTimeout"`
+		WriteTimeout   string `yaml:"writeTimeout"`
+		PoolTimeout    string `yaml:"poolTimeout"`
+		ConnectTimeout string `yaml:"connectTimeout"`
+
+		StartupMode         string `yaml:"startupMode"`
+		HealthCheckInterval string `yaml:"healthCheckInterval"`
+	} `yaml:"redis"`
+	Timeouts struct {
+		HTTPRead    string `yaml:"httpRead"`
+		HTTPWrite   string `yaml:"httpWrite"`
+		HTTPIdle    string `yaml:"httpIdle"`
+		Shutdown    string `yaml:"shutdown"`
+		HandlerOp   string `yaml:"handlerOp"`
+		ShortOp     string `yaml:"shortOp"`
+		PlayLock    string `yaml:"playLock"`
+		LockRelease string `yaml:"lockRelease"`
+		WSWrite     string `yaml:"wsWrite"`
+	} `yaml:"timeouts"`
+	PoolStatsInterval string `yaml:"poolStatsInterval"`
+	PlayLockFallback  string `yaml:"playLockFallback"`
+	BalanceCacheTTL   string `yaml:"balanceCacheTTL"`
+	Settlement        struct {
+		Mode          string `yaml:"mode"`
+		BatchSize     *int   `yaml:"batchSize"`
+		FlushInterval string `yaml:"flushInterval"`
+	} `yaml:"settlement"`
+	Bonus struct {
+		StakeOrder         string `yaml:"stakeOrder"`
+		WageringMultiplier *int   `yaml:"wageringMultiplier"`
+	} `yaml:"bonus"`
+	Jackpot struct {
+		Enabled             *bool    `yaml:"enabled"`
+		ContributionPercent *float64 `yaml:"contributionPercent"`
+		MinBetAmount        *int     `yaml:"minBetAmount"`
+		Seed                *int     `yaml:"seed"`
+		BroadcastInterval   string   `yaml:"broadcastInterval"`
+	} `yaml:"jackpot"`
+	DefaultCurrency string                  `yaml:"defaultCurrency"`
+	Currencies      map[string]fileCurrency `yaml:"currencies"`
+	Paytable        map[string]int          `yaml:"paytable"`
+	RateLimit       struct {
+		PerSecond *float64 `yaml:"perSecond"`
+		Burst     *int     `yaml:"burst"`
+	} `yaml:"rateLimit"`
+	FeedBigWinThreshold  *int `yaml:"feedBigWinThreshold"`
+	LimitCoolingOffHours *int `yaml:"limitCoolingOffHours"`
+	Admin                struct {
+		APIToken string `yaml:"apiToken"`
+	} `yaml:"admin"`
+}
+
+type fileCurrency struct {
+	MinBetAmount   *int `yaml:"minBetAmount"`
+	MaxBetAmount   *int `yaml:"maxBetAmount"`
+	InitialBalance *int `yaml:"initialBalance"`
+}
+
+// readFile parses a YAML config file, rejecting unknown keys.
+func readFile(path string) (fileConfig, error) {
+	var file fileConfig
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return file, fmt.Errorf("failed to read config file: %w", err)
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+		return file, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	return file, nil
+}
@@ -0,0 +1,101 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+)
+
+// Reload loads the configuration again from current's file and the
+// environment. The settings Reloadable allows to change are taken from the new
+// configuration and everything else is kept from current; the list names the
+// changes that need a restart. An invalid configuration is rejected and
+// current is returned unchanged with the error.
+func Reload(current *Config) (*Config, []string, error) {
+	next, err := Load(current.Path, current.IsDevMode)
+	if err != nil {
+		return current, nil, err
+	}
+	app, ignored := Reloadable(current, next)
+	applied := *current
+	applied.App = app
+	return &applied, ignored, nil
+}
+
+// Reloadable returns current with the settings that may change at runtime
+// taken from next: per-currency bet limits, the paytable and rate limits.
+// It also lists the changed settings that only take effect after a restart.
+func Reloadable(current, next *Config) (AppConfig, []string) {
+	app := current.App
+	var ignored []string
+
+	app.Currencies = make(map[string]CurrencyConfig, len(current.App.Currencies))
+	for code, cur := range current.App.Currencies {
+		if n, ok := next.App.Currencies[code]; ok {
+			cur.MinBetAmount = n.MinBetAmount
+			cur.MaxBetAmount = n.MaxBetAmount
+			if n.InitialBalance != cur.InitialBalance {
+				ignored = append(ignored, fmt.Sprintf("initial balance of %s", code))
+			}
+		}
+		app.Currencies[code] = cur
+	}
+	if !sameKeys(current.App.Currencies, next.App.Currencies) || current.App.DefaultCurrency != next.App.DefaultCurrency {
+		ignored = append(ignored, "enabled currencies")
+	}
+	app.Paytable = maps.Clone(next.App.Paytable)
+	app.RateLimit = next.App.RateLimit
+
+	if current.App.ListenPort != next.App.ListenPort {
+		ignored = append(ignored, "listen port")
+	}
+	if current.App.FeedBigWinThreshold != next.App.FeedBigWinThreshold {
+		ignored = append(ignored, "feed big win threshold")
+	}
+	if current.App.LimitCoolingOff != next.App.LimitCoolingOff {
+		ignored = append(ignored, "limit cooling-off")
+	}
+	if current.App.Timeouts != next.App.Timeouts {
+		ignored = append(ignored, "timeouts")
+	}
+	if current.App.PlayLockFallback != next.App.PlayLockFallback {
+		ignored = append(ignored, "play lock fallback")
+	}
+	if current.App.PoolStatsInterval != next.App.PoolStatsInterval {
+		ignored = append(ignored, "pool stats interval")
+	}
+	if current.App.BalanceCacheTTL != next.App.BalanceCacheTTL {
+		ignored = append(ignored, "balance cache TTL")
+	}
+	if current.App.Settlement != next.App.Settlement {
+		ignored = append(ignored, "settlement")
+	}
+	if current.App.Bonus != next.App.Bonus {
+		ignored = append(ignored, "bonus")
+	}
+	if current.App.Jackpot != next.App.Jackpot {
+		ignored = append(ignored, "jackpot")
+	}
+	if current.DB != next.DB {
+		ignored = append(ignored, "database")
+	}
+	if !reflect.DeepEqual(current.Redis, next.Redis) {
+		ignored = append(ignored, "redis")
+	}
+	if current.Admin != next.Admin {
+		ignored = append(ignored, "admin API")
+	}
+	return app, ignored
+}
+
+func sameKeys(a, b map[string]CurrencyConfig) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k := range a {
+		if _, ok := b[k]; !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -2,27 +2,90 @@ package constants
 
 // Message Types Client -> Server & Server -> Client
 const (
-	MsgTypePlay          = "play"
-	MsgTypeEndPlay       = "end_play"
-	MsgTypeGetBalance    = "get_balance"
-	MsgTypePlayResult    = "play_result"
-	MsgTypeBalanceUpdate = "balance_update"
-	MsgTypePlayEnded     = "play_ended"
-	MsgTypeError         = "error"
+	MsgTypeHello           = "hello"
+	MsgTypeHelloAck        = "hello_ack"
+	MsgTypePlay            = "play"
+	MsgTypeEndPlay         = "end_play"
+	MsgTypeGetBalance      = "get_balance"
+	MsgTypeAutoplayStart   = "autoplay_start"
+	MsgTypeAutoplayStop    = "autoplay_stop"
+	MsgTypeAutoplayStarted = "autoplay_started"
+	MsgTypeAutoplayStopped = "autoplay_stopped"
+	MsgTypeGetLeaderboard  = "get_leaderboard"
+	MsgTypeLeaderboard     = "leaderboard"
+	MsgTypeGetLimits       = "get_limits"
+	MsgTypeSetLimit        = "set_limit"
+	MsgTypeSelfExclude     = "self_exclude"
+	MsgTypeLimits          = "limits"
+	MsgTypeSessionReminder = "session_reminder"
+	MsgTypeSubscribeFeed   = "subscribe_feed"
+	MsgTypeUnsubscribeFeed = "unsubscribe_feed"
+	MsgTypeFeedSnapshot    = "feed_snapshot"
+	MsgTypeFeedRound       = "feed_round"
+	MsgTypeGetFreeRounds   = "get_free_rounds"
+	MsgTypeFreeRounds      = "free_rounds"
+	MsgTypeJackpotUpdate   = "jackpot_update"
+	MsgTypePlayResult      = "play_result"
+	MsgTypeBalanceUpdate   = "balance_update"
+	MsgTypePlayEnded       = "play_ended"
+	MsgTypeError           = "error"
 )
 
 // Error Codes Server -> Client
 const (
-	ErrCodeBadRequest        = "BAD_REQUEST"
-	ErrCodeInternalError     = "INTERNAL_ERROR"
-	ErrCodeActivePlayExists  = "ACTIVE_PLAY_EXISTS"
-	ErrCodeInvalidBet        = "INVALID_BET"
-	ErrCodeBetTooHigh        = "BET_TOO_HIGH"
-	ErrCodeInvalidBetType    = "INVALID_BET_TYPE"
-	ErrCodeInsufficientFunds = "INSUFFICIENT_FUNDS"
-	ErrCodeWalletNotFound    = "WALLET_NOT_FOUND"
-	ErrCodeUnknownType       = "UNKNOWN_TYPE"
-	ErrCodeFailedLockRelease = "FAILED_LOCK_RELEASE"
+	ErrCodeBadRequest           = "BAD_REQUEST"
+	ErrCodeInternalError        = "INTERNAL_ERROR"
+	ErrCodeActivePlayExists     = "ACTIVE_PLAY_EXISTS"
+	ErrCodeInvalidBet           = "INVALID_BET"
+	ErrCodeBetTooLow            = "BET_TOO_LOW"
+	ErrCodeBetTooHigh           = "BET_TOO_HIGH"
+	ErrCodeInvalidBetType       = "INVALID_BET_TYPE"
+	ErrCodeInsufficientFunds    = "INSUFFICIENT_FUNDS"
+	ErrCodeWalletNotFound       = "WALLET_NOT_FOUND"
+	ErrCodeUnknownType          = "UNKNOWN_TYPE"
+	ErrCodeFailedLockRelease    = "FAILED_LOCK_RELEASE"
+	ErrCodeUnsupportedVersion   = "UNSUPPORTED_VERSION"
+	ErrCodeInvalidCurrency      = "INVALID_CURRENCY"
+	ErrCodeFeedDropped          = "FEED_DROPPED"
+	ErrCodeSelfExcluded         = "SELF_EXCLUDED"
+	ErrCodeStakeLimitExceeded   = "STAKE_LIMIT_EXCEEDED"
+	ErrCodeLossLimitReached     = "LOSS_LIMIT_REACHED"
+	ErrCodeInvalidLimit         = "INVALID_LIMIT"
+	ErrCodeAutoplayActive       = "AUTOPLAY_ACTIVE"
+	ErrCodeInvalidAutoplay      = "INVALID_AUTOPLAY"
+	ErrCodeRateLimited          = "RATE_LIMITED"
+	ErrCodeFreeRoundUnavailable = "FREE_ROUND_UNAVAILABLE"
+	ErrCodeShuttingDown         = "SHUTTING_DOWN"
+	ErrCodeHelloRequired        = "HELLO_REQUIRED"
+)
+
+// Protocol Versions
+const (
+	ProtocolVersion1       = 1
+	CurrentProtocolVersion = ProtocolVersion1
+	// LegacyProtocolVersion is assumed for clients that never send a hello:
+	// the play, get_balance and end_play messages from before the handshake.
+	// It cannot be requested in a hello.
+	LegacyProtocolVersion = 0
+)
+
+// WebSocket Subprotocols
+const (
+	SubprotocolJSON    = "dice.json"
+	SubprotocolMsgpack = "dice.msgpack"
+)
+
+// Feature Flags advertised in hello_ack
+const (
+	FeatureMsgpackEncoding = "msgpack_encoding"
+	FeatureBalancePush     = "balance_push"
+	FeatureLiveFeed        = "live_feed"
+	FeatureLeaderboards    = "leaderboards"
+	FeatureLimits          = "limits"
+	FeatureAutoplay        = "autoplay"
+	FeatureBonus           = "bonus"
+	FeatureFreeRounds      = "free_rounds"
+	FeatureJackpot         = "jackpot"
 )
 
 // Game Related
@@ -35,7 +98,16 @@ const (
 
 // Redis Keys
 const (
-	RedisKeyPrefixActivePlay = "active_play:"
+	RedisKeyPrefixActivePlay  = "active_play:"
+	RedisKeyFeedRecent        = "feed:recent"
+	RedisKeyPrefixLeaderboard = "leaderboard:"
+	RedisKeyPrefixBalance     = "balance:"
+)
+
+// Redis Pub/Sub Channels
+const (
+	RedisChannelBalanceUpdates = "balance_updates"
+	RedisChannelFeedRounds     = "feed_rounds"
 )
 
 // Wallet Defaults
@@ -44,6 +116,27 @@ const (
 	DefaultInitialBalance = 500
 )
 
+// Ledger Entry Types
+const (
+	LedgerEntryDeposit  = "deposit"
+	LedgerEntryWithdraw = "withdraw"
+	LedgerEntryAdjust   = "adjust"
+	// Bonus entries: amount is the bonus granted, or the bonus released to cash.
+	LedgerEntryBonusGrant   = "bonus_grant"
+	LedgerEntryBonusRelease = "bonus_release"
+	// Promo entries credit the winnings of a free round.
+	LedgerEntryPromo = "promo"
+	// Jackpot entries credit a jackpot win.
+	LedgerEntryJackpot = "jackpot"
+)
+
+// Jackpot Ledger Entry Types
+const (
+	JackpotEntrySeed         = "seed"
+	JackpotEntryContribution = "contribution"
+	JackpotEntryPayout       = "payout"
+)
+
 // Timeouts
 const (
 	DefaultReadTimeout  = 5
@@ -56,4 +149,10 @@ const (
 	ShortOpTimeout      = 3
 	RedisLockTimeout    = 15
 	RedisDelTimeout     = 2
+	WSWriteTimeout      = 10
+)
+
+// Autoplay
+const (
+	AutoplayRoundInterval = 750 // milliseconds between autoplay rounds
 )
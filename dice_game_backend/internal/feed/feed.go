@@ -0,0 +1,205 @@
+// Package feed streams anonymized game rounds to every subscribed connection,
+// across all backend replicas, through Redis pub/sub.
+package feed
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/BrunoSena97/dice_game_backend/internal/constants"
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	// subscriberBuffer is how many rounds a subscriber may fall behind before it is dropped.
+	subscriberBuffer = 64
+	// recentRounds is how many rounds are kept for the snapshot sent on subscribe.
+	recentRounds = 20
+)
+
+// Round is one settled round as shown to other players.
+type Round struct {
+	Player    string    `json:"player"`
+	BetType   string    `json:"betType"`
+	BetAmount int64     `json:"betAmount"`
+	Currency  string    `json:"currency"`
+	Die1      int       `json:"die1"`
+	Die2      int       `json:"die2"`
+	Outcome   string    `json:"outcome"`
+	Winnings  int64     `json:"winnings"`
+	BigWin    bool      `json:"bigWin"`
+	PlayedAt  time.Time `json:"playedAt"`
+}
+
+// Subscription options.
+type Options struct {
+	// BigWinsOnly limits the stream to wins at or above the big win threshold.
+	BigWinsOnly bool
+}
+
+type subscription struct {
+	opts   Options
+	rounds chan Round
+	onDrop func()
+}
+
+// Feed publishes rounds and fans them out to local subscribers.
+type Feed struct {
+	redisClient     redis.UniversalClient
+	bigWinThreshold int64
+
+	mu   sync.Mutex
+	subs map[*subscription]struct{}
+}
+
+// NewFeed creates a feed. Wins of at least bigWinThreshold are flagged as big wins.
+func NewFeed(redisClient redis.UniversalClient, bigWinThreshold int64) *Feed {
+	if redisClient == nil {
+		log.Fatal("RedisClient is nil in NewFeed")
+	}
+	return &Feed{
+		redisClient:     redisClient,
+		bigWinThreshold: bigWinThreshold,
+		subs:            make(map[*subscription]struct{}),
+	}
+}
+
+// Anonymize turns a client ID into a stable public handle.
+func Anonymize(userID string) string {
+	sum := sha256.Sum256([]byte(userID))
+	return "player_" + hex.EncodeToString(sum[:3])
+}
+
+// Run listens on the feed channel until ctx is cancelled.
+func (f *Feed) Run(ctx context.Context) {
+	pubsub := f.redisClient.Subscribe(ctx, constants.RedisChannelFeedRounds)
+	defer pubsub.Close()
+
+	log.Printf("Feed subscribed to %s", constants.RedisChannelFeedRounds)
+	ch := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Feed stopped.")
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				log.Println("Feed channel closed.")
+				return
+			}
+			var round Round
+			if err := json.Unmarshal([]byte(msg.Payload), &round); err != nil {
+				log.Printf("Feed: invalid round %q: %v", msg.Payload, err)
+				continue
+			}
+			f.dispatch(round)
+		}
+	}
+}
+
+// Publish anonymizes a settled round, stores it in the recent list and
+// broadcasts it to every replica. userID is never sent to subscribers.
+func (f *Feed) Publish(ctx context.Context, userID string, round Round) error {
+	round.Player = Anonymize(userID)
+	round.BigWin = round.Winnings > 0 && round.Winnings >= f.bigWinThreshold
+	if round.PlayedAt.IsZero() {
+		round.PlayedAt = time.Now().UTC()
+	}
+
+	data, err := json.Marshal(round)
+	if err != nil {
+		return fmt.Errorf("failed to encode feed round: %w", err)
+	}
+
+	_, err = f.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.LPush(ctx, constants.RedisKeyFeedRecent, data)
+		pipe.LTrim(ctx, constants.RedisKeyFeedRecent, 0, recentRounds-1)
+		pipe.Publish(ctx, constants.RedisChannelFeedRounds, data)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to publish feed round: %w", err)
+	}
+	return nil
+}
+
+// Recent returns the latest rounds, newest first, honouring opts.
+func (f *Feed) Recent(ctx context.Context, opts Options) ([]Round, error) {
+	raw, err := f.redisClient.LRange(ctx, constants.RedisKeyFeedRecent, 0, recentRounds-1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recent feed rounds: %w", err)
+	}
+	rounds := make([]Round, 0, len(raw))
+	for _, item := range raw {
+		var round Round
+		if err := json.Unmarshal([]byte(item), &round); err != nil {
+			log.Printf("Feed: skipping invalid recent round: %v", err)
+			continue
+		}
+		if opts.Matches(round) {
+			rounds = append(rounds, round)
+		}
+	}
+	return rounds, nil
+}
+
+// Matches reports whether a round should be delivered under these options.
+func (o Options) Matches(round Round) bool {
+	return !o.BigWinsOnly || round.BigWin
+}
+
+// Subscribe calls deliver for every matching round until unsubscribe is called.
+// deliver runs on its own goroutine. A subscriber that falls subscriberBuffer
+// rounds behind is dropped: onDrop is called and no further rounds are delivered.
+func (f *Feed) Subscribe(opts Options, deliver func(Round), onDrop func()) (unsubscribe func()) {
+	sub := &subscription{opts: opts, rounds: make(chan Round, subscriberBuffer), onDrop: onDrop}
+
+	f.mu.Lock()
+	f.subs[sub] = struct{}{}
+	f.mu.Unlock()
+
+	go func() {
+		for round := range sub.rounds {
+			deliver(round)
+		}
+	}()
+
+	return func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.remove(sub)
+	}
+}
+
+// dispatch hands a round to every matching subscriber without blocking.
+func (f *Feed) dispatch(round Round) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for sub := range f.subs {
+		if !sub.opts.Matches(round) {
+			continue
+		}
+		select {
+		case sub.rounds <- round:
+		default:
+			log.Println("Feed: dropping slow subscriber")
+			f.remove(sub)
+			go sub.onDrop()
+		}
+	}
+}
+
+// remove deletes sub if still present. f.mu must be held.
+func (f *Feed) remove(sub *subscription) {
+	if _, ok := f.subs[sub]; !ok {
+		return
+	}
+	delete(f.subs, sub)
+	close(sub.rounds)
+}
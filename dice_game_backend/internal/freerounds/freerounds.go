@@ -0,0 +1,181 @@
+// Package freerounds manages free round grants: a number of rounds a player
+// may play at a fixed stake and bet type without being charged. Grants are
+// created and revoked by operators; playing one is settled by the wallet
+// (see wallet.Service.SettleFreeRound), which consumes it atomically.
+package freerounds
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/BrunoSena97/dice_game_backend/internal/constants"
+	"github.com/BrunoSena97/dice_game_backend/internal/platform/database"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// MaxRoundsPerGrant caps the rounds of a single grant.
+const MaxRoundsPerGrant = 1000
+
+var (
+	ErrGrantNotFound       = errors.New("free round grant not found")
+	ErrInvalidGrant        = errors.New("invalid free round grant")
+	ErrUnsupportedCurrency = errors.New("unsupported currency")
+	ErrReasonRequired      = errors.New("a reason is required")
+	ErrActorRequired       = errors.New("an actor is required")
+)
+
+// Grant is a free round grant.
+type Grant struct {
+	ID              int64
+	UserID          string
+	Currency        string
+	BetType         string
+	Stake           int64
+	RoundsGranted   int
+	RoundsRemaining int
+	ExpiresAt       time.Time
+	Reason          string
+	Actor           string
+	RevokedAt       *time.Time
+	CreatedAt       time.Time
+}
+
+// Active reports whether the grant still has rounds that may be played at now.
+func (g Grant) Active(now time.Time) bool {
+	return g.RoundsRemaining > 0 && g.RevokedAt == nil && now.Before(g.ExpiresAt)
+}
+
+// Service stores grants in PostgreSQL. Listings whose context allows stale
+// reads run on the read replica; lookups before a play use the primary.
+type Service struct {
+	db         *database.Router
+	currencies []string
+	now        func() time.Time
+}
+
+// NewService creates a grant service for the given supported currencies.
+func NewService(db *database.Router, currencies []string) *Service {
+	if db == nil {
+		log.Fatal("freerounds.Service requires a non-nil database router")
+	}
+	return &Service{db: db, currencies: currencies, now: time.Now}
+}
+
+// Create stores a new grant of rounds free rounds at stake on betType, valid
+// until expiresAt. A reason is mandatory.
+func (s *Service) Create(ctx context.Context, g Grant) (Grant, error) {
+	switch {
+	case !slices.Contains(s.currencies, g.Currency):
+		return Grant{}, fmt.Errorf("%w: %s", ErrUnsupportedCurrency, g.Currency)
+	case g.BetType != constants.BetTypeLt7 && g.BetType != constants.BetTypeGt7:
+		return Grant{}, fmt.Errorf("%w: bet type %q", ErrInvalidGrant, g.BetType)
+	case g.Stake <= 0:
+		return Grant{}, fmt.Errorf("%w: stake must be positive (%d)", ErrInvalidGrant, g.Stake)
+	case g.RoundsGranted <= 0 || g.RoundsGranted > MaxRoundsPerGrant:
+		return Grant{}, fmt.Errorf("%w: rounds must be between 1 and %d (%d)", ErrInvalidGrant, MaxRoundsPerGrant, g.RoundsGranted)
+	case !g.ExpiresAt.After(s.now()):
+		return Grant{}, fmt.Errorf("%w: expiry %s is in the past", ErrInvalidGrant, g.ExpiresAt.Format(time.RFC3339))
+	case g.Reason == "":
+		return Grant{}, ErrReasonRequired
+	case g.Actor == "":
+		return Grant{}, ErrActorRequired
+	}
+
+	query := `
+		INSERT INTO free_round_grants (user_id, currency, bet_type, stake, rounds_granted, rounds_remaining, expires_at, reason, actor)
+		VALUES ($1, $2, $3, $4, $5, $5, $6, $7, $8)
+		RETURNING id, rounds_remaining, created_at;
+	`
+	err := s.db.Primary().QueryRow(ctx, query, g.UserID, g.Currency, g.BetType, g.Stake, g.RoundsGranted, g.ExpiresAt, g.Reason, g.Actor).
+		Scan(&g.ID, &g.RoundsRemaining, &g.CreatedAt)
+	if err != nil {
+		log.Printf("Error creating free round grant for user %s: %v", g.UserID, err)
+		return Grant{}, fmt.Errorf("failed to create free round grant for user %s: %w", g.UserID, err)
+	}
+	log.Printf("FREE ROUNDS: grant %d of %d x %d %s on %s for user %s by %s (reason: %q), expires %s",
+		g.ID, g.RoundsGranted, g.Stake, g.Currency, g.BetType, g.UserID, g.Actor, g.Reason, g.ExpiresAt.Format(time.RFC3339))
+	return g, nil
+}
+
+const grantColumns = `id, user_id, currency, bet_type, stake, rounds_granted, rounds_remaining, expires_at, reason, actor, revoked_at, created_at`
+
+func scanGrant(row pgx.CollectableRow) (Grant, error) {
+	var g Grant
+	err := row.Scan(&g.ID, &g.UserID, &g.Currency, &g.BetType, &g.Stake, &g.RoundsGranted, &g.RoundsRemaining,
+		&g.ExpiresAt, &g.Reason, &g.Actor, &g.RevokedAt, &g.CreatedAt)
+	return g, err
+}
+
+// Get returns one of the user's grants, whether or not it is still active.
+func (s *Service) Get(ctx context.Context, userID string, id int64) (Grant, error) {
+	query := `SELECT ` + grantColumns + ` FROM free_round_grants WHERE id = $1 AND user_id = $2;`
+	rows, err := s.db.Reader(ctx).Query(ctx, query, id, userID)
+	if err != nil {
+		return Grant{}, fmt.Errorf("failed to query free round grant %d: %w", id, err)
+	}
+	g, err := pgx.CollectExactlyOneRow(rows, scanGrant)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Grant{}, ErrGrantNotFound
+	}
+	if err != nil {
+		return Grant{}, fmt.Errorf("failed to read free round grant %d: %w", id, err)
+	}
+	return g, nil
+}
+
+// List returns the user's grants, newest first. With activeOnly, grants that
+// are used up, expired or revoked are left out.
+func (s *Service) List(ctx context.Context, userID string, activeOnly bool) ([]Grant, error) {
+	query := `
+		SELECT ` + grantColumns + `
+		FROM free_round_grants
+		WHERE user_id = $1 AND (NOT $2 OR (rounds_remaining > 0 AND revoked_at IS NULL AND expires_at > $3))
+		ORDER BY id DESC;
+	`
+	var grants []Grant
+	err := s.db.Read(ctx, func(db *pgxpool.Pool) error {
+		rows, err := db.Query(ctx, query, userID, activeOnly, s.now())
+		if err != nil {
+			return err
+		}
+		grants, err = pgx.CollectRows(rows, scanGrant)
+		return err
+	})
+	if err != nil {
+		log.Printf("Error listing free round grants for user %s: %v", userID, err)
+		return nil, fmt.Errorf("database error listing free round grants for user %s: %w", userID, err)
+	}
+	return grants, nil
+}
+
+// Revoke ends a grant early; its remaining rounds can no longer be played.
+// Revoking a grant twice keeps the first revocation.
+func (s *Service) Revoke(ctx context.Context, id int64, actor string) (Grant, error) {
+	if actor == "" {
+		return Grant{}, ErrActorRequired
+	}
+	query := `
+		UPDATE free_round_grants
+		SET revoked_at = COALESCE(revoked_at, NOW()), updated_at = NOW()
+		WHERE id = $1
+		RETURNING ` + grantColumns + `;
+	`
+	rows, err := s.db.Primary().Query(ctx, query, id)
+	if err != nil {
+		return Grant{}, fmt.Errorf("failed to revoke free round grant %d: %w", id, err)
+	}
+	g, err := pgx.CollectExactlyOneRow(rows, scanGrant)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Grant{}, ErrGrantNotFound
+	}
+	if err != nil {
+		return Grant{}, fmt.Errorf("failed to revoke free round grant %d: %w", id, err)
+	}
+	log.Printf("FREE ROUNDS: grant %d of user %s revoked by %s with %d rounds left", g.ID, g.UserID, actor, g.RoundsRemaining)
+	return g, nil
+}
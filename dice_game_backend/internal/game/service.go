@@ -2,17 +2,67 @@ package game
 
 import (
 	"context"
+	crand "crypto/rand"
 	"fmt"
 	"log"
-	"math/rand"
+	"math/rand/v2"
+	"sync"
 
 	"github.com/BrunoSena97/dice_game_backend/internal/constants"
 )
 
-type Service struct{}
+// Paytable maps each bet type to its winnings as a percentage of the stake.
+// 100 pays 1:1.
+type Paytable map[string]int64
 
+// DefaultPaytable pays 1:1 on both bet types.
+func DefaultPaytable() Paytable {
+	return Paytable{constants.BetTypeLt7: 100, constants.BetTypeGt7: 100}
+}
+
+// Service rolls the dice with a ChaCha8 generator. The generator is not safe
+// for concurrent use, so rolls are serialised.
+type Service struct {
+	mu       sync.Mutex
+	rng      *rand.Rand
+	paytable Paytable
+}
+
+// NewService returns a service seeded from the operating system's CSPRNG.
 func NewService() *Service {
-	return &Service{}
+	var seed [32]byte
+	if _, err := crand.Read(seed[:]); err != nil {
+		log.Fatalf("GAME SVC FATAL: failed to seed dice generator: %v", err)
+	}
+	log.Println("GAME SVC: Dice generator seeded from crypto/rand.")
+	return NewSeededService(seed)
+}
+
+// NewSeededService returns a service whose rolls are fully determined by seed.
+// Use it for reproducible simulations and tests, never in production.
+func NewSeededService(seed [32]byte) *Service {
+	return &Service{rng: rand.New(rand.NewChaCha8(seed)), paytable: DefaultPaytable()}
+}
+
+// SetPaytable replaces the paytable used by subsequent rounds.
+func (s *Service) SetPaytable(p Paytable) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.paytable = p
+}
+
+// payout returns the winnings for a winning stake on betType.
+func (s *Service) payout(betType string, betAmount int64) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return betAmount * s.paytable[betType] / 100
+}
+
+// RollDice rolls two fair six-sided dice.
+func (s *Service) RollDice() (int, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rng.IntN(6) + 1, s.rng.IntN(6) + 1
 }
 
 // PlayRound implements the core game logic (<7 / >7 / 7=loss).
@@ -22,8 +72,7 @@ func (s *Service) PlayRound(ctx context.Context, betType string, betAmount int64
 		return GameResult{}, fmt.Errorf("%w: %s", ErrInvalidBetType, betType)
 	}
 
-	die1 := rand.Intn(6) + 1
-	die2 := rand.Intn(6) + 1
+	die1, die2 := s.RollDice()
 	sumResult := die1 + die2
 
 	var outcome string
@@ -35,14 +84,14 @@ func (s *Service) PlayRound(ctx context.Context, betType string, betAmount int64
 	case sumResult < 7:
 		if betType == constants.BetTypeLt7 {
 			outcome = constants.OutcomeWin
-			winnings = betAmount
+			winnings = s.payout(betType, betAmount)
 		} else {
 			outcome = constants.OutcomeLose
 		}
 	default:
 		if betType == constants.BetTypeGt7 {
 			outcome = constants.OutcomeWin
-			winnings = betAmount
+			winnings = s.payout(betType, betAmount)
 		} else {
 			outcome = constants.OutcomeLose
 		}
@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/BrunoSena97/dice_game_backend/internal/autoplay"
+	"github.com/BrunoSena97/dice_game_backend/internal/constants"
+)
+
+// AutoplayStartPayload starts a server-side autoplay session. MaxBetAmount caps
+// progressive strategies and defaults to the currency's max bet.
+type AutoplayStartPayload struct {
+	ClientID     string `json:"clientId"`
+	BetAmount    int64  `json:"betAmount"`
+	BetType      string `json:"betType"`
+	Currency     string `json:"currency,omitempty"`
+	Rounds       int    `json:"rounds"`
+	Strategy     string `json:"strategy,omitempty"`
+	MaxBetAmount int64  `json:"maxBetAmount,omitempty"`
+	StopOnWin    int64  `json:"stopOnWin,omitempty"`
+	StopOnLoss   int64  `json:"stopOnLoss,omitempty"`
+}
+
+type AutoplayStopPayload struct {
+	ClientID string `json:"clientId"`
+}
+
+type AutoplayStartedPayload struct {
+	ClientID     string `json:"clientId"`
+	Rounds       int    `json:"rounds"`
+	Strategy     string `json:"strategy"`
+	MaxBetAmount int64  `json:"maxBetAmount"`
+	Currency     string `json:"currency"`
+}
+
+type AutoplayStoppedPayload struct {
+	ClientID     string `json:"clientId"`
+	Reason       string `json:"reason"`
+	RoundsPlayed int    `json:"roundsPlayed"`
+	Net          int64  `json:"net"`
+	Currency     string `json:"currency"`
+}
+
+func (h *Handler) handleAutoplayStart(c *client, payloadBytes []byte, clientID string) {
+	var payload AutoplayStartPayload
+	if err := c.codec.DecodePayload(payloadBytes, &payload); err != nil {
+		log.Printf("[Autoplay-%s] Error unmarshalling payload: %v", clientID, err)
+		h.sendError(c, constants.ErrCodeBadRequest, "Invalid autoplay_start payload format")
+		return
+	}
+	if clientID == "" || payload.ClientID != clientID {
+		log.Printf("[Autoplay-%s] Mismatched or missing ClientID in payload (%s)", clientID, payload.ClientID)
+		h.sendError(c, constants.ErrCodeBadRequest, "Client ID mismatch or missing")
+		return
+	}
+	if payload.Currency == "" {
+		payload.Currency = h.app().DefaultCurrency
+	}
+
+	base := PlayPayload{ClientID: clientID, BetAmount: payload.BetAmount, BetType: payload.BetType, Currency: payload.Currency}
+	if err := h.validatePlayPayload(base); err != nil {
+		errCode, errMsg := validationErrorToCode(err)
+		h.sendError(c, errCode, errMsg)
+		return
+	}
+
+	maxBet := h.app().Currencies[payload.Currency].MaxBetAmount
+	if payload.MaxBetAmount > 0 {
+		if payload.MaxBetAmount > maxBet {
+			h.sendError(c, constants.ErrCodeInvalidAutoplay, "Autoplay bet cap exceeds the maximum bet.")
+			return
+		}
+		maxBet = payload.MaxBetAmount
+	}
+	cfg := autoplay.Config{
+		BaseBet:    payload.BetAmount,
+		Rounds:     payload.Rounds,
+		Strategy:   payload.Strategy,
+		MaxBet:     maxBet,
+		StopOnWin:  payload.StopOnWin,
+		StopOnLoss: payload.StopOnLoss,
+	}
+	if err := cfg.Validate(); err != nil {
+		h.sendError(c, constants.ErrCodeInvalidAutoplay, "Invalid autoplay request: "+err.Error())
+		return
+	}
+
+	ctx, ok := c.startAutoplay()
+	if !ok {
+		h.sendError(c, constants.ErrCodeAutoplayActive, "Autoplay is already running.")
+		return
+	}
+
+	log.Printf("[Autoplay-%s] Starting %d rounds (%s, base %d %s, cap %d)", clientID, cfg.Rounds, cfg.Strategy, cfg.BaseBet, payload.Currency, cfg.MaxBet)
+	started := AutoplayStartedPayload{
+		ClientID:     clientID,
+		Rounds:       cfg.Rounds,
+		Strategy:     cfg.Strategy,
+		MaxBetAmount: cfg.MaxBet,
+		Currency:     payload.Currency,
+	}
+	if err := h.sendMessage(c, constants.MsgTypeAutoplayStarted, started); err != nil {
+		log.Printf("[Autoplay-%s] Error sending autoplay_started: %v", clientID, err)
+	}
+
+	go h.runAutoplay(ctx, c, clientID, base, cfg)
+}
+
+func (h *Handler) handleAutoplayStop(c *client, payloadBytes []byte, clientID string) {
+	var payload AutoplayStopPayload
+	if err := c.codec.DecodePayload(payloadBytes, &payload); err != nil {
+		log.Printf("[Autoplay-%s] Error unmarshalling payload: %v", clientID, err)
+		h.sendError(c, constants.ErrCodeBadRequest, "Invalid autoplay_stop payload format")
+		return
+	}
+	if clientID == "" || payload.ClientID != clientID {
+		log.Printf("[Autoplay-%s] Mismatched or missing ClientID in payload (%s)", clientID, payload.ClientID)
+		h.sendError(c, constants.ErrCodeBadRequest, "Client ID mismatch or missing")
+		return
+	}
+	if !c.stopAutoplay(false) {
+		h.sendError(c, constants.ErrCodeBadRequest, "No autoplay is running.")
+	}
+}
+
+// runAutoplay plays the session's rounds on a ticker through settlePlay until
+// it completes, hits a stop rule, fails, or ctx is cancelled. A round in flight
+// is always settled before the session stops.
+func (h *Handler) runAutoplay(ctx context.Context, c *client, clientID string, base PlayPayload, cfg autoplay.Config) {
+	defer c.finishAutoplay()
+
+	session := autoplay.NewSession(cfg)
+	ticker := time.NewTicker(time.Duration(constants.AutoplayRoundInterval) * time.Millisecond)
+	defer ticker.Stop()
+
+	reason := autoplay.StopError
+	for {
+		round := base
+		round.BetAmount = session.NextBet()
+		outcome, ok := h.settlePlay(c, clientID, round)
+		if !ok {
+			break
+		}
+
+		won := outcome.Result.Outcome == constants.OutcomeWin
+		net := -round.BetAmount
+		if won {
+			net = outcome.Result.Winnings
+		}
+		if stop, done := session.Record(won, net); done {
+			reason = stop
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			reason = autoplay.StopRequested
+		case <-ticker.C:
+			continue
+		}
+		break
+	}
+
+	if errors.Is(context.Cause(ctx), errDisconnected) {
+		log.Printf("[Autoplay-%s] Stopped by disconnect after %d rounds (net %d)", clientID, session.Played(), session.Net())
+		return
+	}
+	log.Printf("[Autoplay-%s] Finished: %s after %d rounds (net %d)", clientID, reason, session.Played(), session.Net())
+	stopped := AutoplayStoppedPayload{
+		ClientID:     clientID,
+		Reason:       reason,
+		RoundsPlayed: session.Played(),
+		Net:          session.Net(),
+		Currency:     base.Currency,
+	}
+	if err := h.sendMessage(c, constants.MsgTypeAutoplayStopped, stopped); err != nil {
+		log.Printf("[Autoplay-%s] Error sending autoplay_stopped: %v", clientID, err)
+	}
+}
@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/BrunoSena97/dice_game_backend/internal/constants"
+	"github.com/gorilla/websocket"
+)
+
+// client holds the per-connection state of a WebSocket session.
+// Writes may come from the read loop and from push subscriptions, so they are serialised.
+type client struct {
+	conn    *websocket.Conn
+	codec   Codec
+	writeMu sync.Mutex
+	// writeTimeout bounds each frame write so a stalled peer cannot block pushes.
+	writeTimeout time.Duration
+
+	// playing is set while the connection holds the active play lock.
+	playing atomic.Bool
+
+	// balanceUserID and unsubscribeBalance track the push subscription.
+	// They are only touched by the read loop.
+	balanceUserID      string
+	unsubscribeBalance func()
+
+	// unsubscribeFeed ends the live feed subscription. Only touched by the read loop.
+	unsubscribeFeed func()
+
+	// unsubscribeJackpot ends the jackpot pool pushes. Only touched by the read loop.
+	unsubscribeJackpot func()
+
+	// limiter throttles incoming messages. Only touched by the read loop.
+	limiter tokenBucket
+
+	// protocolVersion is the version negotiated by hello, or
+	// constants.LegacyProtocolVersion until then. Written by the read loop.
+	protocolVersion atomic.Int64
+
+	// sessionStart and remindersSent drive responsible gaming session reminders.
+	sessionStart  time.Time
+	remindersSent atomic.Int64
+
+	// autoplayCancel and autoplayDone are set while an autoplay session runs.
+	autoplayMu     sync.Mutex
+	autoplayCancel context.CancelCauseFunc
+	autoplayDone   chan struct{}
+}
+
+// legacy reports whether the connection speaks the protocol from before the
+// hello handshake.
+func (c *client) legacy() bool {
+	return c.protocolVersion.Load() == constants.LegacyProtocolVersion
+}
+
+var (
+	errDisconnected    = errors.New("connection closed")
+	errAutoplayStopped = errors.New("autoplay stop requested")
+)
+
+func newClient(conn *websocket.Conn, writeTimeout time.Duration) *client {
+	return &client{
+		conn:         conn,
+		codec:        codecForSubprotocol(conn.Subprotocol()),
+		writeTimeout: writeTimeout,
+		sessionStart: time.Now(),
+	}
+}
+
+// write encodes msg with the connection's codec and sends it as one frame.
+func (c *client) write(msg ServerMessage) error {
+	data, err := c.codec.Encode(msg)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s message: %w", c.codec.Name(), err)
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if err := c.conn.SetWriteDeadline(time.Now().Add(c.writeTimeout)); err != nil {
+		return fmt.Errorf("failed to set write deadline: %w", err)
+	}
+	return c.conn.WriteMessage(c.codec.FrameType(), data)
+}
+
+// startAutoplay claims the connection's autoplay slot, returning the session's
+// context. It reports false if a session is already running.
+func (c *client) startAutoplay() (context.Context, bool) {
+	c.autoplayMu.Lock()
+	defer c.autoplayMu.Unlock()
+	if c.autoplayCancel != nil {
+		return nil, false
+	}
+	ctx, cancel := context.WithCancelCause(context.Background())
+	c.autoplayCancel = cancel
+	c.autoplayDone = make(chan struct{})
+	return ctx, true
+}
+
+func (c *client) autoplayActive() bool {
+	c.autoplayMu.Lock()
+	defer c.autoplayMu.Unlock()
+	return c.autoplayCancel != nil
+}
+
+// stopAutoplay asks a running session to stop after its current round. On
+// disconnect it also waits for the session to finish so nothing writes to a
+// closed connection. It reports whether a session was running.
+func (c *client) stopAutoplay(disconnect bool) bool {
+	c.autoplayMu.Lock()
+	cancel, done := c.autoplayCancel, c.autoplayDone
+	c.autoplayMu.Unlock()
+	if cancel == nil {
+		return false
+	}
+	if disconnect {
+		cancel(errDisconnected)
+		<-done
+	} else {
+		cancel(errAutoplayStopped)
+	}
+	return true
+}
+
+// finishAutoplay releases the autoplay slot. Called by the session goroutine on exit.
+func (c *client) finishAutoplay() {
+	c.autoplayMu.Lock()
+	defer c.autoplayMu.Unlock()
+	c.autoplayCancel(nil)
+	close(c.autoplayDone)
+	c.autoplayCancel = nil
+	c.autoplayDone = nil
+}
@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/BrunoSena97/dice_game_backend/internal/constants"
+	"github.com/gorilla/websocket"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Codec encodes and decodes WebSocket messages for one wire format.
+// Every codec works on the same message structs; only the bytes differ.
+type Codec interface {
+	// Name is the WebSocket subprotocol that selects this codec.
+	Name() string
+	// FrameType is the websocket frame type used for this encoding.
+	FrameType() int
+	// DecodeEnvelope splits a frame into its message type and still-encoded payload.
+	DecodeEnvelope(data []byte) (msgType string, payload []byte, err error)
+	// DecodePayload decodes a payload returned by DecodeEnvelope into v.
+	DecodePayload(payload []byte, v interface{}) error
+	// Encode serialises a server message into a single frame.
+	Encode(msg ServerMessage) ([]byte, error)
+}
+
+// Subprotocols lists the WebSocket subprotocols the server accepts, in order of preference.
+func Subprotocols() []string {
+	return []string{constants.SubprotocolMsgpack, constants.SubprotocolJSON}
+}
+
+// codecForSubprotocol returns the codec negotiated during the upgrade.
+// Clients that request no subprotocol get JSON.
+func codecForSubprotocol(subprotocol string) Codec {
+	switch subprotocol {
+	case constants.SubprotocolMsgpack:
+		return msgpackCodec{}
+	default:
+		return jsonCodec{}
+	}
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string   { return constants.SubprotocolJSON }
+func (jsonCodec) FrameType() int { return websocket.TextMessage }
+
+func (jsonCodec) DecodeEnvelope(data []byte) (string, []byte, error) {
+	var msg WsMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return "", nil, fmt.Errorf("invalid JSON envelope: %w", err)
+	}
+	return msg.Type, msg.Payload, nil
+}
+
+func (jsonCodec) DecodePayload(payload []byte, v interface{}) error {
+	return json.Unmarshal(payload, v)
+}
+
+func (jsonCodec) Encode(msg ServerMessage) ([]byte, error) {
+	return json.Marshal(msg)
+}
+
+// msgpackEnvelope mirrors WsMessage, deferring payload decoding until the type is known.
+type msgpackEnvelope struct {
+	Type    string             `msgpack:"type"`
+	Payload msgpack.RawMessage `msgpack:"payload"`
+}
+
+// msgpackCodec encodes messages as MessagePack maps keyed by the JSON field names,
+// so both encodings share one schema.
+type msgpackCodec struct{}
+
+func (msgpackCodec) Name() string   { return constants.SubprotocolMsgpack }
+func (msgpackCodec) FrameType() int { return websocket.BinaryMessage }
+
+func (msgpackCodec) DecodeEnvelope(data []byte) (string, []byte, error) {
+	var msg msgpackEnvelope
+	if err := msgpack.Unmarshal(data, &msg); err != nil {
+		return "", nil, fmt.Errorf("invalid MessagePack envelope: %w", err)
+	}
+	return msg.Type, msg.Payload, nil
+}
+
+func (msgpackCodec) DecodePayload(payload []byte, v interface{}) error {
+	dec := msgpack.NewDecoder(bytes.NewReader(payload))
+	dec.SetCustomStructTag("json")
+	return dec.Decode(v)
+}
+
+func (msgpackCodec) Encode(msg ServerMessage) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+	enc.UseCompactInts(true)
+	if err := enc.Encode(msg); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,166 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// TestCodecsRoundTripEveryMessage encodes every protocol message, with every
+// field set, in both wire formats. Each must decode back to the same payload,
+// and both must carry the same field names.
+func TestCodecsRoundTripEveryMessage(t *testing.T) {
+	codecs := []Codec{jsonCodec{}, msgpackCodec{}}
+
+	for _, m := range ProtocolSpec().Messages {
+		t.Run(m.Type, func(t *testing.T) {
+			payloadType := reflect.TypeOf(m.Payload)
+			want := reflect.New(payloadType)
+			seed := 0
+			fill(want.Elem(), &seed)
+
+			fields := make(map[string][]string, len(codecs))
+			for _, codec := range codecs {
+				data, err := codec.Encode(ServerMessage{Type: m.Type, Payload: want.Elem().Interface()})
+				if err != nil {
+					t.Fatalf("%s: encode: %v", codec.Name(), err)
+				}
+				msgType, payload, err := codec.DecodeEnvelope(data)
+				if err != nil {
+					t.Fatalf("%s: decode envelope: %v", codec.Name(), err)
+				}
+				if msgType != m.Type {
+					t.Errorf("%s: type = %q, want %q", codec.Name(), msgType, m.Type)
+				}
+				got := reflect.New(payloadType)
+				if err := codec.DecodePayload(payload, got.Interface()); err != nil {
+					t.Fatalf("%s: decode payload: %v", codec.Name(), err)
+				}
+				inUTC(got.Elem())
+				if !reflect.DeepEqual(got.Elem().Interface(), want.Elem().Interface()) {
+					t.Errorf("%s round trip:\n got %+v\nwant %+v", codec.Name(), got.Elem().Interface(), want.Elem().Interface())
+				}
+
+				fields[codec.Name()], err = wireFields(codec, data)
+				if err != nil {
+					t.Fatalf("%s: decode generically: %v", codec.Name(), err)
+				}
+			}
+
+			jsonFields, msgpackFields := fields[jsonCodec{}.Name()], fields[msgpackCodec{}.Name()]
+			if !slices.Equal(jsonFields, msgpackFields) {
+				t.Errorf("field names differ:\n JSON    %v\n msgpack %v", jsonFields, msgpackFields)
+			}
+		})
+	}
+}
+
+// fill sets every exported field of v to a distinct non-zero value. Slices
+// and maps get two elements.
+func fill(v reflect.Value, seed *int) {
+	*seed++
+	n := *seed
+	if v.Type() == reflect.TypeOf(time.Time{}) {
+		v.Set(reflect.ValueOf(time.Unix(int64(1_700_000_000+n), int64(n*1000)).UTC()))
+		return
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(fmt.Sprintf("s%d", n))
+	case reflect.Bool:
+		v.SetBool(true)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(int64(n))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v.SetUint(uint64(n))
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(float64(n) + 0.5)
+	case reflect.Pointer:
+		v.Set(reflect.New(v.Type().Elem()))
+		fill(v.Elem(), seed)
+	case reflect.Slice:
+		v.Set(reflect.MakeSlice(v.Type(), 2, 2))
+		for i := range v.Len() {
+			fill(v.Index(i), seed)
+		}
+	case reflect.Map:
+		v.Set(reflect.MakeMap(v.Type()))
+		for range 2 {
+			key, elem := reflect.New(v.Type().Key()).Elem(), reflect.New(v.Type().Elem()).Elem()
+			fill(key, seed)
+			fill(elem, seed)
+			v.SetMapIndex(key, elem)
+		}
+	case reflect.Struct:
+		for i := range v.NumField() {
+			if v.Type().Field(i).IsExported() {
+				fill(v.Field(i), seed)
+			}
+		}
+	default:
+		panic(fmt.Sprintf("fill: unsupported field type %s", v.Type()))
+	}
+}
+
+// inUTC converts every time in v to UTC; MessagePack decodes times as local.
+func inUTC(v reflect.Value) {
+	if v.Type() == reflect.TypeOf(time.Time{}) {
+		v.Set(reflect.ValueOf(v.Interface().(time.Time).UTC()))
+		return
+	}
+	switch v.Kind() {
+	case reflect.Pointer:
+		if !v.IsNil() {
+			inUTC(v.Elem())
+		}
+	case reflect.Slice:
+		for i := range v.Len() {
+			inUTC(v.Index(i))
+		}
+	case reflect.Struct:
+		for i := range v.NumField() {
+			if v.Type().Field(i).IsExported() {
+				inUTC(v.Field(i))
+			}
+		}
+	}
+}
+
+// wireFields decodes a frame without the message structs and lists the path
+// of every map key in it, sorted.
+func wireFields(codec Codec, data []byte) ([]string, error) {
+	var generic any
+	var err error
+	if _, ok := codec.(msgpackCodec); ok {
+		err = msgpack.Unmarshal(data, &generic)
+	} else {
+		err = json.Unmarshal(data, &generic)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	collectFields(generic, "", &paths)
+	sort.Strings(paths)
+	return paths, nil
+}
+
+func collectFields(v any, prefix string, paths *[]string) {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, elem := range v {
+			*paths = append(*paths, prefix+key)
+			collectFields(elem, prefix+key+".", paths)
+		}
+	case []any:
+		for _, elem := range v {
+			collectFields(elem, prefix+"[].", paths)
+		}
+	}
+}
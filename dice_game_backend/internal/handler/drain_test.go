@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDrainWaitsForRoundsInPlay(t *testing.T) {
+	var h Handler
+	if !h.startPlay() {
+		t.Fatal("round refused before draining")
+	}
+
+	drained := make(chan error, 1)
+	go func() { drained <- h.Drain(context.Background()) }()
+
+	// Drain sets draining before it waits, so poll until new rounds are refused.
+	deadline := time.Now().Add(2 * time.Second)
+	for h.startPlay() {
+		h.plays.Done()
+		if time.Now().After(deadline) {
+			t.Fatal("rounds still accepted while draining")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	select {
+	case err := <-drained:
+		t.Fatalf("Drain returned %v with a round in play", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	h.plays.Done()
+	select {
+	case err := <-drained:
+		if err != nil {
+			t.Fatalf("Drain: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Drain did not return once the round settled")
+	}
+}
+
+func TestDrainGivesUpWithContext(t *testing.T) {
+	var h Handler
+	h.startPlay()
+	defer h.plays.Done()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := h.Drain(ctx); err == nil {
+		t.Fatal("Drain returned without error while a round was in play")
+	}
+}
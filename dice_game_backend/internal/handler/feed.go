@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/BrunoSena97/dice_game_backend/internal/constants"
+	"github.com/BrunoSena97/dice_game_backend/internal/feed"
+	"github.com/BrunoSena97/dice_game_backend/internal/game"
+)
+
+type SubscribeFeedPayload struct {
+	ClientID    string `json:"clientId"`
+	BigWinsOnly bool   `json:"bigWinsOnly,omitempty"`
+}
+
+type UnsubscribeFeedPayload struct {
+	ClientID string `json:"clientId"`
+}
+
+type FeedRoundPayload struct {
+	Player    string    `json:"player"`
+	BetType   string    `json:"betType"`
+	BetAmount int64     `json:"betAmount"`
+	Currency  string    `json:"currency"`
+	Die1      int       `json:"die1"`
+	Die2      int       `json:"die2"`
+	Outcome   string    `json:"outcome"`
+	Winnings  int64     `json:"winnings"`
+	BigWin    bool      `json:"bigWin"`
+	PlayedAt  time.Time `json:"playedAt"`
+}
+
+type FeedSnapshotPayload struct {
+	Rounds []FeedRoundPayload `json:"rounds"`
+}
+
+func toFeedRoundPayload(round feed.Round) FeedRoundPayload {
+	return FeedRoundPayload(round)
+}
+
+func (h *Handler) handleSubscribeFeed(c *client, payloadBytes []byte, clientID string) {
+	var payload SubscribeFeedPayload
+	if err := c.codec.DecodePayload(payloadBytes, &payload); err != nil {
+		log.Printf("[Feed-%s] Error unmarshalling payload: %v", clientID, err)
+		h.sendError(c, constants.ErrCodeBadRequest, "Invalid subscribe_feed payload format")
+		return
+	}
+	if clientID == "" || payload.ClientID != clientID {
+		log.Printf("[Feed-%s] Mismatched or missing ClientID in payload (%s)", clientID, payload.ClientID)
+		h.sendError(c, constants.ErrCodeBadRequest, "Client ID mismatch or missing")
+		return
+	}
+
+	h.unsubscribeFeed(c)
+	opts := feed.Options{BigWinsOnly: payload.BigWinsOnly}
+
+	opCtx, cancel := context.WithTimeout(context.Background(), h.app().Timeouts.ShortOp)
+	recent, err := h.feed.Recent(opCtx, opts)
+	cancel()
+	if err != nil {
+		log.Printf("[Feed-%s] Error loading recent rounds: %v", clientID, err)
+		recent = nil
+	}
+
+	snapshot := FeedSnapshotPayload{Rounds: make([]FeedRoundPayload, 0, len(recent))}
+	for _, round := range recent {
+		snapshot.Rounds = append(snapshot.Rounds, toFeedRoundPayload(round))
+	}
+	if err := h.sendMessage(c, constants.MsgTypeFeedSnapshot, snapshot); err != nil {
+		log.Printf("[Feed-%s] Error sending feed snapshot: %v", clientID, err)
+		return
+	}
+
+	c.unsubscribeFeed = h.feed.Subscribe(opts,
+		func(round feed.Round) {
+			if err := h.sendMessage(c, constants.MsgTypeFeedRound, toFeedRoundPayload(round)); err != nil {
+				log.Printf("[Feed-%s] Error sending feed round: %v", clientID, err)
+			}
+		},
+		func() {
+			log.Printf("[Feed-%s] Subscriber too slow, dropped from feed", clientID)
+			h.sendError(c, constants.ErrCodeFeedDropped, "Live feed stopped because the connection fell behind. Subscribe again to resume.")
+		},
+	)
+	log.Printf("[Feed-%s] Subscribed (bigWinsOnly=%t)", clientID, payload.BigWinsOnly)
+}
+
+func (h *Handler) handleUnsubscribeFeed(c *client, payloadBytes []byte, clientID string) {
+	var payload UnsubscribeFeedPayload
+	if err := c.codec.DecodePayload(payloadBytes, &payload); err != nil {
+		log.Printf("[Feed-%s] Error unmarshalling payload: %v", clientID, err)
+		h.sendError(c, constants.ErrCodeBadRequest, "Invalid unsubscribe_feed payload format")
+		return
+	}
+	h.unsubscribeFeed(c)
+	log.Printf("[Feed-%s] Unsubscribed", clientID)
+}
+
+// unsubscribeFeed drops the connection's feed subscription, if any.
+func (h *Handler) unsubscribeFeed(c *client) {
+	if c.unsubscribeFeed == nil {
+		return
+	}
+	c.unsubscribeFeed()
+	c.unsubscribeFeed = nil
+}
+
+// publishFeedRound broadcasts a settled round without holding up the player.
+func (h *Handler) publishFeedRound(clientID string, payload PlayPayload, result game.GameResult) {
+	round := feed.Round{
+		BetType:   payload.BetType,
+		BetAmount: payload.BetAmount,
+		Currency:  payload.Currency,
+		Die1:      result.Die1,
+		Die2:      result.Die2,
+		Outcome:   result.Outcome,
+		Winnings:  result.Winnings,
+		PlayedAt:  time.Now().UTC(),
+	}
+	go func() {
+		pubCtx, cancel := context.WithTimeout(context.Background(), h.app().Timeouts.ShortOp)
+		defer cancel()
+		if err := h.feed.Publish(pubCtx, clientID, round); err != nil {
+			log.Printf("[Play-%s] Error publishing round to feed: %v", clientID, err)
+		}
+	}()
+}
@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/BrunoSena97/dice_game_backend/internal/constants"
+	"github.com/BrunoSena97/dice_game_backend/internal/freerounds"
+)
+
+type GetFreeRoundsPayload struct {
+	ClientID string `json:"clientId"`
+}
+
+type FreeRoundGrantPayload struct {
+	ID              int64     `json:"id"`
+	Currency        string    `json:"currency"`
+	BetType         string    `json:"betType"`
+	Stake           int64     `json:"stake"`
+	RoundsGranted   int       `json:"roundsGranted"`
+	RoundsRemaining int       `json:"roundsRemaining"`
+	ExpiresAt       time.Time `json:"expiresAt"`
+}
+
+// FreeRoundsPayload lists the player's playable free round grants, newest first.
+type FreeRoundsPayload struct {
+	ClientID string                  `json:"clientId"`
+	Grants   []FreeRoundGrantPayload `json:"grants"`
+}
+
+func (h *Handler) handleGetFreeRounds(c *client, payloadBytes []byte, clientID string) {
+	var payload GetFreeRoundsPayload
+	if err := c.codec.DecodePayload(payloadBytes, &payload); err != nil {
+		log.Printf("[FreeRounds-%s] Error unmarshalling payload: %v", clientID, err)
+		h.sendError(c, constants.ErrCodeBadRequest, "Invalid get_free_rounds payload format")
+		return
+	}
+	if clientID == "" || payload.ClientID != clientID {
+		log.Printf("[FreeRounds-%s] Mismatched or missing ClientID in payload (%s)", clientID, payload.ClientID)
+		h.sendError(c, constants.ErrCodeBadRequest, "Client ID mismatch or missing")
+		return
+	}
+
+	opCtx, cancel := context.WithTimeout(context.Background(), h.app().Timeouts.ShortOp)
+	defer cancel()
+
+	grants, err := h.freeRounds.List(opCtx, clientID, true)
+	if err != nil {
+		log.Printf("[FreeRounds-%s] Error listing grants: %v", clientID, err)
+		h.sendError(c, constants.ErrCodeInternalError, "Failed to load free rounds.")
+		return
+	}
+
+	resp := FreeRoundsPayload{ClientID: clientID, Grants: make([]FreeRoundGrantPayload, 0, len(grants))}
+	for _, g := range grants {
+		resp.Grants = append(resp.Grants, FreeRoundGrantPayload{
+			ID:              g.ID,
+			Currency:        g.Currency,
+			BetType:         g.BetType,
+			Stake:           g.Stake,
+			RoundsGranted:   g.RoundsGranted,
+			RoundsRemaining: g.RoundsRemaining,
+			ExpiresAt:       g.ExpiresAt,
+		})
+	}
+	if err := h.sendMessage(c, constants.MsgTypeFreeRounds, resp); err != nil {
+		log.Printf("[FreeRounds-%s] Error sending free rounds: %v", clientID, err)
+	}
+}
+
+// applyFreeRoundGrant fills a free round play with the stake, bet type and
+// currency of its grant. The grant is only checked here so the player gets a
+// clear error early; it is consumed, and checked again, when the round settles.
+func (h *Handler) applyFreeRoundGrant(c *client, clientID string, payload *PlayPayload) bool {
+	opCtx, cancel := context.WithTimeout(context.Background(), h.app().Timeouts.ShortOp)
+	defer cancel()
+
+	grant, err := h.freeRounds.Get(opCtx, clientID, payload.FreeRoundGrantID)
+	if err != nil && !errors.Is(err, freerounds.ErrGrantNotFound) {
+		log.Printf("[Play-%s] Error loading free round grant %d: %v", clientID, payload.FreeRoundGrantID, err)
+		h.sendError(c, constants.ErrCodeInternalError, "Failed to load free round grant.")
+		return false
+	}
+	if err != nil || !grant.Active(time.Now()) {
+		log.Printf("[Play-%s] Free round grant %d is not playable", clientID, payload.FreeRoundGrantID)
+		h.sendError(c, constants.ErrCodeFreeRoundUnavailable, "This free round grant is used up, expired or unknown.")
+		return false
+	}
+
+	payload.BetAmount = grant.Stake
+	payload.BetType = grant.BetType
+	payload.Currency = grant.Currency
+	return true
+}
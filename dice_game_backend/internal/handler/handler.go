@@ -6,13 +6,23 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/BrunoSena97/dice_game_backend/internal/config"
 	"github.com/BrunoSena97/dice_game_backend/internal/constants"
+	"github.com/BrunoSena97/dice_game_backend/internal/feed"
+	"github.com/BrunoSena97/dice_game_backend/internal/freerounds"
 	"github.com/BrunoSena97/dice_game_backend/internal/game"
+	"github.com/BrunoSena97/dice_game_backend/internal/jackpot"
+	"github.com/BrunoSena97/dice_game_backend/internal/leaderboard"
+	"github.com/BrunoSena97/dice_game_backend/internal/limits"
+	"github.com/BrunoSena97/dice_game_backend/internal/notify"
+	"github.com/BrunoSena97/dice_game_backend/internal/platform/database"
+	"github.com/BrunoSena97/dice_game_backend/internal/playlock"
+	"github.com/BrunoSena97/dice_game_backend/internal/settlement"
 	"github.com/BrunoSena97/dice_game_backend/internal/wallet"
-	"github.com/go-redis/redis/v8"
 	"github.com/gorilla/websocket"
 )
 
@@ -26,37 +36,91 @@ type ServerMessage struct {
 	Payload interface{} `json:"payload"`
 }
 
+type HelloPayload struct {
+	ClientID        string `json:"clientId"`
+	ProtocolVersion int    `json:"protocolVersion"`
+}
+
+type HelloAckPayload struct {
+	ClientID          string         `json:"clientId"`
+	ProtocolVersion   int            `json:"protocolVersion"`
+	SupportedVersions []int          `json:"supportedVersions"`
+	BetTypes          []string       `json:"betTypes"`
+	MaxBetAmount      int64          `json:"maxBetAmount"`
+	Currency          string         `json:"currency"`
+	Currencies        []CurrencyInfo `json:"currencies"`
+	Features          []string       `json:"features"`
+}
+
+type CurrencyInfo struct {
+	Code         string `json:"code"`
+	MinBetAmount int64  `json:"minBetAmount"`
+	MaxBetAmount int64  `json:"maxBetAmount"`
+}
+
 type GetBalancePayload struct {
 	ClientID string `json:"clientId"`
+	Currency string `json:"currency,omitempty"`
 }
 
+// PlayPayload starts a round. With FreeRoundGrantID the round is played on
+// that free round grant, whose stake, bet type and currency replace the
+// payload's.
 type PlayPayload struct {
-	ClientID  string `json:"clientId"`
-	BetAmount int64  `json:"betAmount"`
-	BetType   string `json:"betType"`
+	ClientID         string `json:"clientId"`
+	BetAmount        int64  `json:"betAmount"`
+	BetType          string `json:"betType"`
+	Currency         string `json:"currency,omitempty"`
+	FreeRoundGrantID int64  `json:"freeRoundGrantId,omitempty"`
 }
 
 type EndPlayPayload struct {
 	ClientID string `json:"clientId"`
 }
 
+// BalanceUpdatePayload carries the cash balance. Bonus is set in replies to
+// play and get_balance; pushed balance changes leave it out.
 type BalanceUpdatePayload struct {
-	ClientID string `json:"clientId"`
-	Balance  int64  `json:"balance"`
+	ClientID string        `json:"clientId"`
+	Balance  int64         `json:"balance"`
+	Currency string        `json:"currency"`
+	Bonus    *BonusPayload `json:"bonus,omitempty"`
 }
 
+// BonusPayload is the bonus balance and how much of its wagering requirement
+// has been played. Both wagering fields are zero when no bonus is pending.
+type BonusPayload struct {
+	Balance          int64 `json:"balance"`
+	WageringRequired int64 `json:"wageringRequired"`
+	WageringProgress int64 `json:"wageringProgress"`
+}
+
+func newBonusPayload(b wallet.Bonus) *BonusPayload {
+	return &BonusPayload{Balance: b.Balance, WageringRequired: b.WageringRequired, WageringProgress: b.WageringProgress}
+}
+
+// PlayResultPayload reports a settled round. The free round fields are only
+// set for rounds played on a grant; JackpotWin only when the round won the jackpot.
 type PlayResultPayload struct {
-	ClientID  string `json:"clientId"`
-	Die1      int    `json:"die1"`
-	Die2      int    `json:"die2"`
-	Outcome   string `json:"outcome"`
-	BetAmount int64  `json:"betAmount"`
-	Winnings  int64  `json:"winnings"`
+	ClientID            string `json:"clientId"`
+	Die1                int    `json:"die1"`
+	Die2                int    `json:"die2"`
+	Outcome             string `json:"outcome"`
+	BetAmount           int64  `json:"betAmount"`
+	Winnings            int64  `json:"winnings"`
+	Currency            string `json:"currency"`
+	FreeRoundGrantID    int64  `json:"freeRoundGrantId,omitempty"`
+	FreeRoundsRemaining *int   `json:"freeRoundsRemaining,omitempty"`
+	JackpotWin          int64  `json:"jackpotWin,omitempty"`
 }
 
+// PlayEndedPayload reports the default currency balance in FinalBalance and
+// every wallet the player holds in Balances.
 type PlayEndedPayload struct {
-	ClientID     string `json:"clientId"`
-	FinalBalance int64  `json:"finalBalance"`
+	ClientID     string           `json:"clientId"`
+	FinalBalance int64            `json:"finalBalance"`
+	Currency     string           `json:"currency"`
+	Balances     map[string]int64 `json:"balances,omitempty"`
 }
 
 type ErrorPayload struct {
@@ -67,38 +131,126 @@ type ErrorPayload struct {
 // Handler manages incoming requests/connections.
 type Handler struct {
 	walletSvc   wallet.WalletService
-	redisClient *redis.Client
+	playLocker  playlock.Locker
 	gameSvc     game.GameService
-	appConfig   config.AppConfig
+	balanceHub  *notify.BalanceHub
+	feed        *feed.Feed
+	leaderboard *leaderboard.Service
+	limitsSvc   *limits.Service
+	freeRounds  *freerounds.Service
+	// jackpot runs the jackpot pools; nil when the jackpot is disabled.
+	jackpot *jackpot.Service
+	// settler batches round settlement; nil settles each round immediately.
+	settler   *settlement.Batcher
+	appConfig atomic.Pointer[config.AppConfig]
+
+	// draining is set by Drain; plays counts rounds that started before it.
+	drainMu  sync.Mutex
+	draining bool
+	plays    sync.WaitGroup
 }
 
-// NewHandler creates a new Handler instance.
-func NewHandler(walletSvc wallet.WalletService, redisClient *redis.Client, gameSvc game.GameService, appCfg config.AppConfig) *Handler {
+// NewHandler creates a new Handler instance. jackpotSvc may be nil to run
+// without a jackpot, and settler may be nil to settle rounds immediately.
+func NewHandler(walletSvc wallet.WalletService, playLocker playlock.Locker, gameSvc game.GameService, balanceHub *notify.BalanceHub, liveFeed *feed.Feed, leaderboardSvc *leaderboard.Service, limitsSvc *limits.Service, freeRoundSvc *freerounds.Service, jackpotSvc *jackpot.Service, settler *settlement.Batcher, appCfg config.AppConfig) *Handler {
 	if walletSvc == nil {
 		log.Fatal("WalletService is nil in NewHandler")
 	}
-	if redisClient == nil {
-		log.Fatal("RedisClient is nil in NewHandler")
+	if playLocker == nil {
+		log.Fatal("PlayLocker is nil in NewHandler")
 	}
 	if gameSvc == nil {
 		log.Fatal("GameService is nil in NewHandler")
 	}
-	return &Handler{
+	if balanceHub == nil {
+		log.Fatal("BalanceHub is nil in NewHandler")
+	}
+	if liveFeed == nil {
+		log.Fatal("Feed is nil in NewHandler")
+	}
+	if leaderboardSvc == nil {
+		log.Fatal("LeaderboardService is nil in NewHandler")
+	}
+	if limitsSvc == nil {
+		log.Fatal("LimitsService is nil in NewHandler")
+	}
+	if freeRoundSvc == nil {
+		log.Fatal("FreeRoundService is nil in NewHandler")
+	}
+	h := &Handler{
 		walletSvc:   walletSvc,
-		redisClient: redisClient,
+		playLocker:  playLocker,
 		gameSvc:     gameSvc,
-		appConfig:   appCfg,
+		balanceHub:  balanceHub,
+		feed:        liveFeed,
+		leaderboard: leaderboardSvc,
+		limitsSvc:   limitsSvc,
+		freeRounds:  freeRoundSvc,
+		jackpot:     jackpotSvc,
+		settler:     settler,
+	}
+	h.appConfig.Store(&appCfg)
+	return h
+}
+
+// app returns the current application config. It is swapped on hot reload, so
+// read it once per operation when several values must agree.
+func (h *Handler) app() *config.AppConfig {
+	return h.appConfig.Load()
+}
+
+// ApplyConfig swaps in a reloaded application config. Connections are kept;
+// messages handled afterwards see the new limits.
+func (h *Handler) ApplyConfig(appCfg config.AppConfig) {
+	h.appConfig.Store(&appCfg)
+}
+
+// Drain refuses new rounds, manual and autoplay, and waits until every round
+// already started has settled or ctx is done. Call it on shutdown before
+// stopping the settlement batcher, so no round is left without a settler.
+func (h *Handler) Drain(ctx context.Context) error {
+	h.drainMu.Lock()
+	h.draining = true
+	h.drainMu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		h.plays.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
 }
 
+// startPlay registers a round with Drain. It returns false once draining has
+// begun; otherwise the caller must call h.plays.Done when the round is over.
+func (h *Handler) startPlay() bool {
+	h.drainMu.Lock()
+	defer h.drainMu.Unlock()
+	if h.draining {
+		return false
+	}
+	h.plays.Add(1)
+	return true
+}
+
 // HandleClient manages a single websocket connection.
 func (h *Handler) HandleClient(conn *websocket.Conn) {
 	defer conn.Close()
 	// TODO: Implement Client ID assignment and association with 'conn'
 
+	c := newClient(conn, h.app().Timeouts.WSWrite)
+	defer h.unsubscribeBalances(c)
+	defer h.unsubscribeFeed(c)
+	defer h.unsubscribeJackpot(c)
+	defer c.stopAutoplay(true)
 	var currentClientID string
 
-	log.Printf("Client connected: %s", conn.RemoteAddr())
+	log.Printf("Client connected: %s (encoding: %s)", conn.RemoteAddr(), c.codec.Name())
 
 	for {
 		messageType, messageBytes, err := conn.ReadMessage()
@@ -107,248 +259,330 @@ func (h *Handler) HandleClient(conn *websocket.Conn) {
 			break
 		}
 
-		if messageType != websocket.TextMessage {
-			log.Printf("Received non-text message type: %d from %s. Skipping.", messageType, conn.RemoteAddr())
+		if !c.limiter.allow(h.app().RateLimit, time.Now()) {
+			h.sendError(c, constants.ErrCodeRateLimited, "Too many messages, slow down.")
 			continue
 		}
 
-		var msg WsMessage
-		if err := json.Unmarshal(messageBytes, &msg); err != nil {
-			log.Printf("Error unmarshalling base message from %s: %v. Raw: %s", conn.RemoteAddr(), err, string(messageBytes))
-			h.sendError(conn, constants.ErrCodeBadRequest, "Invalid message format")
+		if messageType != c.codec.FrameType() {
+			log.Printf("Received unexpected frame type %d for %s encoding from %s. Skipping.", messageType, c.codec.Name(), conn.RemoteAddr())
 			continue
 		}
 
-		clientID, err := extractClientID(msg)
+		msgType, payloadBytes, err := c.codec.DecodeEnvelope(messageBytes)
+		if err != nil {
+			log.Printf("Error decoding base message from %s: %v. Raw: %q", conn.RemoteAddr(), err, messageBytes)
+			h.sendError(c, constants.ErrCodeBadRequest, "Invalid message format")
+			continue
+		}
+
+		clientID, err := extractClientID(c.codec, msgType, payloadBytes)
 		if err == nil && clientID != "" {
 			currentClientID = clientID
+			h.subscribePushes(c, currentClientID)
 		}
 
-		log.Printf("Received message type: %s for client %s from %s", msg.Type, currentClientID, conn.RemoteAddr())
+		log.Printf("Received message type: %s for client %s from %s", msgType, currentClientID, conn.RemoteAddr())
+
+		if c.legacy() && requiresHello(msgType) {
+			log.Printf("Refused %s from client %s: no hello sent", msgType, currentClientID)
+			h.sendError(c, constants.ErrCodeHelloRequired, fmt.Sprintf("Send hello to negotiate a protocol version before %s.", msgType))
+			continue
+		}
 
-		switch msg.Type {
+		switch msgType {
+		case constants.MsgTypeHello:
+			negotiated, ok := h.handleHello(c, payloadBytes, currentClientID)
+			if !ok {
+				log.Printf("Closing connection after failed hello for client %s", currentClientID)
+				return
+			}
+			c.protocolVersion.Store(int64(negotiated))
+			h.subscribePushes(c, currentClientID)
 		case constants.MsgTypePlay:
-			h.handlePlay(conn, msg.Payload, currentClientID)
+			h.handlePlay(c, payloadBytes, currentClientID)
 		case constants.MsgTypeGetBalance:
-			h.handleGetBalance(conn, msg.Payload, currentClientID)
+			h.handleGetBalance(c, payloadBytes, currentClientID)
+		case constants.MsgTypeAutoplayStart:
+			h.handleAutoplayStart(c, payloadBytes, currentClientID)
+		case constants.MsgTypeAutoplayStop:
+			h.handleAutoplayStop(c, payloadBytes, currentClientID)
+		case constants.MsgTypeGetLeaderboard:
+			h.handleGetLeaderboard(c, payloadBytes, currentClientID)
+		case constants.MsgTypeGetLimits:
+			h.handleGetLimits(c, payloadBytes, currentClientID)
+		case constants.MsgTypeSetLimit:
+			h.handleSetLimit(c, payloadBytes, currentClientID)
+		case constants.MsgTypeSelfExclude:
+			h.handleSelfExclude(c, payloadBytes, currentClientID)
+		case constants.MsgTypeSubscribeFeed:
+			h.handleSubscribeFeed(c, payloadBytes, currentClientID)
+		case constants.MsgTypeUnsubscribeFeed:
+			h.handleUnsubscribeFeed(c, payloadBytes, currentClientID)
+		case constants.MsgTypeGetFreeRounds:
+			h.handleGetFreeRounds(c, payloadBytes, currentClientID)
 		case constants.MsgTypeEndPlay:
-			h.handleEndPlay(conn, msg.Payload, currentClientID)
+			h.handleEndPlay(c, payloadBytes, currentClientID)
 			log.Printf("Closing connection after end_play request for client %s", currentClientID)
 			return
 		default:
-			log.Printf("Received unknown message type: %s from client %s", msg.Type, currentClientID)
-			h.sendError(conn, constants.ErrCodeUnknownType, "Unknown message type received.")
+			log.Printf("Received unknown message type: %s from client %s", msgType, currentClientID)
+			h.sendError(c, constants.ErrCodeUnknownType, "Unknown message type received.")
 		}
 	}
 
-	log.Printf("Client handler exiting for %s (Client ID: %s)", conn.RemoteAddr(), currentClientID)
+	log.Printf("Client handler exiting for %s (Client ID: %s, protocol v%d)", conn.RemoteAddr(), currentClientID, c.protocolVersion.Load())
 }
 
 // Private handlers
-func (h *Handler) handlePlay(conn *websocket.Conn, payloadJSON json.RawMessage, clientID string) {
+func (h *Handler) handlePlay(c *client, payloadBytes []byte, clientID string) {
 	var payload PlayPayload
-	if err := json.Unmarshal(payloadJSON, &payload); err != nil {
+	if err := c.codec.DecodePayload(payloadBytes, &payload); err != nil {
 		log.Printf("[Play-%s] Error unmarshalling payload: %v", clientID, err)
-		h.sendError(conn, constants.ErrCodeBadRequest, "Invalid play payload format")
+		h.sendError(c, constants.ErrCodeBadRequest, "Invalid play payload format")
 		return
 	}
 
 	if clientID == "" || payload.ClientID != clientID {
 		log.Printf("[Play-%s] Mismatched or missing ClientID in payload (%s)", clientID, payload.ClientID)
-		h.sendError(conn, constants.ErrCodeBadRequest, "Client ID mismatch or missing")
+		h.sendError(c, constants.ErrCodeBadRequest, "Client ID mismatch or missing")
 		return
 	}
 
-	log.Printf("[Play-%s] Processing [Bet: %d, Type: %s]...",
-		clientID, payload.BetAmount, payload.BetType)
+	if payload.Currency == "" {
+		payload.Currency = h.app().DefaultCurrency
+	}
+	if c.autoplayActive() {
+		h.sendError(c, constants.ErrCodeAutoplayActive, "Autoplay is running; stop it before playing manually.")
+		return
+	}
+	if payload.FreeRoundGrantID != 0 && !h.applyFreeRoundGrant(c, clientID, &payload) {
+		return
+	}
+	h.settlePlay(c, clientID, payload)
+}
+
+// playOutcome is the result of one settled round.
+type playOutcome struct {
+	Result       game.GameResult
+	FinalBalance int64
+}
+
+// settlePlay runs one round for a validated-shape payload: limit checks, lock,
+// game, settlement and result messages. It is shared by manual play and
+// autoplay. Failures are reported to the client; the bool is false when the
+// round was refused or did not settle.
+func (h *Handler) settlePlay(c *client, clientID string, payload PlayPayload) (playOutcome, bool) {
+	currency := payload.Currency
 
-	opCtx, cancel := context.WithTimeout(context.Background(), time.Duration(constants.HandlerOpTimeout)*time.Second)
+	log.Printf("[Play-%s] Processing [Bet: %d %s, Type: %s]...",
+		clientID, payload.BetAmount, currency, payload.BetType)
+
+	if !h.startPlay() {
+		log.Printf("[Play-%s] Refused: server is shutting down.", clientID)
+		h.sendError(c, constants.ErrCodeShuttingDown, "Server is shutting down, please reconnect.")
+		return playOutcome{}, false
+	}
+	defer h.plays.Done()
+
+	opCtx, cancel := context.WithTimeout(context.Background(), h.app().Timeouts.HandlerOp)
 	defer cancel()
 
 	if err := h.validatePlayPayload(payload); err != nil {
 		log.Printf("[Play-%s] Validation failed: %v", clientID, err)
 		// Send specific error based on validation failure
 		errCode, errMsg := validationErrorToCode(err)
-		h.sendError(conn, errCode, errMsg)
-		return
+		h.sendError(c, errCode, errMsg)
+		return playOutcome{}, false
 	}
 
-	activePlayKey := constants.RedisKeyPrefixActivePlay + clientID
-	lockAcquired, lockErr := h.acquireRedisLock(opCtx, activePlayKey)
+	lock, lockAcquired, lockErr := h.playLocker.Acquire(opCtx, clientID)
 	if lockErr != nil {
-		log.Printf("[Play-%s] REDIS ERROR checking/setting lock: %v", clientID, lockErr)
-		h.sendError(conn, constants.ErrCodeInternalError, "Failed to check play status.")
-		return
+		log.Printf("[Play-%s] ERROR checking/setting play lock: %v", clientID, lockErr)
+		h.sendError(c, constants.ErrCodeInternalError, "Failed to check play status.")
+		return playOutcome{}, false
 	}
 	if !lockAcquired {
 		log.Printf("[Play-%s] Attempted concurrent play.", clientID)
-		h.sendError(conn, constants.ErrCodeActivePlayExists, "Previous play still processing.")
-		return
+		h.sendError(c, constants.ErrCodeActivePlayExists, "Previous play still processing.")
+		return playOutcome{}, false
 	}
 
+	c.playing.Store(true)
+	defer c.playing.Store(false)
+
 	defer func() {
-		if lockAcquired {
-			released := h.releaseRedisLock(activePlayKey)
-			if !released {
-				log.Printf("[Play-%s] WARN: Failed to release active_play lock: %s", clientID, activePlayKey)
-				h.sendError(conn, constants.ErrCodeFailedLockRelease, "Lock release failed, state may be inconsistent.")
-			}
+		if err := lock.Release(); err != nil {
+			log.Printf("[Play-%s] WARN: Failed to release %s play lock: %v", clientID, lock.Backend(), err)
+			h.sendError(c, constants.ErrCodeFailedLockRelease, "Lock release failed, state may be inconsistent.")
 		}
 	}()
 
-	ensureCtx, ensureCancel := context.WithTimeout(opCtx, time.Duration(constants.ShortOpTimeout)*time.Second)
-	err := h.walletSvc.EnsureWalletExists(ensureCtx, clientID)
+	// Paid rounds are checked against the player's limits by the statement
+	// that settles them. A free round risks none of the player's money, so
+	// only self-exclusion and the session reminder apply to it, checked here.
+	if payload.FreeRoundGrantID != 0 {
+		reminder, limitErr := h.limitsSvc.CheckPlay(opCtx, clientID, currency, 0)
+		if limitErr != nil {
+			var breach *limits.BreachError
+			if errors.As(limitErr, &breach) {
+				h.sendBreach(c, clientID, breach)
+			} else {
+				log.Printf("[Play-%s] Error checking limits: %v", clientID, limitErr)
+				h.sendError(c, constants.ErrCodeInternalError, "Failed to check player limits.")
+			}
+			return playOutcome{}, false
+		}
+		h.remindSession(c, clientID, reminder)
+	}
+
+	// Known wallets are remembered by the wallet service, so this only reaches
+	// Postgres on a player's first play in a currency.
+	ensureCtx, ensureCancel := context.WithTimeout(opCtx, h.app().Timeouts.ShortOp)
+	err := h.walletSvc.EnsureWalletExists(ensureCtx, clientID, currency)
 	ensureCancel()
 	if err != nil {
 		log.Printf("[Play-%s] Error ensuring wallet exists: %v", clientID, err)
-		h.sendError(conn, constants.ErrCodeInternalError, "Could not prepare wallet.")
-		return
+		h.sendError(c, constants.ErrCodeInternalError, "Could not prepare wallet.")
+		return playOutcome{}, false
 	}
 
-	_, debitErr := h.walletSvc.UpdateBalance(opCtx, clientID, -payload.BetAmount)
-	if debitErr != nil {
-		if errors.Is(debitErr, wallet.ErrInsufficientFunds) {
-			h.sendError(conn, constants.ErrCodeInsufficientFunds, "You do not have enough balance for this bet.")
-		} else {
-			log.Printf("[Play-%s] Wallet debit error: %v", clientID, debitErr)
-			h.sendError(conn, constants.ErrCodeInternalError, "Failed to process bet debit.")
-		}
-		return
+	settled, ok := h.settleRound(opCtx, c, clientID, payload)
+	if !ok {
+		return playOutcome{}, false
 	}
-	log.Printf("[Play-%s] Debited %d", clientID, payload.BetAmount)
-
-	gameResult, gameErr := h.gameSvc.PlayRound(opCtx, payload.BetType, payload.BetAmount)
-	if gameErr != nil {
-		log.Printf("[Play-%s] Error during game logic: %v", clientID, gameErr)
-		h.sendError(conn, constants.ErrCodeInternalError, "Failed during game logic.")
-		refundCtx, refundCancel := context.WithTimeout(context.Background(), 5*time.Second)
-		_, refundErr := h.walletSvc.UpdateBalance(refundCtx, clientID, payload.BetAmount)
-		refundCancel()
-		if refundErr != nil {
-			log.Printf("[Play-%s] CRITICAL: Failed to refund debit after game error: %v", clientID, refundErr)
-		}
-		return
-	}
-
-	var finalBalance int64 = -1
-	var creditErr error
-
-	if gameResult.Winnings > 0 {
-		amountToCredit := payload.BetAmount + gameResult.Winnings
-		log.Printf("[Play-%s] Crediting %d (bet %d + win %d)", clientID, amountToCredit, payload.BetAmount, gameResult.Winnings)
-		creditCtx, creditCancel := context.WithTimeout(context.Background(), 5*time.Second)
-		finalBalance, creditErr = h.walletSvc.UpdateBalance(creditCtx, clientID, amountToCredit)
-		creditCancel()
-
-		if creditErr != nil {
-			log.Printf("[Play-%s] CRITICAL: Failed to credit winnings %d: %v", clientID, amountToCredit, creditErr)
-			h.sendError(conn, constants.ErrCodeInternalError, "Failed to credit winnings.")
-			balCtx, balCancel := context.WithTimeout(context.Background(), time.Duration(constants.ShortOpTimeout)*time.Second)
-			currentBalance, _ := h.walletSvc.GetBalance(balCtx, clientID)
-			balCancel()
-			finalBalance = currentBalance
-		} else {
-			log.Printf("[Play-%s] Credited %d, new balance %d", clientID, amountToCredit, finalBalance)
-		}
-	} else {
-		balCtx, balCancel := context.WithTimeout(context.Background(), time.Duration(constants.ShortOpTimeout)*time.Second)
-		currentBalance, balanceErr := h.walletSvc.GetBalance(balCtx, clientID)
-		balCancel()
-		if balanceErr != nil {
-			log.Printf("[Play-%s] Error getting balance after loss: %v", clientID, balanceErr)
-			h.sendError(conn, constants.ErrCodeInternalError, "Failed to retrieve balance state.")
-			finalBalance = -1
-		} else {
-			finalBalance = currentBalance
-		}
+	gameResult, finalBalance := settled.result, settled.settled.Balance
+	h.remindSession(c, clientID, settled.settled.SessionReminder)
+	if won := settled.settled.JackpotWon; won > 0 {
+		log.Printf("[Play-%s] Won the %s jackpot of %d on round %d", clientID, currency, won, settled.settled.Round.ID)
 	}
 
 	resultPayload := PlayResultPayload{
-		ClientID:  clientID,
-		Die1:      gameResult.Die1,
-		Die2:      gameResult.Die2,
-		Outcome:   gameResult.Outcome,
-		BetAmount: payload.BetAmount,
-		Winnings:  gameResult.Winnings,
-	}
-	if err := h.sendMessage(conn, constants.MsgTypePlayResult, resultPayload); err != nil {
+		ClientID:   clientID,
+		Die1:       gameResult.Die1,
+		Die2:       gameResult.Die2,
+		Outcome:    gameResult.Outcome,
+		BetAmount:  payload.BetAmount,
+		Winnings:   gameResult.Winnings,
+		Currency:   currency,
+		JackpotWin: settled.settled.JackpotWon,
+	}
+	if payload.FreeRoundGrantID != 0 {
+		remaining := settled.settled.FreeRoundsRemaining
+		resultPayload.FreeRoundGrantID = payload.FreeRoundGrantID
+		resultPayload.FreeRoundsRemaining = &remaining
+	}
+	if err := h.sendMessage(c, constants.MsgTypePlayResult, resultPayload); err != nil {
 		log.Printf("[Play-%s] Error sending play result: %v", clientID, err)
 	}
+	h.updateLeaderboards(clientID, settled.settled.Round)
+	h.publishFeedRound(clientID, payload, gameResult)
 
-	if finalBalance >= 0 {
-		balancePayload := BalanceUpdatePayload{ClientID: clientID, Balance: finalBalance}
-		if err := h.sendMessage(conn, constants.MsgTypeBalanceUpdate, balancePayload); err != nil {
-			log.Printf("[Play-%s] Error sending final balance update: %v", clientID, err)
-		}
-	} else {
-		log.Printf("[Play-%s] Could not determine final balance reliably after play.", clientID)
+	balancePayload := BalanceUpdatePayload{
+		ClientID: clientID,
+		Balance:  finalBalance,
+		Currency: currency,
+		Bonus:    newBonusPayload(settled.settled.Bonus),
+	}
+	if err := h.sendMessage(c, constants.MsgTypeBalanceUpdate, balancePayload); err != nil {
+		log.Printf("[Play-%s] Error sending final balance update: %v", clientID, err)
 	}
+	return playOutcome{Result: gameResult, FinalBalance: finalBalance}, true
 }
 
-func (h *Handler) handleGetBalance(conn *websocket.Conn, payloadJSON json.RawMessage, clientID string) {
+func (h *Handler) handleGetBalance(c *client, payloadBytes []byte, clientID string) {
 	var payload GetBalancePayload
-	if err := json.Unmarshal(payloadJSON, &payload); err != nil {
+	if err := c.codec.DecodePayload(payloadBytes, &payload); err != nil {
 		log.Printf("[GetBalance-%s] Error unmarshalling payload: %v", clientID, err)
-		h.sendError(conn, constants.ErrCodeBadRequest, "Invalid get_balance payload format")
+		h.sendError(c, constants.ErrCodeBadRequest, "Invalid get_balance payload format")
 		return
 	}
 	if clientID == "" || payload.ClientID != clientID {
 		log.Printf("[GetBalance-%s] Mismatched or missing ClientID in payload (%s)", clientID, payload.ClientID)
-		h.sendError(conn, constants.ErrCodeBadRequest, "Client ID mismatch or missing")
+		h.sendError(c, constants.ErrCodeBadRequest, "Client ID mismatch or missing")
 		return
 	}
 
-	log.Printf("[GetBalance-%s] Processing...", clientID)
+	currency := payload.Currency
+	if currency == "" {
+		currency = h.app().DefaultCurrency
+	}
+	if _, ok := h.app().Currencies[currency]; !ok {
+		log.Printf("[GetBalance-%s] Unsupported currency %s", clientID, currency)
+		h.sendError(c, constants.ErrCodeInvalidCurrency, "Unsupported currency.")
+		return
+	}
 
-	opCtx, cancel := context.WithTimeout(context.Background(), time.Duration(constants.ShortOpTimeout)*time.Second)
+	log.Printf("[GetBalance-%s] Processing %s...", clientID, currency)
+
+	opCtx, cancel := context.WithTimeout(context.Background(), h.app().Timeouts.ShortOp)
 	defer cancel()
 
-	if err := h.walletSvc.EnsureWalletExists(opCtx, clientID); err != nil {
+	if err := h.walletSvc.EnsureWalletExists(opCtx, clientID, currency); err != nil {
 		log.Printf("[GetBalance-%s] Error ensuring wallet exists: %v", clientID, err)
-		h.sendError(conn, constants.ErrCodeInternalError, "Could not prepare wallet.")
+		h.sendError(c, constants.ErrCodeInternalError, "Could not prepare wallet.")
 		return
 	}
 
-	balance, err := h.walletSvc.GetBalance(opCtx, clientID)
+	// Outside a play a slightly stale balance is fine and can come from the read
+	// replica; while rounds settle it must reflect the latest debit or credit.
+	readCtx := opCtx
+	if !c.playing.Load() && !c.autoplayActive() {
+		readCtx = database.AllowStale(opCtx)
+	}
+	funds, err := h.walletSvc.GetFunds(readCtx, clientID, currency)
 	if err != nil {
 		log.Printf("[GetBalance-%s] Internal error getting balance: %v", clientID, err)
-		h.sendError(conn, constants.ErrCodeInternalError, "Failed to retrieve balance.")
+		h.sendError(c, constants.ErrCodeInternalError, "Failed to retrieve balance.")
 		return
 	}
 
-	balancePayload := BalanceUpdatePayload{ClientID: clientID, Balance: balance}
-	if err := h.sendMessage(conn, constants.MsgTypeBalanceUpdate, balancePayload); err != nil {
+	balancePayload := BalanceUpdatePayload{
+		ClientID: clientID,
+		Balance:  funds.Balance,
+		Currency: currency,
+		Bonus:    newBonusPayload(funds.Bonus),
+	}
+	if err := h.sendMessage(c, constants.MsgTypeBalanceUpdate, balancePayload); err != nil {
 		log.Printf("[GetBalance-%s] Error sending balance update: %v", clientID, err)
 	}
 }
 
-func (h *Handler) handleEndPlay(conn *websocket.Conn, payloadJSON json.RawMessage, clientID string) {
+func (h *Handler) handleEndPlay(c *client, payloadBytes []byte, clientID string) {
 	var payload EndPlayPayload
-	if err := json.Unmarshal(payloadJSON, &payload); err != nil {
+	if err := c.codec.DecodePayload(payloadBytes, &payload); err != nil {
 		log.Printf("[EndPlay-%s] Error unmarshalling payload: %v", clientID, err)
-		h.sendError(conn, constants.ErrCodeBadRequest, "Invalid end_play payload format")
+		h.sendError(c, constants.ErrCodeBadRequest, "Invalid end_play payload format")
 	} else if clientID == "" || payload.ClientID != clientID {
 		log.Printf("[EndPlay-%s] Mismatched or missing ClientID in payload (%s)", clientID, payload.ClientID)
-		h.sendError(conn, constants.ErrCodeBadRequest, "Client ID mismatch or missing")
+		h.sendError(c, constants.ErrCodeBadRequest, "Client ID mismatch or missing")
 	}
 
 	log.Printf("[EndPlay-%s] Processing leave request...", clientID)
 
-	opCtx, cancel := context.WithTimeout(context.Background(), time.Duration(constants.ShortOpTimeout)*time.Second)
+	opCtx, cancel := context.WithTimeout(context.Background(), h.app().Timeouts.ShortOp)
 	defer cancel()
 
-	finalBalance, err := h.walletSvc.GetBalance(opCtx, clientID)
+	var finalBalance int64 = -1
+	balances, err := h.walletSvc.GetBalances(opCtx, clientID)
 	if err != nil {
-		log.Printf("[EndPlay-%s] Error getting final balance: %v", clientID, err)
-		h.sendError(conn, constants.ErrCodeInternalError, "Failed to retrieve final balance.")
-		finalBalance = -1
+		log.Printf("[EndPlay-%s] Error getting final balances: %v", clientID, err)
+		h.sendError(c, constants.ErrCodeInternalError, "Failed to retrieve final balance.")
+		balances = nil
+	} else if balance, ok := balances[h.app().DefaultCurrency]; ok {
+		finalBalance = balance
+	} else {
+		log.Printf("[EndPlay-%s] No %s wallet found", clientID, h.app().DefaultCurrency)
+		h.sendError(c, constants.ErrCodeWalletNotFound, "Failed to retrieve final balance.")
 	}
 
 	endedPayload := PlayEndedPayload{
 		ClientID:     clientID,
 		FinalBalance: finalBalance,
+		Currency:     h.app().DefaultCurrency,
+		Balances:     balances,
 	}
-	if err := h.sendMessage(conn, constants.MsgTypePlayEnded, endedPayload); err != nil {
+	if err := h.sendMessage(c, constants.MsgTypePlayEnded, endedPayload); err != nil {
 		log.Printf("[EndPlay-%s] Error sending play_ended response: %v", clientID, err)
 	} else if finalBalance != -1 {
 		log.Printf("[EndPlay-%s] Sent confirmation with final balance %d", clientID, finalBalance)
@@ -359,62 +593,23 @@ func (h *Handler) handleEndPlay(conn *websocket.Conn, payloadJSON json.RawMessag
 }
 
 // Helpers
-// acquireRedisLock tries to set a key with NX-Not Exists and an expiry.
-func (h *Handler) acquireRedisLock(ctx context.Context, key string) (bool, error) {
-	wasSet, err := h.redisClient.SetNX(ctx, key, "locked", time.Duration(constants.RedisLockTimeout)*time.Second).Result()
-	if err != nil {
-		if errors.Is(err, context.DeadlineExceeded) {
-			log.Printf("Redis lock acquisition timed out for key %s", key)
-		}
-		return false, fmt.Errorf("redis SetNX error for key %s: %w", key, err)
-	}
-	if wasSet {
-		log.Printf("DEBUG: Acquired active_play lock: %s", key)
-	}
-	return wasSet, nil
-}
-
-// releaseRedisLock explicitly deletes the Redis lock key.
-// Returns true on success/key-not-found, false on error.
-func (h *Handler) releaseRedisLock(key string) bool {
-	delCtx, delCancel := context.WithTimeout(context.Background(), time.Duration(constants.RedisDelTimeout)*time.Second)
-	defer delCancel()
-
-	deletedCount, delErr := h.redisClient.Del(delCtx, key).Result()
-	if delErr != nil {
-		if errors.Is(delErr, context.DeadlineExceeded) {
-			log.Printf("Redis lock deletion timed out for key %s", key)
-		} else {
-			log.Printf("REDIS ERROR deleting lock key %s: %v", key, delErr)
-		}
-		return false
-	}
-
-	if deletedCount > 0 {
-		log.Printf("DEBUG: Released active_play lock: %s", key)
-	} else {
-		log.Printf("DEBUG: Attempted to release lock %s, but key did not exist (DEL returned 0 or lock expired).", key)
-	}
-	return true
-}
-
 // sendError sends a structured error message to the client.
-func (h *Handler) sendError(conn *websocket.Conn, code string, message string) {
-	log.Printf("Sending error to %s: Code=%s, Msg=%s", conn.RemoteAddr(), code, message)
+func (h *Handler) sendError(c *client, code string, message string) {
+	log.Printf("Sending error to %s: Code=%s, Msg=%s", c.conn.RemoteAddr(), code, message)
 	errPayload := ErrorPayload{Code: code, Message: message}
-	if err := h.sendMessage(conn, constants.MsgTypeError, errPayload); err != nil {
-		log.Printf("Failed to send error JSON to client %s: %v", conn.RemoteAddr(), err)
+	if err := h.sendMessage(c, constants.MsgTypeError, errPayload); err != nil {
+		log.Printf("Failed to send error to client %s: %v", c.conn.RemoteAddr(), err)
 	}
 }
 
-// sendMessage marshals and sends a structured message to the client.
-func (h *Handler) sendMessage(conn *websocket.Conn, msgType string, payload interface{}) error {
+// sendMessage encodes and sends a structured message to the client.
+func (h *Handler) sendMessage(c *client, msgType string, payload interface{}) error {
 	msg := ServerMessage{Type: msgType, Payload: payload}
-	err := conn.WriteJSON(msg)
+	err := c.write(msg)
 	if err != nil {
-		return fmt.Errorf("failed to write JSON message (type: %s): %w", msgType, err)
+		return fmt.Errorf("failed to write %s message (type: %s): %w", c.codec.Name(), msgType, err)
 	}
-	log.Printf("DEBUG: Sent message type: %s to %s", msgType, conn.RemoteAddr())
+	log.Printf("DEBUG: Sent message type: %s to %s", msgType, c.conn.RemoteAddr())
 	return nil
 }
 
@@ -433,12 +628,20 @@ func (h *Handler) handleReadError(conn *websocket.Conn, err error) {
 }
 
 // validatePlayPayload performs validation specific to the PlayPayload.
+// The currency must already be defaulted.
 func (h *Handler) validatePlayPayload(payload PlayPayload) error {
+	limits, ok := h.app().Currencies[payload.Currency]
+	if !ok {
+		return fmt.Errorf("%w: '%s'", ErrValidationCurrency, payload.Currency)
+	}
 	if payload.BetAmount <= 0 {
 		return fmt.Errorf("%w: amount must be positive (%d)", ErrValidationBetAmount, payload.BetAmount)
 	}
-	if payload.BetAmount > h.appConfig.MaxBetAmount {
-		return fmt.Errorf("%w: amount %d exceeds max %d", ErrValidationBetTooHigh, payload.BetAmount, h.appConfig.MaxBetAmount)
+	if payload.BetAmount < limits.MinBetAmount {
+		return fmt.Errorf("%w: amount %d below min %d", ErrValidationBetTooLow, payload.BetAmount, limits.MinBetAmount)
+	}
+	if payload.BetAmount > limits.MaxBetAmount {
+		return fmt.Errorf("%w: amount %d exceeds max %d", ErrValidationBetTooHigh, payload.BetAmount, limits.MaxBetAmount)
 	}
 	if payload.BetType != constants.BetTypeLt7 && payload.BetType != constants.BetTypeGt7 {
 		return fmt.Errorf("%w: invalid type '%s'", ErrValidationBetType, payload.BetType)
@@ -449,8 +652,10 @@ func (h *Handler) validatePlayPayload(payload PlayPayload) error {
 // Define specific validation error types
 var (
 	ErrValidationBetAmount  = errors.New("invalid bet amount")
+	ErrValidationBetTooLow  = errors.New("bet amount too low")
 	ErrValidationBetTooHigh = errors.New("bet amount too high")
 	ErrValidationBetType    = errors.New("invalid bet type")
+	ErrValidationCurrency   = errors.New("unsupported currency")
 )
 
 // validationErrorToCode maps specific validation errors to client-facing error codes/messages.
@@ -458,6 +663,10 @@ func validationErrorToCode(err error) (code string, message string) {
 	switch {
 	case errors.Is(err, ErrValidationBetAmount):
 		return constants.ErrCodeInvalidBet, "Bet amount must be greater than zero."
+	case errors.Is(err, ErrValidationCurrency):
+		return constants.ErrCodeInvalidCurrency, "Unsupported currency."
+	case errors.Is(err, ErrValidationBetTooLow):
+		return constants.ErrCodeBetTooLow, "Bet amount is below the minimum limit."
 	case errors.Is(err, ErrValidationBetTooHigh):
 		return constants.ErrCodeBetTooHigh, "Bet amount exceeds maximum limit."
 	case errors.Is(err, ErrValidationBetType):
@@ -468,21 +677,71 @@ func validationErrorToCode(err error) (code string, message string) {
 }
 
 // extractClientID attempts to get the ClientID from known payload types.
-func extractClientID(msg WsMessage) (string, error) {
-	switch msg.Type {
+func extractClientID(codec Codec, msgType string, payloadBytes []byte) (string, error) {
+	switch msgType {
+	case constants.MsgTypeHello:
+		var p HelloPayload
+		if err := codec.DecodePayload(payloadBytes, &p); err == nil {
+			return p.ClientID, nil
+		}
 	case constants.MsgTypePlay:
 		var p PlayPayload
-		if err := json.Unmarshal(msg.Payload, &p); err == nil {
+		if err := codec.DecodePayload(payloadBytes, &p); err == nil {
 			return p.ClientID, nil
 		}
 	case constants.MsgTypeGetBalance:
 		var p GetBalancePayload
-		if err := json.Unmarshal(msg.Payload, &p); err == nil {
+		if err := codec.DecodePayload(payloadBytes, &p); err == nil {
+			return p.ClientID, nil
+		}
+	case constants.MsgTypeAutoplayStart:
+		var p AutoplayStartPayload
+		if err := codec.DecodePayload(payloadBytes, &p); err == nil {
+			return p.ClientID, nil
+		}
+	case constants.MsgTypeAutoplayStop:
+		var p AutoplayStopPayload
+		if err := codec.DecodePayload(payloadBytes, &p); err == nil {
+			return p.ClientID, nil
+		}
+	case constants.MsgTypeGetLeaderboard:
+		var p GetLeaderboardPayload
+		if err := codec.DecodePayload(payloadBytes, &p); err == nil {
+			return p.ClientID, nil
+		}
+	case constants.MsgTypeGetLimits:
+		var p GetLimitsPayload
+		if err := codec.DecodePayload(payloadBytes, &p); err == nil {
+			return p.ClientID, nil
+		}
+	case constants.MsgTypeSetLimit:
+		var p SetLimitPayload
+		if err := codec.DecodePayload(payloadBytes, &p); err == nil {
+			return p.ClientID, nil
+		}
+	case constants.MsgTypeSelfExclude:
+		var p SelfExcludePayload
+		if err := codec.DecodePayload(payloadBytes, &p); err == nil {
+			return p.ClientID, nil
+		}
+	case constants.MsgTypeSubscribeFeed:
+		var p SubscribeFeedPayload
+		if err := codec.DecodePayload(payloadBytes, &p); err == nil {
+			return p.ClientID, nil
+		}
+	case constants.MsgTypeUnsubscribeFeed:
+		var p UnsubscribeFeedPayload
+		if err := codec.DecodePayload(payloadBytes, &p); err == nil {
+			return p.ClientID, nil
+		}
+	case constants.MsgTypeGetFreeRounds:
+		var p GetFreeRoundsPayload
+		if err := codec.DecodePayload(payloadBytes, &p); err == nil {
 			return p.ClientID, nil
 		}
 	case constants.MsgTypeEndPlay:
 		var p EndPlayPayload
-		if err := json.Unmarshal(msg.Payload, &p); err == nil {
+		if err := codec.DecodePayload(payloadBytes, &p); err == nil {
 			return p.ClientID, nil
 		}
 	}
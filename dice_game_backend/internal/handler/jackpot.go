@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"log"
+
+	"github.com/BrunoSena97/dice_game_backend/internal/constants"
+	"github.com/BrunoSena97/dice_game_backend/internal/jackpot"
+)
+
+type JackpotPoolPayload struct {
+	Currency string `json:"currency"`
+	Amount   int64  `json:"amount"`
+}
+
+// JackpotUpdatePayload carries the value of every jackpot pool. It is pushed
+// once a connection identifies itself and again whenever a pool changes.
+type JackpotUpdatePayload struct {
+	Pools []JackpotPoolPayload `json:"pools"`
+}
+
+// subscribeJackpot starts pushing jackpot pools to the connection, once.
+func (h *Handler) subscribeJackpot(c *client) {
+	if h.jackpot == nil || c.unsubscribeJackpot != nil {
+		return
+	}
+	c.unsubscribeJackpot = h.jackpot.Subscribe(func(pools []jackpot.Pool) {
+		payload := JackpotUpdatePayload{Pools: make([]JackpotPoolPayload, 0, len(pools))}
+		for _, p := range pools {
+			payload.Pools = append(payload.Pools, JackpotPoolPayload(p))
+		}
+		if err := h.sendMessage(c, constants.MsgTypeJackpotUpdate, payload); err != nil {
+			log.Printf("[Jackpot] Error pushing jackpot update to %s: %v", c.conn.RemoteAddr(), err)
+		}
+	})
+}
+
+// unsubscribeJackpot stops the connection's jackpot pushes, if any.
+func (h *Handler) unsubscribeJackpot(c *client) {
+	if c.unsubscribeJackpot == nil {
+		return
+	}
+	c.unsubscribeJackpot()
+	c.unsubscribeJackpot = nil
+}
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/BrunoSena97/dice_game_backend/internal/constants"
+	"github.com/BrunoSena97/dice_game_backend/internal/leaderboard"
+	"github.com/BrunoSena97/dice_game_backend/internal/rounds"
+)
+
+type GetLeaderboardPayload struct {
+	ClientID string `json:"clientId"`
+	Window   string `json:"window,omitempty"`
+	Metric   string `json:"metric,omitempty"`
+	Currency string `json:"currency,omitempty"`
+	Limit    int    `json:"limit,omitempty"`
+}
+
+type LeaderboardEntryPayload struct {
+	Rank   int64  `json:"rank"`
+	Player string `json:"player"`
+	Score  int64  `json:"score"`
+}
+
+type LeaderboardPayload struct {
+	ClientID string                    `json:"clientId"`
+	Window   string                    `json:"window"`
+	Metric   string                    `json:"metric"`
+	Currency string                    `json:"currency"`
+	Period   string                    `json:"period"`
+	Entries  []LeaderboardEntryPayload `json:"entries"`
+	Self     *LeaderboardEntryPayload  `json:"self"`
+}
+
+func (h *Handler) handleGetLeaderboard(c *client, payloadBytes []byte, clientID string) {
+	var payload GetLeaderboardPayload
+	if err := c.codec.DecodePayload(payloadBytes, &payload); err != nil {
+		log.Printf("[Leaderboard-%s] Error unmarshalling payload: %v", clientID, err)
+		h.sendError(c, constants.ErrCodeBadRequest, "Invalid get_leaderboard payload format")
+		return
+	}
+	if clientID == "" || payload.ClientID != clientID {
+		log.Printf("[Leaderboard-%s] Mismatched or missing ClientID in payload (%s)", clientID, payload.ClientID)
+		h.sendError(c, constants.ErrCodeBadRequest, "Client ID mismatch or missing")
+		return
+	}
+	if payload.Currency == "" {
+		payload.Currency = h.app().DefaultCurrency
+	}
+	if _, ok := h.app().Currencies[payload.Currency]; !ok {
+		h.sendError(c, constants.ErrCodeInvalidCurrency, "Unsupported currency.")
+		return
+	}
+
+	opCtx, cancel := context.WithTimeout(context.Background(), h.app().Timeouts.ShortOp)
+	defer cancel()
+
+	res, err := h.leaderboard.Top(opCtx, leaderboard.Query{
+		Currency: payload.Currency,
+		Window:   payload.Window,
+		Metric:   payload.Metric,
+		Limit:    payload.Limit,
+		UserID:   clientID,
+	})
+	if err != nil {
+		if errors.Is(err, leaderboard.ErrInvalidWindow) || errors.Is(err, leaderboard.ErrInvalidMetric) || errors.Is(err, leaderboard.ErrInvalidLimit) {
+			h.sendError(c, constants.ErrCodeBadRequest, "Invalid leaderboard query: "+err.Error())
+			return
+		}
+		log.Printf("[Leaderboard-%s] Error loading leaderboard: %v", clientID, err)
+		h.sendError(c, constants.ErrCodeInternalError, "Failed to load leaderboard.")
+		return
+	}
+
+	resp := LeaderboardPayload{
+		ClientID: clientID,
+		Window:   res.Query.Window,
+		Metric:   res.Query.Metric,
+		Currency: res.Query.Currency,
+		Period:   res.Period,
+		Entries:  make([]LeaderboardEntryPayload, 0, len(res.Entries)),
+	}
+	for _, e := range res.Entries {
+		resp.Entries = append(resp.Entries, LeaderboardEntryPayload(e))
+	}
+	if res.Self != nil {
+		self := LeaderboardEntryPayload(*res.Self)
+		resp.Self = &self
+	}
+	if err := h.sendMessage(c, constants.MsgTypeLeaderboard, resp); err != nil {
+		log.Printf("[Leaderboard-%s] Error sending leaderboard: %v", clientID, err)
+	}
+}
+
+// updateLeaderboards adds a recorded round to the leaderboards in the background.
+func (h *Handler) updateLeaderboards(clientID string, round rounds.Round) {
+	go func() {
+		lbCtx, lbCancel := context.WithTimeout(context.Background(), h.app().Timeouts.ShortOp)
+		defer lbCancel()
+		if err := h.leaderboard.Record(lbCtx, round); err != nil {
+			log.Printf("[Play-%s] Error updating leaderboards: %v", clientID, err)
+		}
+	}()
+}
@@ -0,0 +1,200 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/BrunoSena97/dice_game_backend/internal/constants"
+	"github.com/BrunoSena97/dice_game_backend/internal/limits"
+)
+
+type GetLimitsPayload struct {
+	ClientID string `json:"clientId"`
+}
+
+// SetLimitPayload changes one limit. A value of 0 removes it. Currency is
+// ignored for the session reminder and defaults to the default currency otherwise.
+type SetLimitPayload struct {
+	ClientID string `json:"clientId"`
+	Kind     string `json:"kind"`
+	Currency string `json:"currency,omitempty"`
+	Value    int64  `json:"value"`
+}
+
+type SelfExcludePayload struct {
+	ClientID string `json:"clientId"`
+	Days     int    `json:"days"`
+}
+
+type LimitPayload struct {
+	Kind               string     `json:"kind"`
+	Currency           string     `json:"currency,omitempty"`
+	Value              int64      `json:"value"`
+	PendingValue       *int64     `json:"pendingValue"`
+	PendingEffectiveAt *time.Time `json:"pendingEffectiveAt"`
+}
+
+type LimitsPayload struct {
+	ClientID          string         `json:"clientId"`
+	Limits            []LimitPayload `json:"limits"`
+	SelfExcludedUntil *time.Time     `json:"selfExcludedUntil"`
+}
+
+type SessionReminderPayload struct {
+	ClientID       string `json:"clientId"`
+	SessionMinutes int    `json:"sessionMinutes"`
+}
+
+func (h *Handler) handleGetLimits(c *client, payloadBytes []byte, clientID string) {
+	var payload GetLimitsPayload
+	if err := c.codec.DecodePayload(payloadBytes, &payload); err != nil {
+		log.Printf("[Limits-%s] Error unmarshalling payload: %v", clientID, err)
+		h.sendError(c, constants.ErrCodeBadRequest, "Invalid get_limits payload format")
+		return
+	}
+	if clientID == "" || payload.ClientID != clientID {
+		log.Printf("[Limits-%s] Mismatched or missing ClientID in payload (%s)", clientID, payload.ClientID)
+		h.sendError(c, constants.ErrCodeBadRequest, "Client ID mismatch or missing")
+		return
+	}
+	h.sendLimits(c, clientID)
+}
+
+func (h *Handler) handleSetLimit(c *client, payloadBytes []byte, clientID string) {
+	var payload SetLimitPayload
+	if err := c.codec.DecodePayload(payloadBytes, &payload); err != nil {
+		log.Printf("[Limits-%s] Error unmarshalling payload: %v", clientID, err)
+		h.sendError(c, constants.ErrCodeBadRequest, "Invalid set_limit payload format")
+		return
+	}
+	if clientID == "" || payload.ClientID != clientID {
+		log.Printf("[Limits-%s] Mismatched or missing ClientID in payload (%s)", clientID, payload.ClientID)
+		h.sendError(c, constants.ErrCodeBadRequest, "Client ID mismatch or missing")
+		return
+	}
+	if payload.Kind != limits.KindSessionReminder {
+		if payload.Currency == "" {
+			payload.Currency = h.app().DefaultCurrency
+		}
+		if _, ok := h.app().Currencies[payload.Currency]; !ok {
+			h.sendError(c, constants.ErrCodeInvalidCurrency, "Unsupported currency.")
+			return
+		}
+	}
+
+	opCtx, cancel := context.WithTimeout(context.Background(), h.app().Timeouts.ShortOp)
+	defer cancel()
+
+	if err := h.limitsSvc.SetLimit(opCtx, clientID, payload.Kind, payload.Currency, payload.Value); err != nil {
+		if errors.Is(err, limits.ErrInvalidKind) || errors.Is(err, limits.ErrInvalidValue) || errors.Is(err, limits.ErrCurrencyRequired) {
+			h.sendError(c, constants.ErrCodeInvalidLimit, "Invalid limit: "+err.Error())
+			return
+		}
+		log.Printf("[Limits-%s] Error setting limit: %v", clientID, err)
+		h.sendError(c, constants.ErrCodeInternalError, "Failed to update limit.")
+		return
+	}
+	h.sendLimits(c, clientID)
+}
+
+func (h *Handler) handleSelfExclude(c *client, payloadBytes []byte, clientID string) {
+	var payload SelfExcludePayload
+	if err := c.codec.DecodePayload(payloadBytes, &payload); err != nil {
+		log.Printf("[Limits-%s] Error unmarshalling payload: %v", clientID, err)
+		h.sendError(c, constants.ErrCodeBadRequest, "Invalid self_exclude payload format")
+		return
+	}
+	if clientID == "" || payload.ClientID != clientID {
+		log.Printf("[Limits-%s] Mismatched or missing ClientID in payload (%s)", clientID, payload.ClientID)
+		h.sendError(c, constants.ErrCodeBadRequest, "Client ID mismatch or missing")
+		return
+	}
+
+	opCtx, cancel := context.WithTimeout(context.Background(), h.app().Timeouts.ShortOp)
+	defer cancel()
+
+	if _, err := h.limitsSvc.SelfExclude(opCtx, clientID, payload.Days); err != nil {
+		if errors.Is(err, limits.ErrInvalidDuration) {
+			h.sendError(c, constants.ErrCodeInvalidLimit, fmt.Sprintf("Self-exclusion must be between 1 and %d days.", limits.MaxSelfExclusionDays))
+			return
+		}
+		log.Printf("[Limits-%s] Error self-excluding: %v", clientID, err)
+		h.sendError(c, constants.ErrCodeInternalError, "Failed to self-exclude.")
+		return
+	}
+	h.sendLimits(c, clientID)
+}
+
+func (h *Handler) sendLimits(c *client, clientID string) {
+	opCtx, cancel := context.WithTimeout(context.Background(), h.app().Timeouts.ShortOp)
+	defer cancel()
+
+	settings, err := h.limitsSvc.Get(opCtx, clientID)
+	if err != nil {
+		log.Printf("[Limits-%s] Error loading limits: %v", clientID, err)
+		h.sendError(c, constants.ErrCodeInternalError, "Failed to load limits.")
+		return
+	}
+
+	resp := LimitsPayload{
+		ClientID:          clientID,
+		Limits:            make([]LimitPayload, 0, len(settings.Limits)),
+		SelfExcludedUntil: settings.ExcludedUntil,
+	}
+	for _, l := range settings.Limits {
+		lp := LimitPayload{Kind: l.Kind, Currency: l.Currency, Value: l.Value}
+		if l.Pending != nil {
+			lp.PendingValue = &l.Pending.Value
+			lp.PendingEffectiveAt = &l.Pending.EffectiveAt
+		}
+		resp.Limits = append(resp.Limits, lp)
+	}
+	if err := h.sendMessage(c, constants.MsgTypeLimits, resp); err != nil {
+		log.Printf("[Limits-%s] Error sending limits: %v", clientID, err)
+	}
+}
+
+// remindSession sends a session_reminder each time the connection crosses another
+// multiple of the player's reminder interval. Legacy connections cannot show it.
+func (h *Handler) remindSession(c *client, clientID string, interval time.Duration) {
+	if interval <= 0 || c.legacy() {
+		return
+	}
+	elapsed := time.Since(c.sessionStart)
+	due := int64(elapsed / interval)
+	for {
+		sent := c.remindersSent.Load()
+		if due <= sent {
+			return
+		}
+		if c.remindersSent.CompareAndSwap(sent, due) {
+			break
+		}
+	}
+	payload := SessionReminderPayload{ClientID: clientID, SessionMinutes: int(elapsed / time.Minute)}
+	if err := h.sendMessage(c, constants.MsgTypeSessionReminder, payload); err != nil {
+		log.Printf("[Limits-%s] Error sending session reminder: %v", clientID, err)
+	}
+}
+
+// sendBreach tells the player which limit refused their play.
+func (h *Handler) sendBreach(c *client, clientID string, breach *limits.BreachError) {
+	log.Printf("[Play-%s] Refused by responsible gaming limits: %v", clientID, breach)
+	errCode, errMsg := limitErrorToCode(breach)
+	h.sendError(c, errCode, errMsg)
+}
+
+// limitErrorToCode maps a refused play to its error code and user-facing message.
+func limitErrorToCode(breach *limits.BreachError) (string, string) {
+	switch {
+	case errors.Is(breach, limits.ErrSelfExcluded):
+		return constants.ErrCodeSelfExcluded, fmt.Sprintf("You are self-excluded until %s.", breach.Until.UTC().Format(time.RFC3339))
+	case errors.Is(breach, limits.ErrStakeLimitExceeded):
+		return constants.ErrCodeStakeLimitExceeded, fmt.Sprintf("Bet exceeds your max stake of %d.", breach.Limit)
+	default:
+		return constants.ErrCodeLossLimitReached, fmt.Sprintf("This bet would exceed your %s limit of %d.", breach.Kind, breach.Limit)
+	}
+}
@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/BrunoSena97/dice_game_backend/internal/constants"
+	"github.com/BrunoSena97/dice_game_backend/internal/schemagen"
+)
+
+// supportedProtocolVersions lists every protocol version this server speaks, newest first.
+var supportedProtocolVersions = []int{constants.ProtocolVersion1}
+
+// legacyMessageTypes are the client messages of the protocol from before the
+// hello handshake. A connection that never sent a hello may only send these,
+// and gets none of the pushes added since.
+var legacyMessageTypes = []string{constants.MsgTypeHello, constants.MsgTypePlay, constants.MsgTypeGetBalance, constants.MsgTypeEndPlay}
+
+// clientMessageTypes lists every message type the server accepts.
+var clientMessageTypes = func() []string {
+	var types []string
+	for _, m := range ProtocolSpec().Messages {
+		if m.Direction == schemagen.DirectionClient {
+			types = append(types, m.Type)
+		}
+	}
+	return types
+}()
+
+// requiresHello reports whether msgType may only be sent after a hello.
+// Unknown types are left to the unknown type error.
+func requiresHello(msgType string) bool {
+	return slices.Contains(clientMessageTypes, msgType) && !slices.Contains(legacyMessageTypes, msgType)
+}
+
+// supportedBetTypes lists the bet types accepted by the play message.
+var supportedBetTypes = []string{constants.BetTypeLt7, constants.BetTypeGt7}
+
+// isSupportedProtocolVersion reports whether the server can speak the given version.
+func isSupportedProtocolVersion(version int) bool {
+	for _, v := range supportedProtocolVersions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+// enabledFeatures returns the optional features this server has switched on.
+func (h *Handler) enabledFeatures() []string {
+	features := []string{constants.FeatureMsgpackEncoding, constants.FeatureBalancePush, constants.FeatureLiveFeed, constants.FeatureLeaderboards, constants.FeatureLimits, constants.FeatureAutoplay, constants.FeatureBonus, constants.FeatureFreeRounds}
+	if h.jackpot != nil {
+		features = append(features, constants.FeatureJackpot)
+	}
+	return features
+}
+
+// currencyInfo describes every enabled currency, default first.
+func (h *Handler) currencyInfo() []CurrencyInfo {
+	app := h.app()
+	codes := app.CurrencyCodes()
+	info := make([]CurrencyInfo, 0, len(codes))
+	for _, code := range codes {
+		cur := app.Currencies[code]
+		info = append(info, CurrencyInfo{Code: code, MinBetAmount: cur.MinBetAmount, MaxBetAmount: cur.MaxBetAmount})
+	}
+	return info
+}
+
+// handleHello negotiates the protocol version for the connection.
+// It returns the agreed version and false when the client must be disconnected.
+func (h *Handler) handleHello(c *client, payloadBytes []byte, clientID string) (int, bool) {
+	var payload HelloPayload
+	if err := c.codec.DecodePayload(payloadBytes, &payload); err != nil {
+		log.Printf("[Hello-%s] Error unmarshalling payload: %v", clientID, err)
+		h.sendError(c, constants.ErrCodeBadRequest, "Invalid hello payload format")
+		return 0, false
+	}
+
+	if !isSupportedProtocolVersion(payload.ProtocolVersion) {
+		log.Printf("[Hello-%s] Unsupported protocol version %d", clientID, payload.ProtocolVersion)
+		h.sendError(c, constants.ErrCodeUnsupportedVersion,
+			fmt.Sprintf("Protocol version %d is not supported. Supported versions: %s.",
+				payload.ProtocolVersion, formatVersions(supportedProtocolVersions)))
+		return 0, false
+	}
+
+	app := h.app()
+	ackPayload := HelloAckPayload{
+		ClientID:          clientID,
+		ProtocolVersion:   payload.ProtocolVersion,
+		SupportedVersions: supportedProtocolVersions,
+		BetTypes:          supportedBetTypes,
+		MaxBetAmount:      app.Currencies[app.DefaultCurrency].MaxBetAmount,
+		Currency:          app.DefaultCurrency,
+		Currencies:        h.currencyInfo(),
+		Features:          h.enabledFeatures(),
+	}
+	if err := h.sendMessage(c, constants.MsgTypeHelloAck, ackPayload); err != nil {
+		log.Printf("[Hello-%s] Error sending hello_ack: %v", clientID, err)
+	}
+
+	log.Printf("[Hello-%s] Negotiated protocol version %d", clientID, payload.ProtocolVersion)
+	return payload.ProtocolVersion, true
+}
+
+// formatVersions renders a version list for client-facing messages.
+func formatVersions(versions []int) string {
+	parts := make([]string, len(versions))
+	for i, v := range versions {
+		parts[i] = fmt.Sprintf("%d", v)
+	}
+	return strings.Join(parts, ", ")
+}
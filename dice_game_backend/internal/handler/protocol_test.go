@@ -0,0 +1,31 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/BrunoSena97/dice_game_backend/internal/constants"
+)
+
+func TestRequiresHello(t *testing.T) {
+	cases := []struct {
+		msgType string
+		want    bool
+	}{
+		{constants.MsgTypeHello, false},
+		{constants.MsgTypePlay, false},
+		{constants.MsgTypeGetBalance, false},
+		{constants.MsgTypeEndPlay, false},
+		{constants.MsgTypeAutoplayStart, true},
+		{constants.MsgTypeSubscribeFeed, true},
+		{constants.MsgTypeSetLimit, true},
+		{constants.MsgTypeGetFreeRounds, true},
+		// Server messages and unknown types get the unknown type error.
+		{constants.MsgTypePlayResult, false},
+		{"no_such_type", false},
+	}
+	for _, c := range cases {
+		if got := requiresHello(c.msgType); got != c.want {
+			t.Errorf("requiresHello(%q) = %t, want %t", c.msgType, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"log"
+
+	"github.com/BrunoSena97/dice_game_backend/internal/constants"
+	"github.com/BrunoSena97/dice_game_backend/internal/notify"
+)
+
+// subscribePushes starts the balance and jackpot pushes for a connection that
+// sent a hello. Legacy connections get neither.
+func (h *Handler) subscribePushes(c *client, clientID string) {
+	if c.legacy() {
+		return
+	}
+	h.subscribeBalances(c, clientID)
+	h.subscribeJackpot(c)
+}
+
+// subscribeBalances makes sure the connection receives balance pushes for clientID,
+// moving the subscription if the connection switched client IDs.
+func (h *Handler) subscribeBalances(c *client, clientID string) {
+	if clientID == "" || clientID == c.balanceUserID {
+		return
+	}
+	h.unsubscribeBalances(c)
+
+	c.balanceUserID = clientID
+	c.unsubscribeBalance = h.balanceHub.Subscribe(clientID, func(event notify.BalanceEvent) {
+		// A connection mid-play gets its authoritative balance from the play itself.
+		if c.playing.Load() {
+			return
+		}
+		payload := BalanceUpdatePayload{ClientID: event.UserID, Balance: event.Balance, Currency: event.Currency}
+		if err := h.sendMessage(c, constants.MsgTypeBalanceUpdate, payload); err != nil {
+			log.Printf("[Push-%s] Error pushing balance update: %v", event.UserID, err)
+		}
+	})
+	log.Printf("[Push-%s] Subscribed %s to balance updates", clientID, c.conn.RemoteAddr())
+}
+
+// unsubscribeBalances drops the connection's balance subscription, if any.
+func (h *Handler) unsubscribeBalances(c *client) {
+	if c.unsubscribeBalance == nil {
+		return
+	}
+	c.unsubscribeBalance()
+	log.Printf("[Push-%s] Unsubscribed %s from balance updates", c.balanceUserID, c.conn.RemoteAddr())
+	c.unsubscribeBalance = nil
+	c.balanceUserID = ""
+}
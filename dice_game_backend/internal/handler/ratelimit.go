@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"time"
+
+	"github.com/BrunoSena97/dice_game_backend/internal/config"
+)
+
+// tokenBucket rate-limits one connection. The limits are passed on every call
+// so that a config reload applies to connections that are already open.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// allow takes a token if one is available.
+func (b *tokenBucket) allow(limit config.RateLimitConfig, now time.Time) bool {
+	if limit.PerSecond <= 0 {
+		return true
+	}
+	burst := float64(limit.Burst)
+	if b.last.IsZero() {
+		b.tokens = burst
+	} else {
+		b.tokens += now.Sub(b.last).Seconds() * limit.PerSecond
+	}
+	if b.tokens > burst {
+		b.tokens = burst
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
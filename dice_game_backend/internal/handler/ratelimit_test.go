@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/BrunoSena97/dice_game_backend/internal/config"
+)
+
+func TestTokenBucketBurst(t *testing.T) {
+	limit := config.RateLimitConfig{PerSecond: 1, Burst: 3}
+	now := time.Unix(1000, 0)
+	var b tokenBucket
+
+	for i := range limit.Burst {
+		if !b.allow(limit, now) {
+			t.Fatalf("message %d of a full burst was refused", i+1)
+		}
+	}
+	if b.allow(limit, now) {
+		t.Fatal("message beyond the burst was allowed")
+	}
+}
+
+func TestTokenBucketRefill(t *testing.T) {
+	limit := config.RateLimitConfig{PerSecond: 4, Burst: 2}
+	now := time.Unix(1000, 0)
+	var b tokenBucket
+	b.allow(limit, now)
+	b.allow(limit, now)
+
+	cases := []struct {
+		after time.Duration
+		want  bool
+	}{
+		// 4/s refills one token every 250ms.
+		{100 * time.Millisecond, false},
+		{150 * time.Millisecond, true},
+		{0, false},
+		{250 * time.Millisecond, true},
+		// A long pause refills no more than the burst.
+		{time.Minute, true},
+		{0, true},
+		{0, false},
+	}
+	for i, c := range cases {
+		now = now.Add(c.after)
+		if got := b.allow(limit, now); got != c.want {
+			t.Fatalf("step %d, %v later: allow = %t, want %t", i, c.after, got, c.want)
+		}
+	}
+}
+
+func TestTokenBucketDisabledAndReloaded(t *testing.T) {
+	now := time.Unix(1000, 0)
+	var b tokenBucket
+	for range 100 {
+		if !b.allow(config.RateLimitConfig{}, now) {
+			t.Fatal("message refused with rate limiting disabled")
+		}
+	}
+
+	// A reload that lowers the burst caps tokens already banked.
+	b = tokenBucket{}
+	b.allow(config.RateLimitConfig{PerSecond: 1, Burst: 10}, now)
+	lowered := config.RateLimitConfig{PerSecond: 1, Burst: 1}
+	if !b.allow(lowered, now) {
+		t.Fatal("first message after lowering the burst was refused")
+	}
+	if b.allow(lowered, now) {
+		t.Fatal("message beyond the lowered burst was allowed")
+	}
+}
@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"github.com/BrunoSena97/dice_game_backend/internal/autoplay"
+	"github.com/BrunoSena97/dice_game_backend/internal/constants"
+	"github.com/BrunoSena97/dice_game_backend/internal/leaderboard"
+	"github.com/BrunoSena97/dice_game_backend/internal/limits"
+	"github.com/BrunoSena97/dice_game_backend/internal/schemagen"
+)
+
+//go:generate go run ../../cmd/protogen -schema ../../api/protocol.schema.json -ts ../../../dice_game_frontend/src/lib/types/protocol.ts
+
+// errorCodes lists every code the server may put in an ErrorPayload.
+var errorCodes = []string{
+	constants.ErrCodeBadRequest,
+	constants.ErrCodeInternalError,
+	constants.ErrCodeActivePlayExists,
+	constants.ErrCodeInvalidBet,
+	constants.ErrCodeBetTooLow,
+	constants.ErrCodeBetTooHigh,
+	constants.ErrCodeInvalidBetType,
+	constants.ErrCodeInsufficientFunds,
+	constants.ErrCodeWalletNotFound,
+	constants.ErrCodeUnknownType,
+	constants.ErrCodeFailedLockRelease,
+	constants.ErrCodeUnsupportedVersion,
+	constants.ErrCodeInvalidCurrency,
+	constants.ErrCodeFeedDropped,
+	constants.ErrCodeSelfExcluded,
+	constants.ErrCodeStakeLimitExceeded,
+	constants.ErrCodeLossLimitReached,
+	constants.ErrCodeInvalidLimit,
+	constants.ErrCodeAutoplayActive,
+	constants.ErrCodeInvalidAutoplay,
+	constants.ErrCodeRateLimited,
+	constants.ErrCodeFreeRoundUnavailable,
+	constants.ErrCodeShuttingDown,
+	constants.ErrCodeHelloRequired,
+}
+
+// ProtocolSpec describes every WebSocket message this handler sends or accepts.
+// It is the single source of truth for the generated schema and TypeScript types.
+func ProtocolSpec() schemagen.Spec {
+	return schemagen.Spec{
+		Title:           "Dice Game WebSocket Protocol",
+		ProtocolVersion: constants.CurrentProtocolVersion,
+		Messages: []schemagen.Message{
+			{Type: constants.MsgTypeHello, Direction: schemagen.DirectionClient, Payload: HelloPayload{}},
+			{Type: constants.MsgTypePlay, Direction: schemagen.DirectionClient, Payload: PlayPayload{}},
+			{Type: constants.MsgTypeGetBalance, Direction: schemagen.DirectionClient, Payload: GetBalancePayload{}},
+			{Type: constants.MsgTypeEndPlay, Direction: schemagen.DirectionClient, Payload: EndPlayPayload{}},
+			{Type: constants.MsgTypeAutoplayStart, Direction: schemagen.DirectionClient, Payload: AutoplayStartPayload{}},
+			{Type: constants.MsgTypeAutoplayStop, Direction: schemagen.DirectionClient, Payload: AutoplayStopPayload{}},
+			{Type: constants.MsgTypeGetLeaderboard, Direction: schemagen.DirectionClient, Payload: GetLeaderboardPayload{}},
+			{Type: constants.MsgTypeGetLimits, Direction: schemagen.DirectionClient, Payload: GetLimitsPayload{}},
+			{Type: constants.MsgTypeSetLimit, Direction: schemagen.DirectionClient, Payload: SetLimitPayload{}},
+			{Type: constants.MsgTypeSelfExclude, Direction: schemagen.DirectionClient, Payload: SelfExcludePayload{}},
+			{Type: constants.MsgTypeSubscribeFeed, Direction: schemagen.DirectionClient, Payload: SubscribeFeedPayload{}},
+			{Type: constants.MsgTypeUnsubscribeFeed, Direction: schemagen.DirectionClient, Payload: UnsubscribeFeedPayload{}},
+			{Type: constants.MsgTypeGetFreeRounds, Direction: schemagen.DirectionClient, Payload: GetFreeRoundsPayload{}},
+			{Type: constants.MsgTypeHelloAck, Direction: schemagen.DirectionServer, Payload: HelloAckPayload{}},
+			{Type: constants.MsgTypePlayResult, Direction: schemagen.DirectionServer, Payload: PlayResultPayload{}},
+			{Type: constants.MsgTypeBalanceUpdate, Direction: schemagen.DirectionServer, Payload: BalanceUpdatePayload{}},
+			{Type: constants.MsgTypePlayEnded, Direction: schemagen.DirectionServer, Payload: PlayEndedPayload{}},
+			{Type: constants.MsgTypeAutoplayStarted, Direction: schemagen.DirectionServer, Payload: AutoplayStartedPayload{}},
+			{Type: constants.MsgTypeAutoplayStopped, Direction: schemagen.DirectionServer, Payload: AutoplayStoppedPayload{}},
+			{Type: constants.MsgTypeLeaderboard, Direction: schemagen.DirectionServer, Payload: LeaderboardPayload{}},
+			{Type: constants.MsgTypeLimits, Direction: schemagen.DirectionServer, Payload: LimitsPayload{}},
+			{Type: constants.MsgTypeSessionReminder, Direction: schemagen.DirectionServer, Payload: SessionReminderPayload{}},
+			{Type: constants.MsgTypeFeedSnapshot, Direction: schemagen.DirectionServer, Payload: FeedSnapshotPayload{}},
+			{Type: constants.MsgTypeFeedRound, Direction: schemagen.DirectionServer, Payload: FeedRoundPayload{}},
+			{Type: constants.MsgTypeFreeRounds, Direction: schemagen.DirectionServer, Payload: FreeRoundsPayload{}},
+			{Type: constants.MsgTypeJackpotUpdate, Direction: schemagen.DirectionServer, Payload: JackpotUpdatePayload{}},
+			{Type: constants.MsgTypeError, Direction: schemagen.DirectionServer, Payload: ErrorPayload{}},
+		},
+		Enums: []schemagen.Enum{
+			{Name: "BetType", Values: supportedBetTypes, Fields: []string{"PlayPayload.betType", "AutoplayStartPayload.betType", "HelloAckPayload.betTypes", "FeedRoundPayload.betType", "FreeRoundGrantPayload.betType"}},
+			{Name: "Outcome", Values: []string{constants.OutcomeWin, constants.OutcomeLose}, Fields: []string{"PlayResultPayload.outcome", "FeedRoundPayload.outcome"}},
+			{Name: "AutoplayStrategy", Values: autoplay.Strategies, Fields: []string{"AutoplayStartPayload.strategy", "AutoplayStartedPayload.strategy"}},
+			{Name: "AutoplayStopReason", Values: autoplay.StopReasons, Fields: []string{"AutoplayStoppedPayload.reason"}},
+			{Name: "LeaderboardWindow", Values: leaderboard.Windows, Fields: []string{"GetLeaderboardPayload.window", "LeaderboardPayload.window"}},
+			{Name: "LeaderboardMetric", Values: leaderboard.Metrics, Fields: []string{"GetLeaderboardPayload.metric", "LeaderboardPayload.metric"}},
+			{Name: "LimitKind", Values: limits.Kinds, Fields: []string{"SetLimitPayload.kind", "LimitPayload.kind"}},
+			{Name: "ErrorCode", Values: errorCodes, Fields: []string{"ErrorPayload.code"}},
+		},
+	}
+}
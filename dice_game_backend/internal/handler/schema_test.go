@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/BrunoSena97/dice_game_backend/internal/schemagen"
+)
+
+// TestGeneratedProtocolUpToDate fails when the checked-in schema or TypeScript
+// definitions no longer match the Go message structs. The TypeScript file
+// lives in the frontend, outside this module; its check is skipped when the
+// module is built without the frontend next to it.
+func TestGeneratedProtocolUpToDate(t *testing.T) {
+	spec := ProtocolSpec()
+
+	cases := []struct {
+		path     string
+		generate func(schemagen.Spec) ([]byte, error)
+		// outside is the directory, outside this module, that holds path.
+		outside string
+	}{
+		{"../../api/protocol.schema.json", schemagen.JSONSchema, ""},
+		{"../../../dice_game_frontend/src/lib/types/protocol.ts", schemagen.TypeScript, "../../../dice_game_frontend"},
+	}
+
+	for _, tc := range cases {
+		t.Run(filepath.Base(tc.path), func(t *testing.T) {
+			if tc.outside != "" {
+				if _, err := os.Stat(tc.outside); errors.Is(err, fs.ErrNotExist) {
+					t.Skipf("%s not found; skipping the check of %s, which lives outside this module", tc.outside, tc.path)
+				}
+			}
+			want, err := tc.generate(spec)
+			if err != nil {
+				t.Fatalf("generating %s: %v", tc.path, err)
+			}
+			got, err := os.ReadFile(tc.path)
+			if err != nil {
+				t.Fatalf("reading %s: %v", tc.path, err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("%s is stale; run `go generate ./internal/handler`", tc.path)
+			}
+		})
+	}
+}
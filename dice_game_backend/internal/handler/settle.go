@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/BrunoSena97/dice_game_backend/internal/constants"
+	"github.com/BrunoSena97/dice_game_backend/internal/game"
+	"github.com/BrunoSena97/dice_game_backend/internal/limits"
+	"github.com/BrunoSena97/dice_game_backend/internal/rounds"
+	"github.com/BrunoSena97/dice_game_backend/internal/settlement"
+	"github.com/BrunoSena97/dice_game_backend/internal/wallet"
+)
+
+// roundSettlement is a played round whose stake and winnings were applied.
+type roundSettlement struct {
+	result  game.GameResult
+	settled wallet.SettledRound
+}
+
+// settleRound plays the round and applies its stake and payout to the wallet,
+// either on its own or through the settlement batcher. The result is only
+// revealed once the round is settled, so a round the cash and bonus balances
+// cannot cover is refused as if it was never played. The bool is false when the
+// round was refused or failed; the player has been told why.
+func (h *Handler) settleRound(opCtx context.Context, c *client, clientID string, payload PlayPayload) (roundSettlement, bool) {
+	gameResult, gameErr := h.gameSvc.PlayRound(opCtx, payload.BetType, payload.BetAmount)
+	if gameErr != nil {
+		log.Printf("[Play-%s] Error during game logic: %v", clientID, gameErr)
+		h.sendError(c, constants.ErrCodeInternalError, "Failed during game logic.")
+		return roundSettlement{}, false
+	}
+
+	played := wallet.PlayedRound{
+		Round: rounds.Round{
+			UserID:    clientID,
+			Currency:  payload.Currency,
+			BetType:   payload.BetType,
+			BetAmount: payload.BetAmount,
+			Die1:      gameResult.Die1,
+			Die2:      gameResult.Die2,
+			Outcome:   gameResult.Outcome,
+			Winnings:  gameResult.Winnings,
+		},
+		StakeOrder: h.app().Bonus.StakeOrder,
+	}
+	if payload.FreeRoundGrantID != 0 {
+		// Free rounds are rare and must consume their grant, so they are
+		// never batched.
+		played.Round.FreeRoundGrantID = payload.FreeRoundGrantID
+		settled, err := h.walletSvc.SettleFreeRound(opCtx, played)
+		if err != nil {
+			h.sendDebitError(c, clientID, err)
+			return roundSettlement{}, false
+		}
+		log.Printf("[Play-%s] Settled free round %d on grant %d, new balance %d", clientID, settled.Round.ID, payload.FreeRoundGrantID, settled.Balance)
+		return roundSettlement{result: gameResult, settled: settled}, true
+	}
+	if h.jackpot != nil {
+		played.Jackpot = h.jackpot.Play(played.Round)
+	}
+	if h.settler != nil {
+		settled, ok := h.settleBatched(opCtx, c, clientID, played)
+		return roundSettlement{result: gameResult, settled: settled}, ok
+	}
+
+	settled, err := h.walletSvc.SettleRound(opCtx, played)
+	if err != nil {
+		h.sendDebitError(c, clientID, err)
+		return roundSettlement{}, false
+	}
+	log.Printf("[Play-%s] Settled round %d, new balance %d", clientID, settled.Round.ID, settled.Balance)
+	return roundSettlement{result: gameResult, settled: settled}, true
+}
+
+// settleBatched hands a played round to the settlement batcher, which applies
+// it together with other players' rounds, and waits for its result. A round
+// is only reported as failed once it can no longer settle.
+func (h *Handler) settleBatched(opCtx context.Context, c *client, clientID string, played wallet.PlayedRound) (wallet.SettledRound, bool) {
+	pending, err := h.settler.Submit(opCtx, played)
+	if err != nil {
+		log.Printf("[Play-%s] Error queueing round for settlement: %v", clientID, err)
+		h.sendError(c, constants.ErrCodeInternalError, "Failed to settle round.")
+		return wallet.SettledRound{}, false
+	}
+
+	var res settlement.Result
+	select {
+	case res = <-pending.Result():
+	case <-opCtx.Done():
+		if pending.Withdraw() {
+			log.Printf("[Play-%s] Timed out waiting for batch settlement, round withdrawn: %v", clientID, opCtx.Err())
+			h.sendError(c, constants.ErrCodeInternalError, "Round settlement timed out.")
+			return wallet.SettledRound{}, false
+		}
+		// A batch is already writing the round, so it may still settle. Its
+		// result arrives within the flush timeout, and the play lock is held
+		// until then.
+		res = <-pending.Result()
+	}
+	if res.Err != nil {
+		h.sendDebitError(c, clientID, res.Err)
+		return wallet.SettledRound{}, false
+	}
+	log.Printf("[Play-%s] Settled round %d in batch, new balance %d", clientID, res.Round.ID, res.Balance)
+	return res.SettledRound, true
+}
+
+// sendDebitError tells the player why their stake could not be charged.
+func (h *Handler) sendDebitError(c *client, clientID string, err error) {
+	var breach *limits.BreachError
+	if errors.As(err, &breach) {
+		h.sendBreach(c, clientID, breach)
+		return
+	}
+	if errors.Is(err, wallet.ErrInsufficientFunds) {
+		h.sendError(c, constants.ErrCodeInsufficientFunds, "You do not have enough balance for this bet.")
+		return
+	}
+	if errors.Is(err, wallet.ErrFreeRoundUnavailable) {
+		h.sendError(c, constants.ErrCodeFreeRoundUnavailable, "This free round grant is used up, expired or unknown.")
+		return
+	}
+	log.Printf("[Play-%s] Wallet debit error: %v", clientID, err)
+	h.sendError(c, constants.ErrCodeInternalError, "Failed to process bet debit.")
+}
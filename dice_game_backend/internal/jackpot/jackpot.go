@@ -0,0 +1,286 @@
+// Package jackpot runs the progressive jackpot: one pool per currency, fed by
+// a share of every stake and paid out in full to the player whose round hits
+// the trigger. The service decides what each round does to its pool; the
+// wallet applies that in the statement that settles the round, so a round and
+// its jackpot part commit together. Contributions are only ledgered by the
+// round; each replica rolls them into the pools, polls the pools and hands
+// changed values to its local subscribers.
+package jackpot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"maps"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/BrunoSena97/dice_game_backend/internal/constants"
+	"github.com/BrunoSena97/dice_game_backend/internal/platform/database"
+	"github.com/BrunoSena97/dice_game_backend/internal/rounds"
+	"github.com/BrunoSena97/dice_game_backend/internal/wallet"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// TriggerDie is the face both dice must show to win the jackpot.
+const TriggerDie = 6
+
+// Config sets how pools are funded and won. Amounts are in the minor units of
+// each pool's currency.
+type Config struct {
+	// ContributionPercent of every stake is added to the pool, rounded down.
+	ContributionPercent float64
+	// MinBetAmount is the smallest stake that can win the jackpot. Smaller
+	// stakes still contribute.
+	MinBetAmount int64
+	// Seed is what a pool starts at, and is reset to after a win.
+	Seed int64
+	// BroadcastInterval is how often pools are polled for subscribers.
+	BroadcastInterval time.Duration
+}
+
+// Pool is the current value of one currency's jackpot.
+type Pool struct {
+	Currency string
+	Amount   int64
+}
+
+type subscription struct {
+	pools chan []Pool
+}
+
+// Service prices rounds against the pools and broadcasts pool values.
+type Service struct {
+	db         *database.Router
+	dbpool     *pgxpool.Pool
+	currencies []string
+	cfg        Config
+	// basisPoints is ContributionPercent in hundredths of a percent.
+	basisPoints int64
+
+	mu      sync.Mutex
+	current map[string]int64
+	subs    map[*subscription]struct{}
+}
+
+// NewService creates a jackpot service for the given currencies. Call Seed
+// before the first round and Run to start broadcasting.
+func NewService(db *database.Router, currencies []string, cfg Config) *Service {
+	if db == nil {
+		log.Fatal("database router is required in jackpot.NewService")
+	}
+	if cfg.BroadcastInterval <= 0 {
+		log.Fatalf("jackpot.NewService: broadcast interval must be positive, got %v", cfg.BroadcastInterval)
+	}
+	return &Service{
+		db:          db,
+		dbpool:      db.Primary(),
+		currencies:  currencies,
+		cfg:         cfg,
+		basisPoints: int64(math.Round(cfg.ContributionPercent * 100)),
+		subs:        make(map[*subscription]struct{}),
+	}
+}
+
+// Contribution is the share of stake that goes into the pool.
+func (s *Service) Contribution(stake int64) int64 {
+	return stake * s.basisPoints / 10000
+}
+
+// Triggers reports whether a round wins the jackpot: double sixes on a stake
+// of at least MinBetAmount. Free rounds never win it.
+func (s *Service) Triggers(r rounds.Round) bool {
+	return r.FreeRoundGrantID == 0 && r.BetAmount >= s.cfg.MinBetAmount &&
+		r.Die1 == TriggerDie && r.Die2 == TriggerDie
+}
+
+// Play is what a round does to its pool, for the wallet to apply when it
+// settles the round. Free rounds stake nothing and are left out.
+func (s *Service) Play(r rounds.Round) wallet.JackpotPlay {
+	if r.FreeRoundGrantID != 0 {
+		return wallet.JackpotPlay{}
+	}
+	return wallet.JackpotPlay{
+		Contribution: s.Contribution(r.BetAmount),
+		Wins:         s.Triggers(r),
+		Seed:         s.cfg.Seed,
+	}
+}
+
+// seedQuery creates a missing pool at the seed and ledgers the seed.
+const seedQuery = `
+	WITH seeded AS (
+		INSERT INTO jackpots (currency, amount) VALUES ($1, $2)
+		ON CONFLICT (currency) DO NOTHING
+		RETURNING currency, amount
+	)
+	INSERT INTO jackpot_ledger (currency, entry_type, amount, pool_after, pooled_at)
+	SELECT currency, $3, amount, amount, NOW() FROM seeded;
+`
+
+// Seed creates the pool of every currency that has none yet. Existing pools
+// keep their value.
+func (s *Service) Seed(ctx context.Context) error {
+	for _, currency := range s.currencies {
+		tag, err := s.dbpool.Exec(ctx, seedQuery, currency, s.cfg.Seed, constants.JackpotEntrySeed)
+		if err != nil {
+			return fmt.Errorf("failed to seed %s jackpot: %w", currency, err)
+		}
+		if tag.RowsAffected() > 0 {
+			log.Printf("JACKPOT: %s pool seeded with %d", currency, s.cfg.Seed)
+		}
+	}
+	return nil
+}
+
+// rollUpQuery adds the contributions ledgered since the last roll-up to a
+// pool and marks them pooled. The pool row lock serialises roll-ups with each
+// other and with payouts, so a contribution is pooled exactly once; one that
+// commits during a roll-up waits for the next.
+const rollUpQuery = `
+	WITH pool AS (
+		SELECT currency FROM jackpots WHERE currency = $1 FOR UPDATE
+	), pooled AS (
+		UPDATE jackpot_ledger AS l SET pooled_at = NOW()
+		FROM pool
+		WHERE l.currency = pool.currency AND l.pooled_at IS NULL
+		RETURNING l.amount
+	)
+	UPDATE jackpots SET amount = amount + (SELECT SUM(amount) FROM pooled), updated_at = NOW()
+	WHERE currency = $1 AND EXISTS (SELECT 1 FROM pooled);
+`
+
+// RollUp adds the contributions ledgered by settled rounds to their pools.
+// Run calls it before every poll.
+func (s *Service) RollUp(ctx context.Context) error {
+	for _, currency := range s.currencies {
+		if _, err := s.dbpool.Exec(ctx, rollUpQuery, currency); err != nil {
+			return fmt.Errorf("failed to roll contributions into %s jackpot: %w", currency, err)
+		}
+	}
+	return nil
+}
+
+// Pools returns every pool, ordered by currency. A context allowing stale
+// reads is served by the read replica.
+func (s *Service) Pools(ctx context.Context) ([]Pool, error) {
+	query := `SELECT currency, amount FROM jackpots ORDER BY currency;`
+	var pools []Pool
+	err := s.db.Read(ctx, func(db *pgxpool.Pool) error {
+		rows, err := db.Query(ctx, query)
+		if err != nil {
+			return err
+		}
+		pools, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (Pool, error) {
+			var p Pool
+			err := row.Scan(&p.Currency, &p.Amount)
+			return p, err
+		})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("database error reading jackpot pools: %w", err)
+	}
+	return pools, nil
+}
+
+// Run rolls contributions into the pools and polls them every
+// BroadcastInterval until ctx is cancelled, handing them to subscribers
+// whenever a value changed.
+func (s *Service) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.BroadcastInterval)
+	defer ticker.Stop()
+
+	log.Printf("Jackpot broadcaster polling every %v", s.cfg.BroadcastInterval)
+	for {
+		s.poll(ctx)
+		select {
+		case <-ctx.Done():
+			log.Println("Jackpot broadcaster stopped.")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Service) poll(ctx context.Context) {
+	pollCtx, cancel := context.WithTimeout(ctx, s.cfg.BroadcastInterval)
+	defer cancel()
+
+	if err := s.RollUp(pollCtx); err != nil && ctx.Err() == nil {
+		log.Printf("Jackpot broadcaster: %v", err)
+	}
+	pools, err := s.Pools(database.AllowStale(pollCtx))
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Jackpot broadcaster: %v", err)
+		}
+		return
+	}
+	current := make(map[string]int64, len(pools))
+	for _, p := range pools {
+		current[p.Currency] = p.Amount
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.current != nil && maps.Equal(s.current, current) {
+		return
+	}
+	s.current = current
+	for sub := range s.subs {
+		s.offer(sub, pools)
+	}
+}
+
+// offer hands pools to sub, replacing any update it has not taken yet, so a
+// slow subscriber only ever misses values that were already outdated. s.mu
+// must be held.
+func (s *Service) offer(sub *subscription, pools []Pool) {
+	select {
+	case <-sub.pools:
+	default:
+	}
+	sub.pools <- pools
+}
+
+// Subscribe calls deliver with the current pools, once they are known, and
+// again whenever they change, until unsubscribe is called. deliver runs on
+// its own goroutine.
+func (s *Service) Subscribe(deliver func([]Pool)) (unsubscribe func()) {
+	sub := &subscription{pools: make(chan []Pool, 1)}
+
+	s.mu.Lock()
+	s.subs[sub] = struct{}{}
+	if s.current != nil {
+		s.offer(sub, s.snapshot())
+	}
+	s.mu.Unlock()
+
+	go func() {
+		for pools := range sub.pools {
+			deliver(pools)
+		}
+	}()
+
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if _, ok := s.subs[sub]; ok {
+			delete(s.subs, sub)
+			close(sub.pools)
+		}
+	}
+}
+
+// snapshot returns the last polled pools, ordered by currency. s.mu must be held.
+func (s *Service) snapshot() []Pool {
+	pools := make([]Pool, 0, len(s.current))
+	for _, currency := range slices.Sorted(maps.Keys(s.current)) {
+		pools = append(pools, Pool{Currency: currency, Amount: s.current[currency]})
+	}
+	return pools
+}
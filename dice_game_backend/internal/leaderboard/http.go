@@ -0,0 +1,108 @@
+package leaderboard
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/BrunoSena97/dice_game_backend/internal/constants"
+)
+
+type entryResponse struct {
+	Rank   int64  `json:"rank"`
+	Player string `json:"player"`
+	Score  int64  `json:"score"`
+}
+
+type response struct {
+	Window   string          `json:"window"`
+	Metric   string          `json:"metric"`
+	Currency string          `json:"currency"`
+	Period   string          `json:"period"`
+	Entries  []entryResponse `json:"entries"`
+	Self     *entryResponse  `json:"self"`
+}
+
+type errorResponse struct {
+	Error   string `json:"error"`
+	Message string `json:"message"`
+}
+
+// HTTPHandler serves GET /leaderboard?window=&metric=&currency=&limit=&clientId=.
+type HTTPHandler struct {
+	svc             *Service
+	currencies      map[string]bool
+	defaultCurrency string
+}
+
+func NewHTTPHandler(svc *Service, currencies []string, defaultCurrency string) *HTTPHandler {
+	allowed := make(map[string]bool, len(currencies))
+	for _, c := range currencies {
+		allowed[c] = true
+	}
+	return &HTTPHandler{svc: svc, currencies: allowed, defaultCurrency: defaultCurrency}
+}
+
+func (h *HTTPHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	q := Query{
+		Currency: strings.ToUpper(params.Get("currency")),
+		Window:   params.Get("window"),
+		Metric:   params.Get("metric"),
+		UserID:   params.Get("clientId"),
+	}
+	if q.Currency == "" {
+		q.Currency = h.defaultCurrency
+	}
+	if !h.currencies[q.Currency] {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: constants.ErrCodeInvalidCurrency, Message: "Unsupported currency."})
+		return
+	}
+	if raw := params.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: constants.ErrCodeBadRequest, Message: "limit must be an integer."})
+			return
+		}
+		q.Limit = limit
+	}
+
+	res, err := h.svc.Top(r.Context(), q)
+	if err != nil {
+		if errors.Is(err, ErrInvalidWindow) || errors.Is(err, ErrInvalidMetric) || errors.Is(err, ErrInvalidLimit) {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: constants.ErrCodeBadRequest, Message: err.Error()})
+			return
+		}
+		log.Printf("LEADERBOARD ERROR: %v", err)
+		writeJSON(w, http.StatusInternalServerError, errorResponse{Error: constants.ErrCodeInternalError, Message: "Failed to load leaderboard."})
+		return
+	}
+
+	resp := response{
+		Window:   res.Query.Window,
+		Metric:   res.Query.Metric,
+		Currency: res.Query.Currency,
+		Period:   res.Period,
+		Entries:  make([]entryResponse, 0, len(res.Entries)),
+	}
+	for _, e := range res.Entries {
+		resp.Entries = append(resp.Entries, entryResponse(e))
+	}
+	if res.Self != nil {
+		self := entryResponse(*res.Self)
+		resp.Self = &self
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("LEADERBOARD: Failed to write response: %v", err)
+	}
+}
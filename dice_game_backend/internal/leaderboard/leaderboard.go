@@ -0,0 +1,314 @@
+// Package leaderboard ranks players by net winnings, biggest single win and
+// rounds played over daily, weekly and all-time windows, using Redis sorted sets.
+package leaderboard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/BrunoSena97/dice_game_backend/internal/constants"
+	"github.com/BrunoSena97/dice_game_backend/internal/feed"
+	"github.com/BrunoSena97/dice_game_backend/internal/platform/database"
+	"github.com/BrunoSena97/dice_game_backend/internal/rounds"
+	"github.com/go-redis/redis/v8"
+)
+
+// Windows.
+const (
+	WindowDaily   = "daily"
+	WindowWeekly  = "weekly"
+	WindowAllTime = "all_time"
+)
+
+// Metrics.
+const (
+	MetricNet        = "net"
+	MetricBiggestWin = "biggest_win"
+	MetricRounds     = "rounds"
+)
+
+const (
+	DefaultLimit = 10
+	MaxLimit     = 100
+
+	dailyTTL  = 8 * 24 * time.Hour
+	weeklyTTL = 5 * 7 * 24 * time.Hour
+)
+
+var (
+	Windows = []string{WindowDaily, WindowWeekly, WindowAllTime}
+	Metrics = []string{MetricNet, MetricBiggestWin, MetricRounds}
+
+	ErrInvalidWindow = errors.New("invalid leaderboard window")
+	ErrInvalidMetric = errors.New("invalid leaderboard metric")
+	ErrInvalidLimit  = errors.New("invalid leaderboard limit")
+)
+
+// Query selects one leaderboard. UserID is optional; when set, the caller's own standing is included.
+type Query struct {
+	Currency string
+	Window   string
+	Metric   string
+	Limit    int
+	UserID   string
+}
+
+// Entry is one ranked player. Player is the anonymized handle.
+type Entry struct {
+	Rank   int64
+	Player string
+	Score  int64
+}
+
+// Result is a leaderboard page plus the caller's own standing, if ranked.
+type Result struct {
+	Query   Query
+	Period  string
+	Entries []Entry
+	Self    *Entry
+}
+
+// Service maintains and queries the leaderboards.
+type Service struct {
+	redisClient redis.UniversalClient
+	store       *rounds.Store
+	now         func() time.Time
+}
+
+func NewService(redisClient redis.UniversalClient, store *rounds.Store) *Service {
+	if redisClient == nil {
+		log.Fatal("RedisClient is nil in leaderboard.NewService")
+	}
+	if store == nil {
+		log.Fatal("rounds.Store is nil in leaderboard.NewService")
+	}
+	return &Service{redisClient: redisClient, store: store, now: time.Now}
+}
+
+// Validate checks and defaults a query.
+func (q *Query) Validate() error {
+	if q.Window == "" {
+		q.Window = WindowDaily
+	}
+	if q.Metric == "" {
+		q.Metric = MetricNet
+	}
+	if q.Limit == 0 {
+		q.Limit = DefaultLimit
+	}
+	if !contains(Windows, q.Window) {
+		return fmt.Errorf("%w: %q", ErrInvalidWindow, q.Window)
+	}
+	if !contains(Metrics, q.Metric) {
+		return fmt.Errorf("%w: %q", ErrInvalidMetric, q.Metric)
+	}
+	if q.Limit < 0 || q.Limit > MaxLimit {
+		return fmt.Errorf("%w: %d (max %d)", ErrInvalidLimit, q.Limit, MaxLimit)
+	}
+	return nil
+}
+
+// Record folds a settled round into every window's sorted sets.
+func (s *Service) Record(ctx context.Context, r rounds.Round) error {
+	_, err := s.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, window := range Windows {
+			period, ttl := periodFor(window, r.PlayedAt)
+			pipe.ZIncrBy(ctx, key(r.Currency, MetricNet, window, period), float64(r.Net()), r.UserID)
+			pipe.ZIncrBy(ctx, key(r.Currency, MetricRounds, window, period), 1, r.UserID)
+			if win := winOf(r); win > 0 {
+				pipe.ZAddArgs(ctx, key(r.Currency, MetricBiggestWin, window, period), redis.ZAddArgs{
+					GT:      true,
+					Members: []redis.Z{{Score: float64(win), Member: r.UserID}},
+				})
+			}
+			if ttl > 0 {
+				for _, metric := range Metrics {
+					pipe.Expire(ctx, key(r.Currency, metric, window, period), ttl)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record round %d on leaderboards: %w", r.ID, err)
+	}
+	return nil
+}
+
+// Top returns a leaderboard page for the current period of the query's window.
+func (s *Service) Top(ctx context.Context, q Query) (Result, error) {
+	if err := q.Validate(); err != nil {
+		return Result{}, err
+	}
+	period, _ := periodFor(q.Window, s.now())
+	k := key(q.Currency, q.Metric, q.Window, period)
+
+	res := Result{Query: q, Period: period, Entries: []Entry{}}
+	if q.Limit > 0 {
+		zs, err := s.redisClient.ZRevRangeWithScores(ctx, k, 0, int64(q.Limit-1)).Result()
+		if err != nil {
+			return Result{}, fmt.Errorf("failed to read leaderboard %s: %w", k, err)
+		}
+		for i, z := range zs {
+			member, _ := z.Member.(string)
+			res.Entries = append(res.Entries, Entry{Rank: int64(i + 1), Player: feed.Anonymize(member), Score: int64(z.Score)})
+		}
+	}
+
+	if q.UserID != "" {
+		rank, err := s.redisClient.ZRevRank(ctx, k, q.UserID).Result()
+		switch {
+		case errors.Is(err, redis.Nil):
+		case err != nil:
+			return Result{}, fmt.Errorf("failed to read rank on %s: %w", k, err)
+		default:
+			score, err := s.redisClient.ZScore(ctx, k, q.UserID).Result()
+			if err != nil {
+				return Result{}, fmt.Errorf("failed to read score on %s: %w", k, err)
+			}
+			res.Self = &Entry{Rank: rank + 1, Player: feed.Anonymize(q.UserID), Score: int64(score)}
+		}
+	}
+	return res, nil
+}
+
+// Rebuild recomputes the current period of every window from the rounds table.
+// Each sorted set is built under a temporary key and swapped in with RENAME,
+// so readers never see a half-built board. Rounds recorded while the rebuild
+// runs can be missed; run it when traffic is low or rerun it. Rounds are read
+// from the read replica when one is configured.
+func (s *Service) Rebuild(ctx context.Context) error {
+	now := s.now()
+	type board struct {
+		window, period string
+		ttl            time.Duration
+	}
+	var boards []board
+	for _, window := range Windows {
+		period, ttl := periodFor(window, now)
+		boards = append(boards, board{window, period, ttl})
+	}
+
+	// scores[currency][board][metric][userID]
+	scores := make(map[string]map[board]map[string]map[string]int64)
+	add := func(currency string, b board, metric, userID string, value int64, max bool) {
+		if scores[currency] == nil {
+			scores[currency] = make(map[board]map[string]map[string]int64)
+		}
+		if scores[currency][b] == nil {
+			scores[currency][b] = make(map[string]map[string]int64)
+		}
+		if scores[currency][b][metric] == nil {
+			scores[currency][b][metric] = make(map[string]int64)
+		}
+		m := scores[currency][b][metric]
+		if max {
+			if value > m[userID] {
+				m[userID] = value
+			}
+			return
+		}
+		m[userID] += value
+	}
+
+	count := 0
+	err := s.store.ForEachSince(database.AllowStale(ctx), time.Time{}, func(r rounds.Round) error {
+		count++
+		for _, b := range boards {
+			if p, _ := periodFor(b.window, r.PlayedAt); p != b.period {
+				continue
+			}
+			add(r.Currency, b, MetricNet, r.UserID, r.Net(), false)
+			add(r.Currency, b, MetricRounds, r.UserID, 1, false)
+			if win := winOf(r); win > 0 {
+				add(r.Currency, b, MetricBiggestWin, r.UserID, win, true)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read rounds for leaderboard rebuild: %w", err)
+	}
+
+	for currency, byBoard := range scores {
+		for _, b := range boards {
+			for _, metric := range Metrics {
+				if err := s.replaceBoard(ctx, key(currency, metric, b.window, b.period), byBoard[b][metric], b.ttl); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	log.Printf("Leaderboards rebuilt from %d rounds", count)
+	return nil
+}
+
+// replaceBoard atomically swaps the sorted set at k for the given scores.
+func (s *Service) replaceBoard(ctx context.Context, k string, byUser map[string]int64, ttl time.Duration) error {
+	if len(byUser) == 0 {
+		if err := s.redisClient.Del(ctx, k).Err(); err != nil {
+			return fmt.Errorf("failed to clear leaderboard %s: %w", k, err)
+		}
+		return nil
+	}
+
+	tmp := k + ":rebuild"
+	members := make([]*redis.Z, 0, len(byUser))
+	for userID, score := range byUser {
+		members = append(members, &redis.Z{Score: float64(score), Member: userID})
+	}
+	_, err := s.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, tmp)
+		pipe.ZAdd(ctx, tmp, members...)
+		pipe.Rename(ctx, tmp, k)
+		if ttl > 0 {
+			pipe.Expire(ctx, k, ttl)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write rebuilt leaderboard %s: %w", k, err)
+	}
+	return nil
+}
+
+// winOf returns the round's winnings if it was a win, otherwise zero.
+func winOf(r rounds.Round) int64 {
+	if r.Outcome == constants.OutcomeWin {
+		return r.Winnings
+	}
+	return 0
+}
+
+// periodFor names the period containing t for a window, with the TTL its keys should carry.
+func periodFor(window string, t time.Time) (string, time.Duration) {
+	t = t.UTC()
+	switch window {
+	case WindowDaily:
+		return t.Format("2006-01-02"), dailyTTL
+	case WindowWeekly:
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week), weeklyTTL
+	default:
+		return "all", 0
+	}
+}
+
+// key names a sorted set. The currency is a hash tag so that every key touched
+// by one round lands in the same Redis Cluster slot.
+func key(currency, metric, window, period string) string {
+	return fmt.Sprintf("%s{%s}:%s:%s:%s", constants.RedisKeyPrefixLeaderboard, currency, metric, window, period)
+}
+
+func contains(list []string, v string) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
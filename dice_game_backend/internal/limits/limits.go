@@ -0,0 +1,371 @@
+// Package limits enforces responsible gaming limits: loss caps, a maximum
+// stake, session reminders and self-exclusion. Limits are stored in PostgreSQL.
+//
+// Making a limit stricter takes effect immediately. Loosening or removing one
+// is only applied after a cooling-off delay; until then it is held as pending.
+package limits
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Limit kinds. Loss and stake limits are per currency; the session reminder
+// interval (in minutes) applies to the player as a whole.
+const (
+	KindDailyLoss       = "daily_loss"
+	KindWeeklyLoss      = "weekly_loss"
+	KindMonthlyLoss     = "monthly_loss"
+	KindMaxStake        = "max_stake"
+	KindSessionReminder = "session_reminder"
+)
+
+// MaxSelfExclusionDays caps a single self-exclusion request at five years.
+const MaxSelfExclusionDays = 5 * 365
+
+var (
+	Kinds = []string{KindDailyLoss, KindWeeklyLoss, KindMonthlyLoss, KindMaxStake, KindSessionReminder}
+
+	// LossKinds are the loss limits, shortest period first.
+	LossKinds = []string{KindDailyLoss, KindWeeklyLoss, KindMonthlyLoss}
+
+	ErrInvalidKind      = errors.New("invalid limit kind")
+	ErrInvalidValue     = errors.New("invalid limit value")
+	ErrCurrencyRequired = errors.New("currency required for this limit")
+	ErrInvalidDuration  = errors.New("invalid self-exclusion duration")
+
+	ErrSelfExcluded       = errors.New("player is self-excluded")
+	ErrStakeLimitExceeded = errors.New("stake exceeds the player's max stake")
+	ErrLossLimitReached   = errors.New("loss limit reached")
+)
+
+// Limit is one configured limit. A Value of zero means no limit.
+type Limit struct {
+	Kind     string
+	Currency string
+	Value    int64
+	Pending  *PendingChange
+}
+
+// PendingChange is a loosened limit waiting out its cooling-off delay.
+type PendingChange struct {
+	Value       int64
+	EffectiveAt time.Time
+}
+
+// Settings is everything a player has configured.
+type Settings struct {
+	Limits        []Limit
+	ExcludedUntil *time.Time
+}
+
+// BreachError explains why a play was refused. It unwraps to ErrSelfExcluded,
+// ErrStakeLimitExceeded or ErrLossLimitReached.
+type BreachError struct {
+	Err   error
+	Kind  string
+	Limit int64
+	// Until is set for self-exclusion.
+	Until time.Time
+}
+
+func (e *BreachError) Error() string {
+	if errors.Is(e.Err, ErrSelfExcluded) {
+		return fmt.Sprintf("%v until %s", e.Err, e.Until.Format(time.RFC3339))
+	}
+	return fmt.Sprintf("%v: %s limit %d", e.Err, e.Kind, e.Limit)
+}
+
+func (e *BreachError) Unwrap() error { return e.Err }
+
+// Service reads, changes and enforces player limits.
+type Service struct {
+	dbpool     *pgxpool.Pool
+	coolingOff time.Duration
+	now        func() time.Time
+}
+
+// NewService creates a limits service. coolingOff is how long a loosened limit stays pending.
+func NewService(dbpool *pgxpool.Pool, coolingOff time.Duration) *Service {
+	if dbpool == nil {
+		log.Fatal("limits.Service requires a non-nil dbpool")
+	}
+	return &Service{dbpool: dbpool, coolingOff: coolingOff, now: time.Now}
+}
+
+// Get returns all of a player's limits and any active self-exclusion.
+func (s *Service) Get(ctx context.Context, userID string) (Settings, error) {
+	now := s.now()
+	all, err := s.loadLimits(ctx, userID, "", now)
+	if err != nil {
+		return Settings{}, err
+	}
+	until, err := s.excludedUntil(ctx, userID, now)
+	if err != nil {
+		return Settings{}, err
+	}
+	return Settings{Limits: all, ExcludedUntil: until}, nil
+}
+
+// SetLimit changes one limit. A value of zero removes it. Stricter values apply
+// at once and cancel any pending change; looser ones become pending.
+func (s *Service) SetLimit(ctx context.Context, userID, kind, currency string, value int64) error {
+	if !isKind(kind) {
+		return fmt.Errorf("%w: %q", ErrInvalidKind, kind)
+	}
+	if value < 0 {
+		return fmt.Errorf("%w: %d", ErrInvalidValue, value)
+	}
+	if kind == KindSessionReminder {
+		currency = ""
+	} else if currency == "" {
+		return fmt.Errorf("%w: %s", ErrCurrencyRequired, kind)
+	}
+
+	tx, err := s.dbpool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	now := s.now()
+	var current int64
+	var pendingValue *int64
+	var pendingAt *time.Time
+	err = tx.QueryRow(ctx, `
+		SELECT value, pending_value, pending_effective_at
+		FROM player_limits
+		WHERE user_id = $1 AND kind = $2 AND currency = $3
+		FOR UPDATE;
+	`, userID, kind, currency).Scan(&current, &pendingValue, &pendingAt)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("failed to read %s limit for user %s: %w", kind, userID, err)
+	}
+	if pendingValue != nil && pendingAt != nil && !pendingAt.After(now) {
+		current = *pendingValue
+	}
+
+	query := `
+		INSERT INTO player_limits (user_id, kind, currency, value, pending_value, pending_effective_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
+		ON CONFLICT (user_id, kind, currency) DO UPDATE
+		SET value = EXCLUDED.value,
+		    pending_value = EXCLUDED.pending_value,
+		    pending_effective_at = EXCLUDED.pending_effective_at,
+		    updated_at = NOW();
+	`
+	if isStricter(value, current) || value == current {
+		_, err = tx.Exec(ctx, query, userID, kind, currency, value, nil, nil)
+	} else {
+		_, err = tx.Exec(ctx, query, userID, kind, currency, current, value, now.Add(s.coolingOff))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to store %s limit for user %s: %w", kind, userID, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit limit change: %w", err)
+	}
+	log.Printf("LIMITS: user %s set %s %s to %d (was %d)", userID, kind, currency, value, current)
+	return nil
+}
+
+// SelfExclude blocks the player from playing for the given number of days.
+// An existing longer exclusion is kept; exclusions can never be shortened.
+func (s *Service) SelfExclude(ctx context.Context, userID string, days int) (time.Time, error) {
+	if days <= 0 || days > MaxSelfExclusionDays {
+		return time.Time{}, fmt.Errorf("%w: %d days (1-%d)", ErrInvalidDuration, days, MaxSelfExclusionDays)
+	}
+	until := s.now().Add(time.Duration(days) * 24 * time.Hour)
+
+	var effective time.Time
+	err := s.dbpool.QueryRow(ctx, `
+		INSERT INTO self_exclusions (user_id, excluded_until)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET excluded_until = GREATEST(self_exclusions.excluded_until, EXCLUDED.excluded_until),
+		    updated_at = NOW()
+		RETURNING excluded_until;
+	`, userID, until).Scan(&effective)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to store self-exclusion for user %s: %w", userID, err)
+	}
+	log.Printf("LIMITS: user %s self-excluded until %s", userID, effective.Format(time.RFC3339))
+	return effective, nil
+}
+
+// checkPlayQuery reads everything CheckPlay needs in one round trip: any
+// active self-exclusion, the effective limits of the currency (pending changes
+// past their cooling-off count) and, only when a loss limit is set, the net
+// losses of the current day, week and month.
+const checkPlayQuery = `
+	WITH effective AS (
+		SELECT kind,
+			CASE WHEN pending_value IS NOT NULL AND pending_effective_at <= $3 THEN pending_value ELSE value END AS value
+		FROM player_limits
+		WHERE user_id = $1 AND currency IN ($2, '')
+	), pivoted AS (
+		SELECT
+			COALESCE(MAX(value) FILTER (WHERE kind = $4), 0) AS max_stake,
+			COALESCE(MAX(value) FILTER (WHERE kind = $5), 0) AS daily,
+			COALESCE(MAX(value) FILTER (WHERE kind = $6), 0) AS weekly,
+			COALESCE(MAX(value) FILTER (WHERE kind = $7), 0) AS monthly,
+			COALESCE(MAX(value) FILTER (WHERE kind = $8), 0) AS reminder
+		FROM effective
+	)
+	SELECT
+		(SELECT excluded_until FROM self_exclusions WHERE user_id = $1 AND excluded_until > $3),
+		p.max_stake, p.daily, p.weekly, p.monthly, p.reminder,
+		losses.daily, losses.weekly, losses.monthly
+	FROM pivoted AS p
+	CROSS JOIN LATERAL (
+		SELECT
+			COALESCE(-SUM(net_amount) FILTER (WHERE played_at >= $9), 0) AS daily,
+			COALESCE(-SUM(net_amount) FILTER (WHERE played_at >= $10), 0) AS weekly,
+			COALESCE(-SUM(net_amount) FILTER (WHERE played_at >= $11), 0) AS monthly
+		FROM rounds
+		WHERE (p.daily > 0 OR p.weekly > 0 OR p.monthly > 0)
+			AND user_id = $1 AND currency = $2
+			AND played_at >= LEAST($9::timestamptz, $10::timestamptz, $11::timestamptz)
+	) AS losses;
+`
+
+// Standing is what a play is checked against: any active self-exclusion, the
+// effective limits of its currency and the player's net losses in each loss
+// limit's period. Limits of zero are not set.
+type Standing struct {
+	ExcludedUntil *time.Time
+	MaxStake      int64
+	// LossLimits and Losses follow the order of LossKinds.
+	LossLimits, Losses [3]int64
+	// ReminderMinutes is the session reminder interval.
+	ReminderMinutes int64
+}
+
+// Check verifies that stake may be wagered. On success it returns the
+// player's session reminder interval, zero when none is set.
+func (st Standing) Check(stake int64) (time.Duration, error) {
+	if st.ExcludedUntil != nil {
+		return 0, &BreachError{Err: ErrSelfExcluded, Until: *st.ExcludedUntil}
+	}
+	if st.MaxStake > 0 && stake > st.MaxStake {
+		return 0, &BreachError{Err: ErrStakeLimitExceeded, Kind: KindMaxStake, Limit: st.MaxStake}
+	}
+	for i, kind := range LossKinds {
+		// The stake is the most this round can add to the player's losses.
+		if limit := st.LossLimits[i]; limit > 0 && st.Losses[i]+stake > limit {
+			return 0, &BreachError{Err: ErrLossLimitReached, Kind: kind, Limit: limit}
+		}
+	}
+	return time.Duration(st.ReminderMinutes) * time.Minute, nil
+}
+
+// CheckPlay verifies that stake may be wagered in currency. It must be called
+// before the round is settled, while the player's play lock is held, so that
+// losses from earlier rounds are already recorded. On success it returns the
+// player's session reminder interval, zero when none is set.
+//
+// Limits are read from the primary on every play rather than cached, so a
+// stricter limit or a self-exclusion stops play on every replica at once. The
+// check costs one query; the rounds table is only summed when a loss limit
+// is set. Paid rounds are checked by the statement that settles them instead.
+func (s *Service) CheckPlay(ctx context.Context, userID, currency string, stake int64) (time.Duration, error) {
+	now := s.now()
+	dayStart, weekStart, monthStart := PeriodStarts(now)
+
+	var st Standing
+	err := s.dbpool.QueryRow(ctx, checkPlayQuery, userID, currency, now,
+		KindMaxStake, KindDailyLoss, KindWeeklyLoss, KindMonthlyLoss, KindSessionReminder,
+		dayStart, weekStart, monthStart,
+	).Scan(&st.ExcludedUntil, &st.MaxStake, &st.LossLimits[0], &st.LossLimits[1], &st.LossLimits[2], &st.ReminderMinutes,
+		&st.Losses[0], &st.Losses[1], &st.Losses[2])
+	if err != nil {
+		return 0, fmt.Errorf("failed to check limits for user %s: %w", userID, err)
+	}
+	return st.Check(stake)
+}
+
+// loadLimits returns the effective limits of a player, promoting pending
+// changes whose cooling-off has elapsed. With a currency, only that currency's
+// limits and the player-wide ones are returned.
+func (s *Service) loadLimits(ctx context.Context, userID, currency string, now time.Time) ([]Limit, error) {
+	query := `
+		SELECT kind, currency, value, pending_value, pending_effective_at
+		FROM player_limits
+		WHERE user_id = $1 AND ($2 = '' OR currency IN ($2, ''))
+		ORDER BY kind, currency;
+	`
+	rows, err := s.dbpool.Query(ctx, query, userID, currency)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query limits for user %s: %w", userID, err)
+	}
+	defer rows.Close()
+
+	var out []Limit
+	for rows.Next() {
+		var l Limit
+		var pendingValue *int64
+		var pendingAt *time.Time
+		if err := rows.Scan(&l.Kind, &l.Currency, &l.Value, &pendingValue, &pendingAt); err != nil {
+			return nil, fmt.Errorf("failed to scan limit: %w", err)
+		}
+		if pendingValue != nil && pendingAt != nil {
+			if pendingAt.After(now) {
+				l.Pending = &PendingChange{Value: *pendingValue, EffectiveAt: *pendingAt}
+			} else {
+				l.Value = *pendingValue
+			}
+		}
+		if l.Value == 0 && l.Pending == nil {
+			continue
+		}
+		out = append(out, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read limits for user %s: %w", userID, err)
+	}
+	return out, nil
+}
+
+func (s *Service) excludedUntil(ctx context.Context, userID string, now time.Time) (*time.Time, error) {
+	var until time.Time
+	err := s.dbpool.QueryRow(ctx, `
+		SELECT excluded_until FROM self_exclusions WHERE user_id = $1 AND excluded_until > $2;
+	`, userID, now).Scan(&until)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read self-exclusion for user %s: %w", userID, err)
+	}
+	return &until, nil
+}
+
+// PeriodStarts returns the start of the UTC day, ISO week (Monday) and month containing t.
+func PeriodStarts(t time.Time) (day, week, month time.Time) {
+	t = t.UTC()
+	day = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	week = day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	month = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return day, week, month
+}
+
+// isStricter reports whether next tightens current. Zero means no limit.
+func isStricter(next, current int64) bool {
+	return next > 0 && (current == 0 || next < current)
+}
+
+func isKind(kind string) bool {
+	for _, k := range Kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,138 @@
+// Package notify fans wallet events out to every live connection of a player,
+// across all backend replicas, through Redis pub/sub.
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/BrunoSena97/dice_game_backend/internal/constants"
+	"github.com/go-redis/redis/v8"
+)
+
+// subscriberBuffer is how many undelivered events a subscriber may queue
+// before newer events are dropped for it.
+const subscriberBuffer = 16
+
+// BalanceEvent is published whenever a wallet balance changes.
+type BalanceEvent struct {
+	UserID   string `json:"userId"`
+	Currency string `json:"currency"`
+	Balance  int64  `json:"balance"`
+}
+
+type balanceSubscription struct {
+	userID string
+	events chan BalanceEvent
+}
+
+// BalanceHub delivers balance events to local subscribers and publishes
+// local changes so that other replicas can do the same.
+type BalanceHub struct {
+	redisClient redis.UniversalClient
+
+	mu   sync.RWMutex
+	subs map[string]map[*balanceSubscription]struct{}
+}
+
+// NewBalanceHub creates a hub. Call Run to start receiving events.
+func NewBalanceHub(redisClient redis.UniversalClient) *BalanceHub {
+	if redisClient == nil {
+		log.Fatal("RedisClient is nil in NewBalanceHub")
+	}
+	return &BalanceHub{
+		redisClient: redisClient,
+		subs:        make(map[string]map[*balanceSubscription]struct{}),
+	}
+}
+
+// Run listens on the balance channel until ctx is cancelled.
+func (h *BalanceHub) Run(ctx context.Context) {
+	pubsub := h.redisClient.Subscribe(ctx, constants.RedisChannelBalanceUpdates)
+	defer pubsub.Close()
+
+	log.Printf("Balance hub subscribed to %s", constants.RedisChannelBalanceUpdates)
+	ch := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Balance hub stopped.")
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				log.Println("Balance hub channel closed.")
+				return
+			}
+			var event BalanceEvent
+			if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+				log.Printf("Balance hub: invalid event %q: %v", msg.Payload, err)
+				continue
+			}
+			h.dispatch(event)
+		}
+	}
+}
+
+// NotifyBalance publishes a balance change to every replica.
+// Failures are logged; the change itself has already been committed.
+func (h *BalanceHub) NotifyBalance(ctx context.Context, userID, currency string, balance int64) {
+	data, err := json.Marshal(BalanceEvent{UserID: userID, Currency: currency, Balance: balance})
+	if err != nil {
+		log.Printf("Balance hub: failed to encode event for %s: %v", userID, err)
+		return
+	}
+
+	pubCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), time.Duration(constants.ShortOpTimeout)*time.Second)
+	defer cancel()
+	if err := h.redisClient.Publish(pubCtx, constants.RedisChannelBalanceUpdates, data).Err(); err != nil {
+		log.Printf("Balance hub: failed to publish balance for %s: %v", userID, err)
+	}
+}
+
+// Subscribe calls deliver for every balance event of userID until the
+// returned unsubscribe function is called. deliver runs on its own goroutine,
+// one event at a time, so a slow connection never stalls the hub.
+func (h *BalanceHub) Subscribe(userID string, deliver func(BalanceEvent)) (unsubscribe func()) {
+	sub := &balanceSubscription{userID: userID, events: make(chan BalanceEvent, subscriberBuffer)}
+
+	h.mu.Lock()
+	if h.subs[userID] == nil {
+		h.subs[userID] = make(map[*balanceSubscription]struct{})
+	}
+	h.subs[userID][sub] = struct{}{}
+	h.mu.Unlock()
+
+	go func() {
+		for event := range sub.events {
+			deliver(event)
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			h.mu.Lock()
+			delete(h.subs[userID], sub)
+			if len(h.subs[userID]) == 0 {
+				delete(h.subs, userID)
+			}
+			close(sub.events)
+			h.mu.Unlock()
+		})
+	}
+}
+
+func (h *BalanceHub) dispatch(event BalanceEvent) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for sub := range h.subs[event.UserID] {
+		select {
+		case sub.events <- event:
+		default:
+			log.Printf("Balance hub: subscriber for %s is full, dropping event", event.UserID)
+		}
+	}
+}
@@ -16,6 +16,47 @@ type Config struct {
 	Password string
 	DBName   string
 	SSLMode  string
+
+	// Pool sizing and connection lifetimes.
+	MaxConns          int32
+	MinConns          int32
+	MaxConnLifetime   time.Duration
+	MaxConnIdleTime   time.Duration
+	HealthCheckPeriod time.Duration
+	// ConnectTimeout bounds establishing the pool at startup.
+	ConnectTimeout time.Duration
+	// AutoMigrate applies pending migrations at startup instead of only checking the version.
+	AutoMigrate bool
+	// PlayLockConns sizes the separate pool that holds Postgres play locks.
+	PlayLockConns int32
+
+	// ReplicaHost enables a streaming read replica sharing the primary's
+	// credentials and pool settings. Empty disables it.
+	ReplicaHost string
+	ReplicaPort int
+	// ReplicaMaxLag is the staleness beyond which reads go back to the primary.
+	ReplicaMaxLag        time.Duration
+	ReplicaCheckInterval time.Duration
+}
+
+// Replica returns the connection settings of the read replica.
+func (c Config) Replica() Config {
+	replica := c
+	replica.Host = c.ReplicaHost
+	replica.Port = c.ReplicaPort
+	replica.AutoMigrate = false
+	return replica
+}
+
+// PlayLocks returns the settings of the pool holding Postgres play locks. Each
+// held lock pins a connection, so locks get their own pool and can never take
+// the connections rounds need to settle.
+func (c Config) PlayLocks() Config {
+	locks := c
+	locks.MaxConns = c.PlayLockConns
+	locks.MinConns = 0
+	locks.AutoMigrate = false
+	return locks
 }
 
 // Connect establishes a connection pool to the PostgreSQL database.
@@ -27,8 +68,11 @@ func Connect(ctx context.Context, cfg Config) (*pgxpool.Pool, error) {
 		return nil, fmt.Errorf("failed to parse database connection config: %w", err)
 	}
 
-	poolConfig.MaxConns = int32(10)
-	poolConfig.HealthCheckPeriod = 1 * time.Minute
+	poolConfig.MaxConns = cfg.MaxConns
+	poolConfig.MinConns = cfg.MinConns
+	poolConfig.MaxConnLifetime = cfg.MaxConnLifetime
+	poolConfig.MaxConnIdleTime = cfg.MaxConnIdleTime
+	poolConfig.HealthCheckPeriod = cfg.HealthCheckPeriod
 
 	log.Printf("Database pool config: MaxConns=%d, MinConns=%d, MaxConnLifetime=%v, MaxConnIdleTime=%v",
 		poolConfig.MaxConns, poolConfig.MinConns, poolConfig.MaxConnLifetime, poolConfig.MaxConnIdleTime)
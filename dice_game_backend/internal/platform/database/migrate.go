@@ -0,0 +1,314 @@
+package database
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Migrations are embedded SQL files named NNNN_description.up.sql with a
+// matching NNNN_description.down.sql. Each runs in its own transaction.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLockID is the advisory lock key serialising migration runs, so
+// replicas starting together do not apply the same migration twice.
+const migrationLockID int64 = 0x646963655f6d6967 // "dice_mig"
+
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+var (
+	ErrSchemaOutdated = errors.New("database schema is behind the application")
+	ErrSchemaTooNew   = errors.New("database schema is ahead of the application")
+)
+
+// Migration is one schema version.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationState is a migration and when it was applied, if it was.
+type MigrationState struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// Migrations returns the embedded migrations in version order.
+func Migrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list migrations: %w", err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		m := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if m == nil {
+			return nil, fmt.Errorf("unexpected migration file name %q", entry.Name())
+		}
+		version, _ := strconv.Atoi(m[1])
+		body, err := fs.ReadFile(migrationFiles, "migrations/"+entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+
+		mig := byVersion[version]
+		if mig == nil {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		} else if mig.Name != m[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			mig.Up = string(body)
+		} else {
+			mig.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" || mig.Down == "" {
+			return nil, fmt.Errorf("migration %04d_%s needs both an up and a down file", mig.Version, mig.Name)
+		}
+		migrations = append(migrations, *mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	for i, mig := range migrations {
+		if mig.Version != i+1 {
+			return nil, fmt.Errorf("migration versions must be contiguous from 1: found %d at position %d", mig.Version, i+1)
+		}
+	}
+	return migrations, nil
+}
+
+// LatestVersion is the schema version this build expects.
+func LatestVersion() (int, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return 0, err
+	}
+	return len(migrations), nil
+}
+
+// MigrateUp applies every pending migration and returns those applied.
+func MigrateUp(ctx context.Context, pool *pgxpool.Pool) ([]Migration, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+	err = withMigrationLock(ctx, pool, func(conn *pgx.Conn) error {
+		current, err := currentVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if current > len(migrations) {
+			return fmt.Errorf("%w: at version %d, latest known is %d", ErrSchemaTooNew, current, len(migrations))
+		}
+		for _, mig := range migrations[current:] {
+			if err := applyMigration(ctx, conn, mig, true); err != nil {
+				return err
+			}
+			applied = append(applied, mig)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// MigrateDown reverts the last steps applied migrations and returns those reverted.
+func MigrateDown(ctx context.Context, pool *pgxpool.Pool, steps int) ([]Migration, error) {
+	if steps < 1 {
+		return nil, fmt.Errorf("invalid number of migrations to revert: %d", steps)
+	}
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var reverted []Migration
+	err = withMigrationLock(ctx, pool, func(conn *pgx.Conn) error {
+		current, err := currentVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if current > len(migrations) {
+			return fmt.Errorf("%w: at version %d, latest known is %d", ErrSchemaTooNew, current, len(migrations))
+		}
+		for v := current; v > 0 && len(reverted) < steps; v-- {
+			mig := migrations[v-1]
+			if err := applyMigration(ctx, conn, mig, false); err != nil {
+				return err
+			}
+			reverted = append(reverted, mig)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// MigrationStatus lists every known migration and whether it is applied.
+func MigrationStatus(ctx context.Context, pool *pgxpool.Pool) ([]MigrationState, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	if exists, err := migrationsTableExists(ctx, pool); err != nil || !exists {
+		states := make([]MigrationState, len(migrations))
+		for i, mig := range migrations {
+			states[i].Migration = mig
+		}
+		return states, err
+	}
+
+	rows, err := pool.Query(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	appliedAt := make(map[int]time.Time)
+	var version int
+	var at time.Time
+	_, err = pgx.ForEachRow(rows, []any{&version, &at}, func() error {
+		appliedAt[version] = at
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+
+	states := make([]MigrationState, len(migrations))
+	for i, mig := range migrations {
+		states[i].Migration = mig
+		if t, ok := appliedAt[mig.Version]; ok {
+			states[i].AppliedAt = &t
+		}
+	}
+	return states, nil
+}
+
+// CheckSchema verifies that the database is at exactly the version this build
+// expects, so the server never runs against a missing or newer schema.
+func CheckSchema(ctx context.Context, pool *pgxpool.Pool) (int, error) {
+	latest, err := LatestVersion()
+	if err != nil {
+		return 0, err
+	}
+	current := 0
+	if exists, err := migrationsTableExists(ctx, pool); err != nil {
+		return 0, err
+	} else if exists {
+		if err := pool.QueryRow(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+			return 0, fmt.Errorf("failed to read schema version: %w", err)
+		}
+	}
+	switch {
+	case current < latest:
+		return current, fmt.Errorf("%w: at version %d, expected %d (run `server migrate up`)", ErrSchemaOutdated, current, latest)
+	case current > latest:
+		return current, fmt.Errorf("%w: at version %d, expected %d", ErrSchemaTooNew, current, latest)
+	}
+	return current, nil
+}
+
+// withMigrationLock runs fn on a dedicated connection holding the migration
+// advisory lock. Session-level advisory locks belong to a connection, so the
+// same connection must take, use and release it.
+func withMigrationLock(ctx context.Context, pool *pgxpool.Pool, fn func(conn *pgx.Conn) error) error {
+	poolConn, err := pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection for migrations: %w", err)
+	}
+	defer poolConn.Release()
+	conn := poolConn.Conn()
+
+	log.Println("Waiting for migration lock...")
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+		return fmt.Errorf("failed to take migration lock: %w", err)
+	}
+	defer func() {
+		unlockCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+		if _, err := conn.Exec(unlockCtx, `SELECT pg_advisory_unlock($1)`, migrationLockID); err != nil {
+			log.Printf("ERROR: Failed to release migration lock: %v", err)
+		}
+	}()
+
+	if err := ensureMigrationsTable(ctx, conn); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+// migrationsTableExists reports whether migrations were ever run, without
+// creating anything, so status and startup checks stay read-only.
+func migrationsTableExists(ctx context.Context, pool *pgxpool.Pool) (bool, error) {
+	var exists bool
+	if err := pool.QueryRow(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to look up schema_migrations table: %w", err)
+	}
+	return exists, nil
+}
+
+func ensureMigrationsTable(ctx context.Context, conn *pgx.Conn) error {
+	_, err := conn.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+	return nil
+}
+
+func currentVersion(ctx context.Context, conn *pgx.Conn) (int, error) {
+	var current int
+	if err := conn.QueryRow(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return current, nil
+}
+
+// applyMigration runs one migration up or down and records it, atomically.
+func applyMigration(ctx context.Context, conn *pgx.Conn, mig Migration, up bool) error {
+	direction, body := "up", mig.Up
+	if !up {
+		direction, body = "down", mig.Down
+	}
+	start := time.Now()
+
+	err := pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, body); err != nil {
+			return err
+		}
+		var err error
+		if up {
+			_, err = tx.Exec(ctx, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, mig.Version, mig.Name)
+		} else {
+			_, err = tx.Exec(ctx, `DELETE FROM schema_migrations WHERE version = $1`, mig.Version)
+		}
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("migration %04d_%s %s failed: %w", mig.Version, mig.Name, direction, err)
+	}
+	log.Printf("Migrated %s %04d_%s in %v", direction, mig.Version, mig.Name, time.Since(start).Round(time.Millisecond))
+	return nil
+}
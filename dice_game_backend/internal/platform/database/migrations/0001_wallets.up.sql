@@ -1,6 +1,8 @@
+-- Statements are idempotent so databases created by the old db_init script
+-- can be brought under migration control without manual steps.
 CREATE TABLE IF NOT EXISTS wallets (
     id SERIAL PRIMARY KEY,
-    user_id VARCHAR(255) UNIQUE NOT NULL,
+    user_id VARCHAR(255) NOT NULL,
     balance BIGINT NOT NULL DEFAULT 500,
     currency VARCHAR(3) NOT NULL DEFAULT 'PTS',
     created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
@@ -9,5 +11,9 @@ CREATE TABLE IF NOT EXISTS wallets (
 
 CREATE INDEX IF NOT EXISTS idx_wallets_user_id ON wallets(user_id);
 
+-- Wallets are keyed by (user, currency); older volumes had user_id UNIQUE on its own.
+ALTER TABLE wallets DROP CONSTRAINT IF EXISTS wallets_user_id_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_wallets_user_currency ON wallets(user_id, currency);
+
 ALTER TABLE wallets DROP CONSTRAINT IF EXISTS balance_non_negative;
-ALTER TABLE wallets ADD CONSTRAINT balance_non_negative CHECK (balance >= 0);
\ No newline at end of file
+ALTER TABLE wallets ADD CONSTRAINT balance_non_negative CHECK (balance >= 0);
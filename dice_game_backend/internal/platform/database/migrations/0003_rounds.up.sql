@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS rounds (
+    id BIGSERIAL PRIMARY KEY,
+    user_id VARCHAR(255) NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    bet_type VARCHAR(16) NOT NULL,
+    bet_amount BIGINT NOT NULL,
+    die1 SMALLINT NOT NULL,
+    die2 SMALLINT NOT NULL,
+    outcome VARCHAR(8) NOT NULL,
+    winnings BIGINT NOT NULL,
+    net_amount BIGINT NOT NULL,
+    played_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_rounds_user_played_at ON rounds(user_id, played_at);
+CREATE INDEX IF NOT EXISTS idx_rounds_played_at ON rounds(played_at);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS self_exclusions;
+DROP TABLE IF EXISTS player_limits;
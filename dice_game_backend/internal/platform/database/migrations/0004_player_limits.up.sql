@@ -0,0 +1,19 @@
+-- Responsible gaming limits. currency is '' for player-wide limits (session reminder).
+CREATE TABLE IF NOT EXISTS player_limits (
+    user_id VARCHAR(255) NOT NULL,
+    kind VARCHAR(32) NOT NULL,
+    currency VARCHAR(3) NOT NULL DEFAULT '',
+    value BIGINT NOT NULL DEFAULT 0,
+    pending_value BIGINT,
+    pending_effective_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, kind, currency),
+    CONSTRAINT limit_value_non_negative CHECK (value >= 0 AND (pending_value IS NULL OR pending_value >= 0))
+);
+
+CREATE TABLE IF NOT EXISTS self_exclusions (
+    user_id VARCHAR(255) PRIMARY KEY,
+    excluded_until TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,3 @@
+-- version counts committed balance changes so cached balances can be ordered:
+-- a cache write carrying an older version never replaces a newer one.
+ALTER TABLE wallets ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,6 @@
+ALTER TABLE rounds DROP COLUMN IF EXISTS bonus_stake;
+ALTER TABLE wallet_ledger DROP COLUMN IF EXISTS bonus_after;
+ALTER TABLE wallets DROP CONSTRAINT IF EXISTS bonus_balance_non_negative;
+ALTER TABLE wallets DROP COLUMN IF EXISTS wagering_progress;
+ALTER TABLE wallets DROP COLUMN IF EXISTS wagering_required;
+ALTER TABLE wallets DROP COLUMN IF EXISTS bonus_balance;
@@ -0,0 +1,15 @@
+-- A wallet's bonus balance is held apart from its cash balance until the
+-- wagering requirement is met: wagering_progress counts stakes played since the
+-- bonus was granted, and once it reaches wagering_required the bonus moves to
+-- the cash balance.
+ALTER TABLE wallets ADD COLUMN IF NOT EXISTS bonus_balance BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE wallets ADD COLUMN IF NOT EXISTS wagering_required BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE wallets ADD COLUMN IF NOT EXISTS wagering_progress BIGINT NOT NULL DEFAULT 0;
+
+ALTER TABLE wallets DROP CONSTRAINT IF EXISTS bonus_balance_non_negative;
+ALTER TABLE wallets ADD CONSTRAINT bonus_balance_non_negative CHECK (bonus_balance >= 0);
+
+ALTER TABLE wallet_ledger ADD COLUMN IF NOT EXISTS bonus_after BIGINT NOT NULL DEFAULT 0;
+
+-- bonus_stake is the part of bet_amount drawn from the bonus balance.
+ALTER TABLE rounds ADD COLUMN IF NOT EXISTS bonus_stake BIGINT NOT NULL DEFAULT 0;
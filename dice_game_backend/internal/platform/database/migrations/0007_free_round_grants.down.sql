@@ -0,0 +1,2 @@
+ALTER TABLE rounds DROP COLUMN IF EXISTS free_round_grant_id;
+DROP TABLE IF EXISTS free_round_grants;
@@ -0,0 +1,22 @@
+-- A free round grant lets a player play rounds_granted rounds at a fixed stake
+-- and bet type without being charged. Playing one decrements rounds_remaining
+-- in the same statement that settles the round, so a grant cannot be overspent.
+CREATE TABLE IF NOT EXISTS free_round_grants (
+    id BIGSERIAL PRIMARY KEY,
+    user_id VARCHAR(255) NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    bet_type VARCHAR(16) NOT NULL,
+    stake BIGINT NOT NULL CHECK (stake > 0),
+    rounds_granted INT NOT NULL CHECK (rounds_granted > 0),
+    rounds_remaining INT NOT NULL CHECK (rounds_remaining >= 0),
+    expires_at TIMESTAMPTZ NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    actor VARCHAR(255) NOT NULL DEFAULT '',
+    revoked_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_free_round_grants_user ON free_round_grants(user_id, id);
+
+ALTER TABLE rounds ADD COLUMN IF NOT EXISTS free_round_grant_id BIGINT REFERENCES free_round_grants(id);
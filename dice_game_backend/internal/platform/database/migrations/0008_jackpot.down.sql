@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS jackpot_ledger;
+DROP TABLE IF EXISTS jackpots;
@@ -0,0 +1,24 @@
+-- One jackpot pool per currency. Every qualifying stake adds a share to
+-- amount; a jackpot win pays out the whole pool and resets it to its seed.
+CREATE TABLE IF NOT EXISTS jackpots (
+    currency VARCHAR(3) PRIMARY KEY,
+    amount BIGINT NOT NULL DEFAULT 0 CHECK (amount >= 0),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- jackpot_ledger records every change to a pool: seeds, contributions and
+-- payouts. round_id is set for contributions and payouts, and a round
+-- contributes and pays out at most once.
+CREATE TABLE IF NOT EXISTS jackpot_ledger (
+    id BIGSERIAL PRIMARY KEY,
+    currency VARCHAR(3) NOT NULL REFERENCES jackpots(currency),
+    entry_type VARCHAR(32) NOT NULL,
+    amount BIGINT NOT NULL,
+    pool_after BIGINT NOT NULL,
+    user_id VARCHAR(255),
+    round_id BIGINT REFERENCES rounds(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (round_id, entry_type)
+);
+
+CREATE INDEX IF NOT EXISTS idx_jackpot_ledger_currency ON jackpot_ledger(currency, id);
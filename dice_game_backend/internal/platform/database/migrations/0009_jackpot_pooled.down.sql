@@ -0,0 +1,13 @@
+-- Roll outstanding contributions into their pools before dropping the marker.
+UPDATE jackpots AS j
+SET amount = j.amount + unpooled.amount, updated_at = NOW()
+FROM (
+    SELECT currency, SUM(amount) AS amount FROM jackpot_ledger
+    WHERE pooled_at IS NULL GROUP BY currency
+) AS unpooled
+WHERE j.currency = unpooled.currency;
+
+DROP INDEX IF EXISTS idx_jackpot_ledger_unpooled;
+ALTER TABLE jackpot_ledger DROP COLUMN IF EXISTS pooled_at;
+UPDATE jackpot_ledger SET pool_after = 0 WHERE pool_after IS NULL;
+ALTER TABLE jackpot_ledger ALTER COLUMN pool_after SET NOT NULL;
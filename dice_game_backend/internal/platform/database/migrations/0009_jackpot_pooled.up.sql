@@ -0,0 +1,13 @@
+-- Contributions are inserted into the ledger without touching their pool, so
+-- rounds never queue on the pool row. pooled_at records when an entry was
+-- added to jackpots.amount: seeds, payouts and a winning round's own
+-- contribution are pooled as they are written, and other contributions are
+-- rolled into the pool in the background. pool_after is only known for
+-- entries pooled as they are written.
+ALTER TABLE jackpot_ledger ALTER COLUMN pool_after DROP NOT NULL;
+ALTER TABLE jackpot_ledger ADD COLUMN IF NOT EXISTS pooled_at TIMESTAMPTZ;
+
+-- Every entry written before this migration already changed its pool.
+UPDATE jackpot_ledger SET pooled_at = created_at WHERE pooled_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_jackpot_ledger_unpooled ON jackpot_ledger(currency) WHERE pooled_at IS NULL;
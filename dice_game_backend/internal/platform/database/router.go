@@ -0,0 +1,172 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Router holds the primary pool and an optional read replica. Writes and
+// reads that must see the latest commit always use the primary; reads whose
+// context was marked with AllowStale go to the replica while it keeps up.
+type Router struct {
+	primary *pgxpool.Pool
+	replica *pgxpool.Pool
+	maxLag  time.Duration
+
+	// usable is false while the replica is unreachable or lags more than maxLag.
+	usable atomic.Bool
+	// lag is the last measured replication lag in nanoseconds.
+	lag atomic.Int64
+}
+
+// ReplicaStatus describes the replica for health reporting.
+type ReplicaStatus struct {
+	Enabled bool          `json:"enabled"`
+	Usable  bool          `json:"usable"`
+	Lag     time.Duration `json:"lagNs"`
+	MaxLag  time.Duration `json:"maxLagNs"`
+}
+
+type staleKey struct{}
+
+// AllowStale marks ctx as tolerating reads up to the replica's max lag behind the primary.
+func AllowStale(ctx context.Context) context.Context {
+	return context.WithValue(ctx, staleKey{}, true)
+}
+
+func staleAllowed(ctx context.Context) bool {
+	allowed, _ := ctx.Value(staleKey{}).(bool)
+	return allowed
+}
+
+// NewRouter routes between primary and replica. A nil replica sends everything to the primary.
+func NewRouter(primary, replica *pgxpool.Pool, maxLag time.Duration) *Router {
+	if primary == nil {
+		log.Fatal("primary pool is nil in database.NewRouter")
+	}
+	r := &Router{primary: primary, replica: replica, maxLag: maxLag}
+	r.usable.Store(replica != nil)
+	return r
+}
+
+// Primary returns the pool for writes and read-your-writes reads.
+func (r *Router) Primary() *pgxpool.Pool {
+	return r.primary
+}
+
+// Reader returns the pool a read on ctx should use. Prefer Read, which also
+// retries on the primary; use Reader for streaming reads that cannot be retried.
+func (r *Router) Reader(ctx context.Context) *pgxpool.Pool {
+	if r.replica != nil && staleAllowed(ctx) && r.usable.Load() {
+		return r.replica
+	}
+	return r.primary
+}
+
+// Read runs fn on the pool chosen by Reader. If the replica fails, or has not
+// replicated the row yet, fn is retried on the primary, so fn must not have
+// side effects beyond scanning its result.
+func (r *Router) Read(ctx context.Context, fn func(db *pgxpool.Pool) error) error {
+	db := r.Reader(ctx)
+	err := fn(db)
+	if err == nil || db == r.primary || ctx.Err() != nil {
+		return err
+	}
+
+	// A PgError means the replica answered; anything else is a connection problem.
+	var pgErr *pgconn.PgError
+	if !errors.Is(err, pgx.ErrNoRows) && !errors.As(err, &pgErr) {
+		r.markUnusable("replica read failed: %v", err)
+	}
+	return fn(r.primary)
+}
+
+// Status reports the replica's state.
+func (r *Router) Status() ReplicaStatus {
+	return ReplicaStatus{
+		Enabled: r.replica != nil,
+		Usable:  r.replica != nil && r.usable.Load(),
+		Lag:     time.Duration(r.lag.Load()),
+		MaxLag:  r.maxLag,
+	}
+}
+
+// MonitorReplica measures replication lag every interval until ctx is done,
+// taking the replica out of rotation while it lags more than the max lag, is
+// not streaming from the primary or cannot be reached, and back in once it catches up.
+func (r *Router) MonitorReplica(ctx context.Context, interval, timeout time.Duration) {
+	if r.replica == nil {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		checkCtx, cancel := context.WithTimeout(ctx, timeout)
+		lag, err := replicationLag(checkCtx, r.replica)
+		cancel()
+		switch {
+		case ctx.Err() != nil:
+			return
+		case errors.Is(err, errNotStreaming):
+			r.markUnusable("replica %v", err)
+		case err != nil:
+			r.markUnusable("replica lag check failed: %v", err)
+		case lag > r.maxLag:
+			r.lag.Store(int64(lag))
+			r.markUnusable("replica lags %v behind the primary (max %v)", lag.Round(time.Millisecond), r.maxLag)
+		default:
+			r.lag.Store(int64(lag))
+			if r.usable.CompareAndSwap(false, true) {
+				log.Printf("Read replica back in rotation (lag %v).", lag.Round(time.Millisecond))
+			}
+		}
+	}
+}
+
+func (r *Router) markUnusable(format string, args ...any) {
+	if r.usable.CompareAndSwap(true, false) {
+		log.Printf("WARN: Read replica out of rotation, stale-tolerant reads go to the primary: "+format, args...)
+	}
+}
+
+// errNotStreaming reports a replica whose WAL receiver is not streaming from
+// the primary, so it falls behind without its lag showing.
+var errNotStreaming = errors.New("WAL receiver is not streaming from the primary")
+
+// replicationLag is how far the replica's replay trails the primary. A replica
+// that has replayed everything it received counts as caught up even if the
+// primary has been idle since the last replayed transaction, but only while
+// its WAL receiver is streaming: a disconnected receiver also leaves nothing
+// to replay.
+func replicationLag(ctx context.Context, replica *pgxpool.Pool) (time.Duration, error) {
+	var recovering, streaming bool
+	var seconds float64
+	err := replica.QueryRow(ctx, `
+		SELECT pg_is_in_recovery(),
+			EXISTS (SELECT 1 FROM pg_stat_wal_receiver WHERE status = 'streaming'),
+			CASE
+				WHEN NOT pg_is_in_recovery() THEN 0
+				WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+				ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+			END::float8`).Scan(&recovering, &streaming, &seconds)
+	if err != nil {
+		return 0, err
+	}
+	if recovering && !streaming {
+		return 0, errNotStreaming
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
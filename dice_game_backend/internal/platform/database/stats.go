@@ -0,0 +1,67 @@
+package database
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PoolStats is a snapshot of connection pool usage.
+type PoolStats struct {
+	MaxConns      int32 `json:"maxConns"`
+	TotalConns    int32 `json:"totalConns"`
+	AcquiredConns int32 `json:"acquiredConns"`
+	IdleConns     int32 `json:"idleConns"`
+	// AcquireCount counts successful acquires; EmptyAcquireCount those that had
+	// to wait because no connection was free.
+	AcquireCount         int64         `json:"acquireCount"`
+	EmptyAcquireCount    int64         `json:"emptyAcquireCount"`
+	CanceledAcquireCount int64         `json:"canceledAcquireCount"`
+	AcquireDuration      time.Duration `json:"acquireDurationNs"`
+}
+
+// Stats returns the current pool usage.
+func Stats(pool *pgxpool.Pool) PoolStats {
+	s := pool.Stat()
+	return PoolStats{
+		MaxConns:             s.MaxConns(),
+		TotalConns:           s.TotalConns(),
+		AcquiredConns:        s.AcquiredConns(),
+		IdleConns:            s.IdleConns(),
+		AcquireCount:         s.AcquireCount(),
+		EmptyAcquireCount:    s.EmptyAcquireCount(),
+		CanceledAcquireCount: s.CanceledAcquireCount(),
+		AcquireDuration:      s.AcquireDuration(),
+	}
+}
+
+// MonitorPool logs a warning every interval in which callers had to wait for
+// a connection, with the average wait, until ctx is done.
+func MonitorPool(ctx context.Context, pool *pgxpool.Pool, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	prev := Stats(pool)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		cur := Stats(pool)
+		waited := cur.EmptyAcquireCount - prev.EmptyAcquireCount
+		canceled := cur.CanceledAcquireCount - prev.CanceledAcquireCount
+		if waited > 0 || canceled > 0 {
+			acquires := cur.AcquireCount - prev.AcquireCount
+			var avg time.Duration
+			if acquires > 0 {
+				avg = (cur.AcquireDuration - prev.AcquireDuration) / time.Duration(acquires)
+			}
+			log.Printf("WARN: Database pool saturated: %d of %d acquires waited for a connection, %d canceled, avg acquire %v (%d/%d in use)",
+				waited, acquires, canceled, avg, cur.AcquiredConns, cur.MaxConns)
+		}
+		prev = cur
+	}
+}
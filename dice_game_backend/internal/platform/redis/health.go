@@ -0,0 +1,84 @@
+package redis
+
+import (
+	"context"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Startup modes decide what happens when Redis cannot be reached at boot.
+const (
+	// StartupFail exits, as a hard dependency would.
+	StartupFail = "fail"
+	// StartupWait retries until Redis answers before serving.
+	StartupWait = "wait"
+	// StartupDegraded serves immediately and lets Health bring Redis back.
+	StartupDegraded = "degraded"
+)
+
+var StartupModes = []string{StartupFail, StartupWait, StartupDegraded}
+
+// Health tracks whether Redis is reachable. It is marked down as soon as a
+// caller sees Redis fail and marked up again only by a successful ping, so
+// callers with a fallback stop hammering a dead server.
+type Health struct {
+	client   redis.UniversalClient
+	interval time.Duration
+	timeout  time.Duration
+	healthy  atomic.Bool
+	// downSince is the Unix nanosecond time Redis was marked down, 0 while healthy.
+	downSince atomic.Int64
+}
+
+// NewHealth creates a tracker with the given initial state. Call Run to start pinging.
+func NewHealth(client redis.UniversalClient, interval, timeout time.Duration, healthy bool) *Health {
+	h := &Health{client: client, interval: interval, timeout: timeout}
+	h.healthy.Store(healthy)
+	if !healthy {
+		h.downSince.Store(time.Now().UnixNano())
+	}
+	return h
+}
+
+// Healthy reports whether Redis was reachable at the last check.
+func (h *Health) Healthy() bool {
+	return h.healthy.Load()
+}
+
+// MarkDown records a Redis failure seen by a caller.
+func (h *Health) MarkDown(err error) {
+	if h.healthy.CompareAndSwap(true, false) {
+		h.downSince.Store(time.Now().UnixNano())
+		log.Printf("WARN: Redis unavailable, switching to degraded mode: %v", err)
+	}
+}
+
+// Run pings Redis every interval until ctx is done, updating the state.
+func (h *Health) Run(ctx context.Context) {
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		pingCtx, cancel := context.WithTimeout(ctx, h.timeout)
+		err := h.client.Ping(pingCtx).Err()
+		cancel()
+		if err != nil {
+			if ctx.Err() == nil {
+				h.MarkDown(err)
+			}
+			continue
+		}
+		if h.healthy.CompareAndSwap(false, true) {
+			down := time.Duration(time.Now().UnixNano() - h.downSince.Swap(0))
+			log.Printf("Redis recovered after %v, leaving degraded mode.", down.Round(time.Second))
+		}
+	}
+}
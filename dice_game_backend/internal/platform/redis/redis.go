@@ -2,38 +2,79 @@ package redis
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"log"
-	"strconv"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/go-redis/redis/v8"
 )
 
+// Deployment modes.
+const (
+	ModeStandalone = "standalone"
+	ModeSentinel   = "sentinel"
+	ModeCluster    = "cluster"
+)
+
+var Modes = []string{ModeStandalone, ModeSentinel, ModeCluster}
+
 type Config struct {
-	Addr     string
+	// Mode selects a single node, a Sentinel-managed primary or a Cluster.
+	Mode string
+	// Addrs is the node address in standalone mode, otherwise the seed list of
+	// Sentinel or Cluster nodes.
+	Addrs []string
+	// MasterName is the Sentinel primary name. Sentinel mode only.
+	MasterName string
+	// Username and Password authenticate against Redis 6 ACLs. An empty
+	// Username uses the default user.
+	Username string
 	Password string
-	DB       string
+	// SentinelUsername and SentinelPassword authenticate against the Sentinels
+	// themselves, when they require it.
+	SentinelUsername string
+	SentinelPassword string
+	// DB is the database index. Cluster mode only supports 0.
+	DB int
+
+	TLS TLSConfig
+
+	// Pool sizing and per-command timeouts.
+	PoolSize     int
+	MinIdleConns int
+	DialTimeout  time.Duration
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	// PoolTimeout is how long a command waits for a free connection.
+	PoolTimeout time.Duration
+	// ConnectTimeout bounds the initial ping at startup.
+	ConnectTimeout time.Duration
+	// StartupMode is one of StartupModes.
+	StartupMode string
+	// HealthCheckInterval is how often Redis is pinged to detect outages and recovery.
+	HealthCheckInterval time.Duration
 }
 
-func ConnectRedis(ctx context.Context, cfg Config) (*redis.Client, error) {
-	log.Printf("Connecting to Redis at %s, DB %s", cfg.Addr, cfg.DB)
+// TLSConfig enables TLS to Redis. CAFile verifies servers signed by a private
+// CA instead of the system roots; ServerName overrides the name checked in
+// the server certificate.
+type TLSConfig struct {
+	Enabled    bool
+	CAFile     string
+	ServerName string
+}
 
-	dbIndex, err := strconv.Atoi(cfg.DB)
+// ConnectRedis builds the client for the configured mode and verifies it with a ping.
+func ConnectRedis(ctx context.Context, cfg Config) (redis.UniversalClient, error) {
+	rdb, err := NewClient(cfg)
 	if err != nil {
-		log.Printf("Invalid Redis DB index '%s', using default 0. Error: %v", cfg.DB, err)
-		dbIndex = 0
+		return nil, err
 	}
 
-	rdb := redis.NewClient(&redis.Options{
-		Addr:         cfg.Addr,
-		Password:     cfg.Password,
-		DB:           dbIndex,
-		PoolSize:     10,
-		ReadTimeout:  3 * time.Second,
-		WriteTimeout: 3 * time.Second,
-	})
-
 	statusCmd := rdb.Ping(ctx)
 	if err := statusCmd.Err(); err != nil {
 		_ = rdb.Close()
@@ -44,3 +85,71 @@ func ConnectRedis(ctx context.Context, cfg Config) (*redis.Client, error) {
 	log.Printf("Connected to Redis: %s", statusCmd.Val())
 	return rdb, nil
 }
+
+// NewClient builds the client for the configured mode without contacting
+// Redis; connections are opened on first use.
+func NewClient(cfg Config) (redis.UniversalClient, error) {
+	log.Printf("Connecting to Redis (%s) at %s, DB %d, TLS %t", cfg.Mode, strings.Join(cfg.Addrs, ","), cfg.DB, cfg.TLS.Enabled)
+
+	tlsConfig, err := cfg.TLS.build()
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &redis.UniversalOptions{
+		Addrs:            cfg.Addrs,
+		MasterName:       cfg.MasterName,
+		DB:               cfg.DB,
+		Username:         cfg.Username,
+		Password:         cfg.Password,
+		SentinelUsername: cfg.SentinelUsername,
+		SentinelPassword: cfg.SentinelPassword,
+		PoolSize:         cfg.PoolSize,
+		MinIdleConns:     cfg.MinIdleConns,
+		DialTimeout:      cfg.DialTimeout,
+		ReadTimeout:      cfg.ReadTimeout,
+		WriteTimeout:     cfg.WriteTimeout,
+		PoolTimeout:      cfg.PoolTimeout,
+		TLSConfig:        tlsConfig,
+	}
+
+	// The mode is explicit rather than inferred from the options as
+	// redis.NewUniversalClient does, so a one-node seed list can still be a Cluster.
+	var rdb redis.UniversalClient
+	switch cfg.Mode {
+	case ModeStandalone:
+		rdb = redis.NewClient(opts.Simple())
+	case ModeSentinel:
+		rdb = redis.NewFailoverClient(opts.Failover())
+	case ModeCluster:
+		rdb = redis.NewClusterClient(opts.Cluster())
+	default:
+		return nil, fmt.Errorf("unknown Redis mode %q", cfg.Mode)
+	}
+	log.Printf("Redis pool config: PoolSize=%d, MinIdleConns=%d, DialTimeout=%v, ReadTimeout=%v, WriteTimeout=%v, PoolTimeout=%v",
+		cfg.PoolSize, cfg.MinIdleConns, cfg.DialTimeout, cfg.ReadTimeout, cfg.WriteTimeout, cfg.PoolTimeout)
+	return rdb, nil
+}
+
+// build returns the tls.Config for the client, or nil when TLS is disabled.
+func (t TLSConfig) build() (*tls.Config, error) {
+	if !t.Enabled {
+		return nil, nil
+	}
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: t.ServerName,
+	}
+	if t.CAFile != "" {
+		pem, err := os.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Redis CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no PEM certificates found in Redis CA file %s", t.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	return tlsConfig, nil
+}
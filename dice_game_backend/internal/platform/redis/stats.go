@@ -0,0 +1,75 @@
+package redis
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// PoolStats is a snapshot of connection pool usage.
+type PoolStats struct {
+	PoolSize   int    `json:"poolSize"`
+	TotalConns uint32 `json:"totalConns"`
+	IdleConns  uint32 `json:"idleConns"`
+	StaleConns uint32 `json:"staleConns"`
+	// Hits and Misses count commands that found a free connection or had to
+	// open one; Timeouts those that gave up waiting for one.
+	Hits     uint32 `json:"hits"`
+	Misses   uint32 `json:"misses"`
+	Timeouts uint32 `json:"timeouts"`
+}
+
+// Stats returns the current pool usage. For a Cluster the counters are summed
+// over every node's pool and PoolSize is the per-node limit.
+func Stats(client redis.UniversalClient) PoolStats {
+	s := client.PoolStats()
+	return PoolStats{
+		PoolSize:   poolSize(client),
+		TotalConns: s.TotalConns,
+		IdleConns:  s.IdleConns,
+		StaleConns: s.StaleConns,
+		Hits:       s.Hits,
+		Misses:     s.Misses,
+		Timeouts:   s.Timeouts,
+	}
+}
+
+// MonitorPool logs a warning every interval in which commands timed out
+// waiting for a connection or the pool ran out of idle connections, until ctx is done.
+func MonitorPool(ctx context.Context, client redis.UniversalClient, interval time.Duration) {
+	_, cluster := client.(*redis.ClusterClient)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	prev := Stats(client)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		cur := Stats(client)
+		timeouts := cur.Timeouts - prev.Timeouts
+		// Summed cluster counters cannot tell which node's pool is full.
+		exhausted := !cluster && int(cur.TotalConns) >= cur.PoolSize && cur.IdleConns == 0
+		if timeouts > 0 || exhausted {
+			log.Printf("WARN: Redis pool saturated: %d pool timeouts, %d/%d connections open, %d idle",
+				timeouts, cur.TotalConns, cur.PoolSize, cur.IdleConns)
+		}
+		prev = cur
+	}
+}
+
+func poolSize(client redis.UniversalClient) int {
+	switch c := client.(type) {
+	case *redis.Client:
+		return c.Options().PoolSize
+	case *redis.ClusterClient:
+		return c.Options().PoolSize
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,201 @@
+// Package playlock serialises the plays of each player. Locks normally live in
+// Redis; when Redis is unavailable a Postgres advisory lock can stand in so
+// that an outage of the cache tier does not stop the game.
+package playlock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/BrunoSena97/dice_game_backend/internal/constants"
+	redisPlatform "github.com/BrunoSena97/dice_game_backend/internal/platform/redis"
+	"github.com/go-redis/redis/v8"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Fallbacks used when Redis is unavailable.
+const (
+	FallbackPostgres = "postgres"
+	FallbackNone     = "none"
+)
+
+var Fallbacks = []string{FallbackPostgres, FallbackNone}
+
+// advisoryNamespace is the first key of the two-key advisory lock form, keeping
+// play locks apart from other advisory locks in the database.
+const advisoryNamespace int32 = 0x706c6179 // "play"
+
+// Lock is a held play lock.
+type Lock interface {
+	// Release frees the lock. An error means it may still be held until it expires.
+	Release() error
+	// Backend names where the lock lives, for logging.
+	Backend() string
+}
+
+// Locker takes per-player play locks.
+type Locker interface {
+	// Acquire takes userID's lock. ok is false, without error, when another play holds it.
+	Acquire(ctx context.Context, userID string) (lock Lock, ok bool, err error)
+}
+
+// RedisLocker holds locks as expiring keys, so a crashed replica's locks free themselves.
+type RedisLocker struct {
+	client         redis.Cmdable
+	ttl            time.Duration
+	releaseTimeout time.Duration
+}
+
+func NewRedisLocker(client redis.Cmdable, ttl, releaseTimeout time.Duration) *RedisLocker {
+	if client == nil {
+		log.Fatal("RedisClient is nil in playlock.NewRedisLocker")
+	}
+	return &RedisLocker{client: client, ttl: ttl, releaseTimeout: releaseTimeout}
+}
+
+func (l *RedisLocker) Acquire(ctx context.Context, userID string) (Lock, bool, error) {
+	key := constants.RedisKeyPrefixActivePlay + userID
+	wasSet, err := l.client.SetNX(ctx, key, "locked", l.ttl).Result()
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			log.Printf("Redis lock acquisition timed out for key %s", key)
+		}
+		return nil, false, fmt.Errorf("redis SetNX error for key %s: %w", key, err)
+	}
+	if !wasSet {
+		return nil, false, nil
+	}
+	log.Printf("DEBUG: Acquired active_play lock: %s", key)
+	return &redisLock{locker: l, key: key}, true, nil
+}
+
+type redisLock struct {
+	locker *RedisLocker
+	key    string
+}
+
+func (k *redisLock) Backend() string { return "redis" }
+
+// Release deletes the key. A key that already expired counts as released.
+func (k *redisLock) Release() error {
+	delCtx, delCancel := context.WithTimeout(context.Background(), k.locker.releaseTimeout)
+	defer delCancel()
+
+	deletedCount, err := k.locker.client.Del(delCtx, k.key).Result()
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			log.Printf("Redis lock deletion timed out for key %s", k.key)
+		}
+		return fmt.Errorf("redis DEL error for key %s: %w", k.key, err)
+	}
+	if deletedCount > 0 {
+		log.Printf("DEBUG: Released active_play lock: %s", k.key)
+	} else {
+		log.Printf("DEBUG: Attempted to release lock %s, but key did not exist (DEL returned 0 or lock expired).", k.key)
+	}
+	return nil
+}
+
+// PostgresLocker holds locks as session-level advisory locks. Each held lock
+// pins one pool connection until released; if the connection dies, Postgres
+// frees the lock with it. Give it a pool of its own (see
+// database.Config.PlayLocks), so held locks cannot starve other queries.
+type PostgresLocker struct {
+	pool           *pgxpool.Pool
+	releaseTimeout time.Duration
+}
+
+func NewPostgresLocker(pool *pgxpool.Pool, releaseTimeout time.Duration) *PostgresLocker {
+	if pool == nil {
+		log.Fatal("DB pool is nil in playlock.NewPostgresLocker")
+	}
+	return &PostgresLocker{pool: pool, releaseTimeout: releaseTimeout}
+}
+
+func (l *PostgresLocker) Acquire(ctx context.Context, userID string) (Lock, bool, error) {
+	conn, err := l.pool.Acquire(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to acquire connection for play lock: %w", err)
+	}
+
+	var locked bool
+	err = conn.QueryRow(ctx, `SELECT pg_try_advisory_lock($1, hashtext($2))`, advisoryNamespace, userID).Scan(&locked)
+	if err != nil {
+		conn.Release()
+		return nil, false, fmt.Errorf("pg_try_advisory_lock error for user %s: %w", userID, err)
+	}
+	if !locked {
+		conn.Release()
+		return nil, false, nil
+	}
+	log.Printf("DEBUG: Acquired Postgres play lock for %s", userID)
+	return &postgresLock{locker: l, conn: conn, userID: userID}, true, nil
+}
+
+type postgresLock struct {
+	locker *PostgresLocker
+	conn   *pgxpool.Conn
+	userID string
+}
+
+func (k *postgresLock) Backend() string { return "postgres" }
+
+// Release unlocks and returns the connection to the pool. If unlocking fails
+// the connection is closed instead, which frees the lock server-side.
+func (k *postgresLock) Release() error {
+	ctx, cancel := context.WithTimeout(context.Background(), k.locker.releaseTimeout)
+	defer cancel()
+
+	var unlocked bool
+	err := k.conn.QueryRow(ctx, `SELECT pg_advisory_unlock($1, hashtext($2))`, advisoryNamespace, k.userID).Scan(&unlocked)
+	if err != nil || !unlocked {
+		closeCtx, closeCancel := context.WithTimeout(context.Background(), k.locker.releaseTimeout)
+		_ = k.conn.Conn().Close(closeCtx)
+		closeCancel()
+		k.conn.Release()
+		if err == nil {
+			err = errors.New("lock was not held")
+		}
+		return fmt.Errorf("pg_advisory_unlock error for user %s, connection closed: %w", k.userID, err)
+	}
+	k.conn.Release()
+	log.Printf("DEBUG: Released Postgres play lock for %s", k.userID)
+	return nil
+}
+
+// FailoverLocker uses the Redis locker while Redis is healthy and the fallback
+// otherwise. A failing Redis lock marks Redis down and the same play retries on
+// the fallback, so players never see the outage. Once the health check sees
+// Redis answer again new plays go back to Redis.
+//
+// During a switch, replicas can briefly disagree about Redis health and take
+// a player's lock in different places. The wallet's conditional debit still
+// keeps balances consistent; only the one-play-at-a-time guarantee is relaxed.
+type FailoverLocker struct {
+	primary  Locker
+	fallback Locker
+	health   *redisPlatform.Health
+}
+
+// NewFailoverLocker returns a locker that falls back when health reports Redis
+// down. A nil fallback disables failover and returns primary's errors.
+func NewFailoverLocker(primary, fallback Locker, health *redisPlatform.Health) *FailoverLocker {
+	if primary == nil || health == nil {
+		log.Fatal("primary locker and health are required in playlock.NewFailoverLocker")
+	}
+	return &FailoverLocker{primary: primary, fallback: fallback, health: health}
+}
+
+func (l *FailoverLocker) Acquire(ctx context.Context, userID string) (Lock, bool, error) {
+	if l.fallback == nil || l.health.Healthy() {
+		lock, ok, err := l.primary.Acquire(ctx, userID)
+		if err == nil || l.fallback == nil || ctx.Err() != nil {
+			return lock, ok, err
+		}
+		l.health.MarkDown(err)
+	}
+	return l.fallback.Acquire(ctx, userID)
+}
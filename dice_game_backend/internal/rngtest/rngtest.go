@@ -0,0 +1,191 @@
+// Package rngtest provides statistical tests of dice fairness: chi-square
+// goodness-of-fit on die faces and on the two-dice sum, lag-1 serial
+// correlation, and a Wald-Wolfowitz runs test. Each test returns a p-value;
+// a fair generator yields p-values uniformly distributed on [0, 1].
+package rngtest
+
+import (
+	"fmt"
+	"math"
+)
+
+// Roller is a source of two-dice rolls, such as game.Service.
+type Roller interface {
+	RollDice() (int, int)
+}
+
+// Result is the outcome of one statistical test.
+type Result struct {
+	Name      string
+	Statistic float64
+	PValue    float64
+}
+
+func (r Result) String() string {
+	return fmt.Sprintf("%-20s statistic=%12.4f p=%.6f", r.Name, r.Statistic, r.PValue)
+}
+
+// Sample rolls n pairs and returns them as pairs.
+func Sample(r Roller, n int) [][2]int {
+	rolls := make([][2]int, n)
+	for i := range rolls {
+		d1, d2 := r.RollDice()
+		rolls[i] = [2]int{d1, d2}
+	}
+	return rolls
+}
+
+// Faces flattens pairs into the sequence of individual die faces.
+func Faces(rolls [][2]int) []int {
+	faces := make([]int, 0, 2*len(rolls))
+	for _, r := range rolls {
+		faces = append(faces, r[0], r[1])
+	}
+	return faces
+}
+
+// Suite runs every test on rolls.
+func Suite(rolls [][2]int) []Result {
+	faces := Faces(rolls)
+	return []Result{
+		FaceChiSquare(faces),
+		SumChiSquare(rolls),
+		SerialCorrelation(faces),
+		Runs(faces),
+	}
+}
+
+// FaceChiSquare tests that every face from 1 to 6 is equally likely.
+func FaceChiSquare(faces []int) Result {
+	observed := make([]float64, 6)
+	for _, f := range faces {
+		observed[f-1]++
+	}
+	expected := make([]float64, 6)
+	for i := range expected {
+		expected[i] = float64(len(faces)) / 6
+	}
+	stat := chiSquare(observed, expected)
+	return Result{Name: "chi-square faces", Statistic: stat, PValue: chiSquarePValue(stat, 5)}
+}
+
+// SumChiSquare tests the sums 2 to 12 against the triangular distribution of two fair dice.
+func SumChiSquare(rolls [][2]int) Result {
+	observed := make([]float64, 11)
+	for _, r := range rolls {
+		observed[r[0]+r[1]-2]++
+	}
+	expected := make([]float64, 11)
+	for i := range expected {
+		sum := i + 2
+		ways := 6 - math.Abs(float64(sum-7))
+		expected[i] = float64(len(rolls)) * ways / 36
+	}
+	stat := chiSquare(observed, expected)
+	return Result{Name: "chi-square sums", Statistic: stat, PValue: chiSquarePValue(stat, 10)}
+}
+
+// SerialCorrelation tests that consecutive faces are uncorrelated. The lag-1
+// coefficient is approximately normal with mean -1/n and variance 1/n.
+func SerialCorrelation(faces []int) Result {
+	n := float64(len(faces))
+	var mean float64
+	for _, f := range faces {
+		mean += float64(f)
+	}
+	mean /= n
+
+	var num, den float64
+	for i, f := range faces {
+		d := float64(f) - mean
+		den += d * d
+		if i+1 < len(faces) {
+			num += d * (float64(faces[i+1]) - mean)
+		}
+	}
+	r := num / den
+	z := (r + 1/n) * math.Sqrt(n)
+	return Result{Name: "serial correlation", Statistic: r, PValue: math.Erfc(math.Abs(z) / math.Sqrt2)}
+}
+
+// Runs counts runs of low (1-3) and high (4-6) faces and compares the count
+// with its expectation under independence (Wald-Wolfowitz).
+func Runs(faces []int) Result {
+	var high, runs float64
+	for i, f := range faces {
+		if f >= 4 {
+			high++
+		}
+		if i == 0 || (f >= 4) != (faces[i-1] >= 4) {
+			runs++
+		}
+	}
+	n := float64(len(faces))
+	low := n - high
+	mu := 2*high*low/n + 1
+	variance := (mu - 1) * (mu - 2) / (n - 1)
+	z := (runs - mu) / math.Sqrt(variance)
+	return Result{Name: "runs", Statistic: z, PValue: math.Erfc(math.Abs(z) / math.Sqrt2)}
+}
+
+func chiSquare(observed, expected []float64) float64 {
+	var stat float64
+	for i := range observed {
+		d := observed[i] - expected[i]
+		stat += d * d / expected[i]
+	}
+	return stat
+}
+
+// chiSquarePValue is the upper-tail probability of the chi-square distribution.
+func chiSquarePValue(stat float64, df int) float64 {
+	return gammaQ(float64(df)/2, stat/2)
+}
+
+// gammaQ is the regularized upper incomplete gamma function Q(a, x), computed
+// by series expansion below a+1 and by continued fraction above it.
+func gammaQ(a, x float64) float64 {
+	if x <= 0 {
+		return 1
+	}
+	lgamma, _ := math.Lgamma(a)
+	prefix := math.Exp(-x + a*math.Log(x) - lgamma)
+
+	if x < a+1 {
+		sum, term := 1/a, 1/a
+		for n := 1; n < 1000; n++ {
+			term *= x / (a + float64(n))
+			sum += term
+			if math.Abs(term) < math.Abs(sum)*1e-15 {
+				break
+			}
+		}
+		return 1 - sum*prefix
+	}
+
+	// Modified Lentz's method.
+	const tiny = 1e-300
+	b := x + 1 - a
+	c := 1 / tiny
+	d := 1 / b
+	h := d
+	for i := 1; i < 1000; i++ {
+		an := -float64(i) * (float64(i) - a)
+		b += 2
+		d = an*d + b
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = b + an/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		delta := d * c
+		h *= delta
+		if math.Abs(delta-1) < 1e-15 {
+			break
+		}
+	}
+	return prefix * h
+}
@@ -0,0 +1,108 @@
+package rngtest
+
+import (
+	"math"
+	"testing"
+
+	"github.com/BrunoSena97/dice_game_backend/internal/game"
+)
+
+// alpha is the significance level. With four tests per run, a fair generator
+// fails spuriously about once in 2,500 runs.
+const alpha = 1e-4
+
+const sampleRolls = 500_000
+
+func TestGameServiceDiceAreFair(t *testing.T) {
+	if testing.Short() {
+		t.Skip("statistical suite skipped in -short mode")
+	}
+	rolls := Sample(game.NewService(), sampleRolls)
+	for _, res := range Suite(rolls) {
+		t.Log(res)
+		if res.PValue < alpha {
+			t.Errorf("%s: p=%g below %g", res.Name, res.PValue, alpha)
+		}
+	}
+}
+
+func TestSeededServiceIsReproducible(t *testing.T) {
+	seed := [32]byte{1, 2, 3}
+	a := Sample(game.NewSeededService(seed), 1000)
+	b := Sample(game.NewSeededService(seed), 1000)
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("roll %d differs between identically seeded services: %v vs %v", i, a[i], b[i])
+		}
+	}
+}
+
+func TestServicesAreIndependentlySeeded(t *testing.T) {
+	a := Sample(game.NewService(), 100)
+	b := Sample(game.NewService(), 100)
+	same := 0
+	for i := range a {
+		if a[i] == b[i] {
+			same++
+		}
+	}
+	if same == len(a) {
+		t.Fatal("two services produced identical rolls; the generator is not seeded")
+	}
+}
+
+// The tests must be able to reject generators that are obviously broken.
+
+type loadedDie struct{ n int }
+
+func (l *loadedDie) RollDice() (int, int) {
+	l.n++
+	if l.n%10 == 0 {
+		return 6, 6
+	}
+	return l.n%6 + 1, (l.n*7)%6 + 1
+}
+
+type cyclingDie struct{ n int }
+
+func (c *cyclingDie) RollDice() (int, int) {
+	c.n += 2
+	return (c.n-2)%6 + 1, (c.n-1)%6 + 1
+}
+
+func TestSuiteRejectsLoadedDice(t *testing.T) {
+	res := FaceChiSquare(Faces(Sample(&loadedDie{}, 60_000)))
+	if res.PValue >= alpha {
+		t.Errorf("loaded die passed face chi-square: %v", res)
+	}
+}
+
+func TestSuiteRejectsCyclingDice(t *testing.T) {
+	faces := Faces(Sample(&cyclingDie{}, 60_000))
+	if res := FaceChiSquare(faces); res.PValue < alpha {
+		t.Errorf("cycling die is uniform and should pass face chi-square: %v", res)
+	}
+	if res := Runs(faces); res.PValue >= alpha {
+		t.Errorf("cycling die passed runs test: %v", res)
+	}
+}
+
+func TestChiSquarePValue(t *testing.T) {
+	// Reference critical values of the chi-square distribution.
+	cases := []struct {
+		stat float64
+		df   int
+		want float64
+	}{
+		{11.0705, 5, 0.05},
+		{15.0863, 5, 0.01},
+		{18.3070, 10, 0.05},
+		{29.5883, 10, 0.001},
+		{1.6103, 5, 0.90},
+	}
+	for _, c := range cases {
+		if got := chiSquarePValue(c.stat, c.df); math.Abs(got-c.want) > 1e-4 {
+			t.Errorf("chiSquarePValue(%g, %d) = %g, want %g", c.stat, c.df, got, c.want)
+		}
+	}
+}
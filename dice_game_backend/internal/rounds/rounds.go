@@ -0,0 +1,89 @@
+// Package rounds reads settled game rounds. Rounds are written by the wallet
+// as they settle; the rounds table is the system of record that derived data
+// such as leaderboards is built from.
+package rounds
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/BrunoSena97/dice_game_backend/internal/constants"
+	"github.com/BrunoSena97/dice_game_backend/internal/platform/database"
+)
+
+// Round is one settled round.
+type Round struct {
+	ID        int64
+	UserID    string
+	Currency  string
+	BetType   string
+	BetAmount int64
+	// BonusStake is the part of BetAmount drawn from the bonus balance.
+	BonusStake int64
+	Die1       int
+	Die2       int
+	Outcome    string
+	Winnings   int64
+	// FreeRoundGrantID is the free round grant that paid the stake, zero when
+	// the player staked their own balance.
+	FreeRoundGrantID int64
+	PlayedAt         time.Time
+}
+
+// Net is the player's net result for the round: winnings on a win, minus the
+// stake on a loss. A lost free round costs the player nothing.
+func (r Round) Net() int64 {
+	if r.Outcome == constants.OutcomeWin {
+		return r.Winnings
+	}
+	if r.FreeRoundGrantID != 0 {
+		return 0
+	}
+	return -r.BetAmount
+}
+
+// Store reads rounds from PostgreSQL. Scans whose context allows
+// stale reads run on the read replica.
+type Store struct {
+	db *database.Router
+}
+
+func NewStore(db *database.Router) *Store {
+	if db == nil {
+		log.Fatal("rounds.Store requires a non-nil database router")
+	}
+	return &Store{db: db}
+}
+
+// ForEachSince streams every round played at or after since, oldest first.
+func (s *Store) ForEachSince(ctx context.Context, since time.Time, fn func(Round) error) error {
+	query := `
+		SELECT id, user_id, currency, bet_type, bet_amount, bonus_stake, die1, die2, outcome, winnings,
+			COALESCE(free_round_grant_id, 0), played_at
+		FROM rounds
+		WHERE played_at >= $1
+		ORDER BY id;
+	`
+	rows, err := s.db.Reader(ctx).Query(ctx, query, since)
+	if err != nil {
+		return fmt.Errorf("failed to query rounds: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var r Round
+		if err := rows.Scan(&r.ID, &r.UserID, &r.Currency, &r.BetType, &r.BetAmount, &r.BonusStake, &r.Die1, &r.Die2,
+			&r.Outcome, &r.Winnings, &r.FreeRoundGrantID, &r.PlayedAt); err != nil {
+			return fmt.Errorf("failed to scan round: %w", err)
+		}
+		if err := fn(r); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read rounds: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package schemagen
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+const jsonSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// JSONSchema renders the spec as a JSON Schema document. The root schema
+// accepts any client or server message; payloads live under $defs.
+func JSONSchema(spec Spec) ([]byte, error) {
+	m, err := buildModel(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	defs := make(map[string]any)
+	for _, e := range spec.Enums {
+		defs[e.Name] = map[string]any{"type": "string", "enum": e.Values}
+	}
+	for _, name := range m.order {
+		defs[name] = m.structSchema(m.structs[name])
+	}
+	defs["ClientMessage"] = m.envelopeSchema(DirectionClient)
+	defs["ServerMessage"] = m.envelopeSchema(DirectionServer)
+
+	doc := map[string]any{
+		"$schema":           jsonSchemaDraft,
+		"title":             spec.Title,
+		"x-protocolVersion": spec.ProtocolVersion,
+		"oneOf": []any{
+			map[string]any{"$ref": "#/$defs/ClientMessage"},
+			map[string]any{"$ref": "#/$defs/ServerMessage"},
+		},
+		"$defs": defs,
+	}
+
+	out, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal JSON schema: %w", err)
+	}
+	return append(out, '\n'), nil
+}
+
+func (m *model) envelopeSchema(direction string) map[string]any {
+	variants := []any{}
+	for _, msg := range m.messages(direction) {
+		variants = append(variants, map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"type":    map[string]any{"const": msg.Type},
+				"payload": map[string]any{"$ref": "#/$defs/" + reflect.TypeOf(msg.Payload).Name()},
+			},
+			"required":             []string{"type", "payload"},
+			"additionalProperties": false,
+		})
+	}
+	return map[string]any{"oneOf": variants}
+}
+
+func (m *model) structSchema(def structDef) map[string]any {
+	props := make(map[string]any)
+	required := []string{}
+	for _, f := range def.Fields {
+		if f.Enum != "" {
+			props[f.Name] = enumSchema(f)
+		} else {
+			props[f.Name] = typeSchema(f.Type)
+		}
+		if !f.Optional {
+			required = append(required, f.Name)
+		}
+	}
+	return map[string]any{
+		"type":                 "object",
+		"properties":           props,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
+
+// enumSchema references the field's enum, wrapped in an array for slice fields.
+func enumSchema(f field) map[string]any {
+	ref := map[string]any{"$ref": "#/$defs/" + f.Enum}
+	if f.Type.Kind() == reflect.Slice {
+		return map[string]any{"type": "array", "items": ref}
+	}
+	return ref
+}
+
+func typeSchema(t reflect.Type) map[string]any {
+	switch t.Kind() {
+	case reflect.Pointer:
+		inner := typeSchema(t.Elem())
+		return map[string]any{"anyOf": []any{inner, map[string]any{"type": "null"}}}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": typeSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": typeSchema(t.Elem())}
+	case reflect.Struct:
+		if t == timeType {
+			return map[string]any{"type": "string", "format": "date-time"}
+		}
+		return map[string]any{"$ref": "#/$defs/" + t.Name()}
+	default:
+		return map[string]any{}
+	}
+}
@@ -0,0 +1,188 @@
+// Package schemagen renders the WebSocket protocol, described by Go message
+// structs, as a JSON Schema document and as TypeScript definitions.
+package schemagen
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Message directions.
+const (
+	DirectionClient = "client"
+	DirectionServer = "server"
+)
+
+// Message describes one message type and the struct carried in its payload.
+type Message struct {
+	Type      string
+	Direction string
+	Payload   any
+}
+
+// Enum is a named set of string values bound to specific payload fields.
+// Fields are given as "<GoStructName>.<jsonFieldName>".
+type Enum struct {
+	Name   string
+	Values []string
+	Fields []string
+}
+
+// Spec is the complete protocol description fed to the generators.
+type Spec struct {
+	Title           string
+	ProtocolVersion int
+	Messages        []Message
+	Enums           []Enum
+}
+
+var ErrInvalidSpec = errors.New("invalid protocol spec")
+
+// field is a single exported, JSON-visible struct field.
+type field struct {
+	Name     string
+	Type     reflect.Type
+	Optional bool
+	Enum     string
+}
+
+// structDef is a payload struct after reflection.
+type structDef struct {
+	Name   string
+	Fields []field
+}
+
+// model is the reflected form of a Spec shared by both generators.
+type model struct {
+	spec    Spec
+	structs map[string]structDef
+	order   []string
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func buildModel(spec Spec) (*model, error) {
+	m := &model{spec: spec, structs: make(map[string]structDef)}
+
+	enumFields := make(map[string]string)
+	for _, e := range spec.Enums {
+		if e.Name == "" || len(e.Values) == 0 {
+			return nil, fmt.Errorf("%w: enum %q must have a name and values", ErrInvalidSpec, e.Name)
+		}
+		for _, f := range e.Fields {
+			enumFields[f] = e.Name
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, msg := range spec.Messages {
+		if msg.Direction != DirectionClient && msg.Direction != DirectionServer {
+			return nil, fmt.Errorf("%w: message %q has unknown direction %q", ErrInvalidSpec, msg.Type, msg.Direction)
+		}
+		key := msg.Direction + ":" + msg.Type
+		if seen[key] {
+			return nil, fmt.Errorf("%w: duplicate %s message %q", ErrInvalidSpec, msg.Direction, msg.Type)
+		}
+		seen[key] = true
+
+		t := reflect.TypeOf(msg.Payload)
+		if t == nil || t.Kind() != reflect.Struct {
+			return nil, fmt.Errorf("%w: payload of %q must be a struct", ErrInvalidSpec, msg.Type)
+		}
+		if err := m.addStruct(t, enumFields); err != nil {
+			return nil, err
+		}
+	}
+
+	for f := range enumFields {
+		name, _, _ := strings.Cut(f, ".")
+		if _, ok := m.structs[name]; !ok {
+			return nil, fmt.Errorf("%w: enum field %q refers to an unknown struct", ErrInvalidSpec, f)
+		}
+	}
+	return m, nil
+}
+
+func (m *model) addStruct(t reflect.Type, enumFields map[string]string) error {
+	if _, ok := m.structs[t.Name()]; ok {
+		return nil
+	}
+	def := structDef{Name: t.Name()}
+	m.structs[t.Name()] = def
+	m.order = append(m.order, t.Name())
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		f := field{
+			Name:     name,
+			Type:     sf.Type,
+			Optional: strings.Contains(opts, "omitempty") || sf.Type.Kind() == reflect.Pointer,
+			Enum:     enumFields[t.Name()+"."+name],
+		}
+		def.Fields = append(def.Fields, f)
+
+		if nested := structElem(sf.Type); nested != nil {
+			if err := m.addStruct(nested, enumFields); err != nil {
+				return err
+			}
+		}
+	}
+	m.structs[t.Name()] = def
+	return nil
+}
+
+// structElem returns the named struct type reachable through pointers,
+// slices and map values, or nil when there is none.
+func structElem(t reflect.Type) reflect.Type {
+	for {
+		switch t.Kind() {
+		case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+			t = t.Elem()
+		case reflect.Struct:
+			if t == timeType || t.Name() == "" {
+				return nil
+			}
+			return t
+		default:
+			return nil
+		}
+	}
+}
+
+// messages returns the messages for one direction sorted by type.
+func (m *model) messages(direction string) []Message {
+	var out []Message
+	for _, msg := range m.spec.Messages {
+		if msg.Direction == direction {
+			out = append(out, msg)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Type < out[j].Type })
+	return out
+}
+
+// pascalCase turns "hello_ack" into "HelloAck".
+func pascalCase(s string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(s, "_") {
+		if part == "" {
+			continue
+		}
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
@@ -0,0 +1,126 @@
+package schemagen
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// TypeScript renders the spec as TypeScript type definitions.
+func TypeScript(spec Spec) ([]byte, error) {
+	m, err := buildModel(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	b.WriteString("// Code generated by cmd/protogen. DO NOT EDIT.\n")
+	b.WriteString("// Source of truth: dice_game_backend/internal/handler.\n\n")
+	fmt.Fprintf(&b, "export const PROTOCOL_VERSION = %d;\n\n", spec.ProtocolVersion)
+
+	b.WriteString("export const MessageType = {\n")
+	for _, msg := range m.uniqueTypes() {
+		fmt.Fprintf(&b, "\t%s: '%s',\n", pascalCase(msg), msg)
+	}
+	b.WriteString("} as const;\n\n")
+	b.WriteString("export type MessageType = (typeof MessageType)[keyof typeof MessageType];\n")
+
+	for _, e := range spec.Enums {
+		fmt.Fprintf(&b, "\nexport type %s =%s;\n", e.Name, literalUnion(e.Values))
+	}
+
+	for _, name := range m.order {
+		def := m.structs[name]
+		fmt.Fprintf(&b, "\nexport interface %s {\n", def.Name)
+		for _, f := range def.Fields {
+			optional := ""
+			if f.Optional {
+				optional = "?"
+			}
+			typ := tsType(f.Type)
+			if f.Enum != "" {
+				typ = f.Enum
+				if f.Type.Kind() == reflect.Slice {
+					typ += "[]"
+				}
+			}
+			fmt.Fprintf(&b, "\t%s%s: %s;\n", f.Name, optional, typ)
+		}
+		b.WriteString("}\n")
+	}
+
+	b.WriteString("\nexport interface BaseWsMessage {\n\ttype: string;\n\tpayload: unknown;\n}\n")
+	b.WriteString("\nexport interface BaseServerMessage {\n\ttype: string;\n\tpayload: unknown;\n}\n")
+
+	for _, direction := range []string{DirectionClient, DirectionServer} {
+		fmt.Fprintf(&b, "\nexport type %sMessage =\n", pascalCase(direction))
+		msgs := m.messages(direction)
+		for i, msg := range msgs {
+			end := ""
+			if i == len(msgs)-1 {
+				end = ";"
+			}
+			fmt.Fprintf(&b, "\t| { type: '%s'; payload: %s }%s\n", msg.Type, reflect.TypeOf(msg.Payload).Name(), end)
+		}
+	}
+
+	return b.Bytes(), nil
+}
+
+// uniqueTypes returns every message type once, in spec order.
+func (m *model) uniqueTypes() []string {
+	var out []string
+	seen := make(map[string]bool)
+	for _, msg := range m.spec.Messages {
+		if !seen[msg.Type] {
+			seen[msg.Type] = true
+			out = append(out, msg.Type)
+		}
+	}
+	return out
+}
+
+// literalUnion renders values as a string literal union, one per line when long.
+// The result carries its own leading whitespace.
+func literalUnion(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = "'" + v + "'"
+	}
+	inline := strings.Join(quoted, " | ")
+	if len(inline) <= 80 {
+		return " " + inline
+	}
+	return "\n\t| " + strings.Join(quoted, "\n\t| ")
+}
+
+func tsType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return tsType(t.Elem()) + " | null"
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		inner := tsType(t.Elem())
+		if strings.Contains(inner, " ") {
+			inner = "(" + inner + ")"
+		}
+		return inner + "[]"
+	case reflect.Map:
+		return "Record<string, " + tsType(t.Elem()) + ">"
+	case reflect.Struct:
+		if t == timeType {
+			return "string"
+		}
+		return t.Name()
+	default:
+		return "unknown"
+	}
+}
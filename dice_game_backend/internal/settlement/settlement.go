@@ -0,0 +1,302 @@
+// Package settlement settles game rounds in batches. Rounds from many players
+// are queued and written together: one transaction applies every stake and
+// payout and records every round, instead of one transaction per round.
+package settlement
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/BrunoSena97/dice_game_backend/internal/wallet"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Settlement modes.
+const (
+	// ModeImmediate settles and records each round on its own, as it is played.
+	ModeImmediate = "immediate"
+	// ModeBatch settles rounds through a Batcher.
+	ModeBatch = "batch"
+)
+
+var Modes = []string{ModeImmediate, ModeBatch}
+
+// ErrStopped is returned for rounds submitted after the batcher stopped.
+var ErrStopped = errors.New("settlement batcher stopped")
+
+// Result is the outcome of settling one round. Err is one of the refusals of
+// wallet.IsRefused when the round was refused, by its wallet or the player's
+// limits; nothing was then charged or recorded.
+type Result struct {
+	wallet.SettledRound
+	Err error
+}
+
+// States of a Pending round.
+const (
+	pendingQueued int32 = iota
+	pendingClaimed
+	pendingWithdrawn
+)
+
+// Pending is a round queued for settlement.
+type Pending struct {
+	round wallet.PlayedRound
+	done  chan Result
+	// state moves from pendingQueued to pendingClaimed when a batch picks the
+	// round up, or to pendingWithdrawn when it is withdrawn first.
+	state atomic.Int32
+}
+
+// Result returns a channel that receives the round's result once its batch
+// is written. A withdrawn round never gets a result.
+func (p *Pending) Result() <-chan Result {
+	return p.done
+}
+
+// Withdraw takes the round out of the queue if no batch has picked it up yet,
+// so it will never be settled. It reports false when the round is already
+// being settled; its result then arrives within the flush timeout.
+func (p *Pending) Withdraw() bool {
+	return p.state.CompareAndSwap(pendingQueued, pendingWithdrawn)
+}
+
+// Batcher queues rounds and settles them in batches of up to maxBatch rounds,
+// flushing at most flushInterval after the first round of a batch arrived.
+type Batcher struct {
+	dbpool        *pgxpool.Pool
+	cache         wallet.BalanceCache
+	notifier      wallet.BalanceNotifier
+	maxBatch      int
+	flushInterval time.Duration
+	flushTimeout  time.Duration
+	// settle writes one batch and returns a result per round. It is
+	// settleBatch, or a stand-in in tests.
+	settle func(ctx context.Context, rounds []wallet.PlayedRound) ([]Result, error)
+
+	// closing is set when Run starts to drain; submitting counts Submit calls
+	// that got past the check and may still send to the queue.
+	mu         sync.Mutex
+	closing    bool
+	submitting sync.WaitGroup
+
+	queue   chan *Pending
+	stopped chan struct{}
+}
+
+// NewBatcher creates a batcher writing to dbpool. cache may be nil. Each flush
+// is bounded by flushTimeout. Call Run to start settling.
+func NewBatcher(dbpool *pgxpool.Pool, cache wallet.BalanceCache, notifier wallet.BalanceNotifier, maxBatch int, flushInterval, flushTimeout time.Duration) *Batcher {
+	if dbpool == nil || notifier == nil {
+		log.Fatal("dbpool and notifier are required in settlement.NewBatcher")
+	}
+	if maxBatch < 1 {
+		log.Fatalf("settlement.NewBatcher: batch size must be positive, got %d", maxBatch)
+	}
+	b := newBatcher(cache, notifier, maxBatch, flushInterval, flushTimeout)
+	b.dbpool = dbpool
+	b.settle = b.settleBatch
+	return b
+}
+
+func newBatcher(cache wallet.BalanceCache, notifier wallet.BalanceNotifier, maxBatch int, flushInterval, flushTimeout time.Duration) *Batcher {
+	return &Batcher{
+		cache:         cache,
+		notifier:      notifier,
+		maxBatch:      maxBatch,
+		flushInterval: flushInterval,
+		flushTimeout:  flushTimeout,
+		queue:         make(chan *Pending, maxBatch),
+		stopped:       make(chan struct{}),
+	}
+}
+
+// Submit queues a round for settlement. The round is settled like
+// wallet.Service.SettleRound, so it is refused unless the cash and bonus
+// balances cover the stake. Once Submit returns without error the round
+// settles unless it is withdrawn, even if ctx ends before the result is read
+// and even if the batcher is shutting down. Rounds submitted once shutdown
+// began get ErrStopped.
+func (b *Batcher) Submit(ctx context.Context, round wallet.PlayedRound) (*Pending, error) {
+	b.mu.Lock()
+	if b.closing {
+		b.mu.Unlock()
+		return nil, ErrStopped
+	}
+	b.submitting.Add(1)
+	b.mu.Unlock()
+	defer b.submitting.Done()
+
+	p := &Pending{round: round, done: make(chan Result, 1)}
+	select {
+	case b.queue <- p:
+		return p, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Run settles queued rounds until ctx is done, then settles what is left in
+// the queue and stops.
+func (b *Batcher) Run(ctx context.Context) {
+	defer close(b.stopped)
+	batch := make([]*Pending, 0, b.maxBatch)
+	timer := time.NewTimer(b.flushInterval)
+	timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			b.drain(batch)
+			log.Println("Settlement batcher stopped.")
+			return
+		case p := <-b.queue:
+			if len(batch) == 0 {
+				timer.Reset(b.flushInterval)
+			}
+			batch = append(batch, p)
+			if len(batch) < b.maxBatch {
+				continue
+			}
+			if !timer.Stop() {
+				<-timer.C
+			}
+		case <-timer.C:
+		}
+		b.flush(batch)
+		batch = batch[:0]
+	}
+}
+
+// Done is closed once Run has settled the remaining rounds and returned.
+func (b *Batcher) Done() <-chan struct{} {
+	return b.stopped
+}
+
+// drain stops accepting rounds, then settles the current batch and every
+// round that was accepted. The queue is closed once no Submit can still send
+// to it, so nothing accepted is left behind.
+func (b *Batcher) drain(batch []*Pending) {
+	b.mu.Lock()
+	b.closing = true
+	b.mu.Unlock()
+	go func() {
+		b.submitting.Wait()
+		close(b.queue)
+	}()
+
+	for p := range b.queue {
+		batch = append(batch, p)
+		if len(batch) < b.maxBatch {
+			continue
+		}
+		b.flush(batch)
+		batch = batch[:0]
+	}
+	if len(batch) > 0 {
+		b.flush(batch)
+	}
+}
+
+// flush settles a batch and resolves every round in it. Withdrawn rounds are
+// left out. A failed write fails the whole batch, and nothing in it is charged.
+func (b *Batcher) flush(queued []*Pending) {
+	batch := make([]*Pending, 0, len(queued))
+	for _, p := range queued {
+		if p.state.CompareAndSwap(pendingQueued, pendingClaimed) {
+			batch = append(batch, p)
+		}
+	}
+	if len(batch) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), b.flushTimeout)
+	defer cancel()
+	start := time.Now()
+
+	played := make([]wallet.PlayedRound, len(batch))
+	for i, p := range batch {
+		played[i] = p.round
+	}
+	results, err := b.settle(ctx, played)
+	if err != nil {
+		log.Printf("ERROR: Settlement batch of %d rounds failed, none settled: %v", len(batch), err)
+		for _, p := range batch {
+			p.done <- Result{Err: fmt.Errorf("settlement batch failed: %w", err)}
+		}
+		return
+	}
+
+	for i, p := range batch {
+		if results[i].Err == nil {
+			r := results[i].Round
+			if b.cache != nil {
+				b.cache.Set(ctx, r.UserID, r.Currency, wallet.Funds{Balance: results[i].Balance, Bonus: results[i].Bonus}, results[i].Version)
+			}
+			b.notifier.NotifyBalance(ctx, r.UserID, r.Currency, results[i].Balance)
+		}
+		p.done <- results[i]
+	}
+	log.Printf("Settled batch of %d rounds in %v", len(batch), time.Since(start).Round(time.Microsecond))
+}
+
+// settleBatch writes a batch in one transaction and one round trip for all its
+// statements, sent in lockOrder. Refused rounds get their error in their
+// Result; any other error fails the batch.
+func (b *Batcher) settleBatch(ctx context.Context, played []wallet.PlayedRound) ([]Result, error) {
+	order := lockOrder(played)
+	results := make([]Result, len(played))
+	err := pgx.BeginFunc(ctx, b.dbpool, func(tx pgx.Tx) error {
+		queued := &pgx.Batch{}
+		for _, i := range order {
+			wallet.QueueSettleRound(queued, played[i])
+		}
+		br := tx.SendBatch(ctx, queued)
+		for _, i := range order {
+			p := played[i]
+			settled, err := wallet.ScanSettledRound(br.QueryRow(), p)
+			if err != nil && !wallet.IsRefused(err) {
+				br.Close()
+				return fmt.Errorf("failed to settle round of user %s: %w", p.Round.UserID, err)
+			}
+			results[i] = Result{SettledRound: settled, Err: err}
+		}
+		return br.Close()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// lockOrder returns the indexes of played in the order their statements must
+// run. Rounds are ordered by wallet, so concurrent batches lock the wallets
+// they share in the same order and cannot deadlock. Jackpot wins go last, as
+// they also lock their pool, so a batch holding a pool waits on no wallet. The
+// sort is stable: rounds of one wallet keep their order, apart from a jackpot
+// win, and see each other's changes.
+func lockOrder(played []wallet.PlayedRound) []int {
+	order := make([]int, len(played))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		x, y := played[order[i]], played[order[j]]
+		if x.Jackpot.Wins != y.Jackpot.Wins {
+			return y.Jackpot.Wins
+		}
+		if x.Round.UserID != y.Round.UserID {
+			return x.Round.UserID < y.Round.UserID
+		}
+		return x.Round.Currency < y.Round.Currency
+	})
+	return order
+}
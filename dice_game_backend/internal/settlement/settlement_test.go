@@ -0,0 +1,216 @@
+package settlement
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/BrunoSena97/dice_game_backend/internal/rounds"
+	"github.com/BrunoSena97/dice_game_backend/internal/wallet"
+)
+
+type nopNotifier struct{}
+
+func (nopNotifier) NotifyBalance(context.Context, string, string, int64) {}
+
+// recorder stands in for the database: it settles every round and records
+// the size of each batch.
+type recorder struct {
+	mu      sync.Mutex
+	batches []int
+}
+
+func (r *recorder) settle(_ context.Context, played []wallet.PlayedRound) ([]Result, error) {
+	r.mu.Lock()
+	r.batches = append(r.batches, len(played))
+	r.mu.Unlock()
+
+	results := make([]Result, len(played))
+	for i, p := range played {
+		results[i] = Result{SettledRound: wallet.SettledRound{Round: p.Round}}
+	}
+	return results, nil
+}
+
+func (r *recorder) sizes() []int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]int(nil), r.batches...)
+}
+
+func testBatcher(t *testing.T, maxBatch int, flushInterval time.Duration) (*Batcher, *recorder) {
+	rec := &recorder{}
+	b := newBatcher(nil, nopNotifier{}, maxBatch, flushInterval, time.Second)
+	b.settle = rec.settle
+	return b, rec
+}
+
+func played(userID string) wallet.PlayedRound {
+	return wallet.PlayedRound{Round: rounds.Round{UserID: userID, Currency: "PTS", BetAmount: 1}}
+}
+
+func await(t *testing.T, p *Pending) Result {
+	t.Helper()
+	select {
+	case res := <-p.Result():
+		return res
+	case <-time.After(2 * time.Second):
+		t.Fatal("round was never resolved")
+		return Result{}
+	}
+}
+
+func TestFlushWhenBatchIsFull(t *testing.T) {
+	b, rec := testBatcher(t, 3, time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go b.Run(ctx)
+
+	var pending []*Pending
+	for _, user := range []string{"a", "b", "c"} {
+		done, err := b.Submit(ctx, played(user))
+		if err != nil {
+			t.Fatalf("Submit: %v", err)
+		}
+		pending = append(pending, done)
+	}
+	for _, done := range pending {
+		if res := await(t, done); res.Err != nil {
+			t.Fatalf("round failed: %v", res.Err)
+		}
+	}
+	if got := rec.sizes(); len(got) != 1 || got[0] != 3 {
+		t.Fatalf("batches = %v, want one full batch of 3", got)
+	}
+}
+
+func TestFlushOnTimer(t *testing.T) {
+	b, rec := testBatcher(t, 100, 20*time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go b.Run(ctx)
+
+	start := time.Now()
+	first, err := b.Submit(ctx, played("a"))
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	second, err := b.Submit(ctx, played("b"))
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	await(t, first)
+	await(t, second)
+
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("batch flushed after %v, before the 20ms flush interval", elapsed)
+	}
+	if got := rec.sizes(); len(got) != 1 || got[0] != 2 {
+		t.Fatalf("batches = %v, want one timed batch of 2", got)
+	}
+}
+
+// Every round Submit accepted must settle, even when it raced with shutdown.
+func TestShutdownSettlesEveryAcceptedRound(t *testing.T) {
+	b, _ := testBatcher(t, 4, time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	go b.Run(ctx)
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		accepted []*Pending
+	)
+	for range 32 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				done, err := b.Submit(context.Background(), played("u"))
+				if errors.Is(err, ErrStopped) {
+					return
+				}
+				if err != nil {
+					t.Errorf("Submit: %v", err)
+					return
+				}
+				mu.Lock()
+				accepted = append(accepted, done)
+				mu.Unlock()
+			}
+		}()
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	wg.Wait()
+
+	select {
+	case <-b.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("batcher did not stop")
+	}
+	for _, done := range accepted {
+		if res := await(t, done); res.Err != nil {
+			t.Fatalf("accepted round failed: %v", res.Err)
+		}
+	}
+	if _, err := b.Submit(context.Background(), played("late")); !errors.Is(err, ErrStopped) {
+		t.Fatalf("Submit after shutdown: got %v, want ErrStopped", err)
+	}
+}
+
+// A round withdrawn before its batch is written is never settled; one already
+// being written can no longer be withdrawn.
+func TestWithdraw(t *testing.T) {
+	b, rec := testBatcher(t, 2, time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go b.Run(ctx)
+
+	withdrawn, err := b.Submit(ctx, played("a"))
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	if !withdrawn.Withdraw() {
+		t.Fatal("queued round could not be withdrawn")
+	}
+	settled, err := b.Submit(ctx, played("b"))
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	if res := await(t, settled); res.Err != nil {
+		t.Fatalf("round failed: %v", res.Err)
+	}
+	if got := rec.sizes(); len(got) != 1 || got[0] != 1 {
+		t.Fatalf("batches = %v, want one batch without the withdrawn round", got)
+	}
+	if settled.Withdraw() {
+		t.Fatal("settled round was withdrawn")
+	}
+}
+
+func TestLockOrder(t *testing.T) {
+	round := func(userID, currency string, wins bool) wallet.PlayedRound {
+		return wallet.PlayedRound{
+			Round:   rounds.Round{UserID: userID, Currency: currency, BetAmount: 1},
+			Jackpot: wallet.JackpotPlay{Wins: wins},
+		}
+	}
+	played := []wallet.PlayedRound{
+		round("b", "PTS", false),
+		round("a", "PTS", true),
+		round("b", "EUR", false),
+		round("a", "PTS", false),
+		round("b", "PTS", false),
+		round("c", "PTS", true),
+		round("a", "EUR", false),
+	}
+	want := []int{6, 3, 2, 0, 4, 1, 5}
+	if got := lockOrder(played); !slices.Equal(got, want) {
+		t.Fatalf("lockOrder = %v, want %v", got, want)
+	}
+}
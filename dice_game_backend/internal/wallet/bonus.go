@@ -0,0 +1,84 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/BrunoSena97/dice_game_backend/internal/constants"
+	"github.com/jackc/pgx/v5"
+)
+
+// Bonus is a wallet's bonus balance and its wagering requirement. Stakes played
+// while WageringRequired is set count towards WageringProgress; when progress
+// reaches the requirement the bonus is released to the cash balance. A bonus
+// that is lost before then clears the requirement.
+type Bonus struct {
+	Balance          int64
+	WageringRequired int64
+	WageringProgress int64
+}
+
+// GetBonus returns the bonus state of a wallet. It is cached with the
+// balance; see GetFunds.
+func (s *Service) GetBonus(ctx context.Context, userID, currency string) (Bonus, error) {
+	funds, err := s.GetFunds(ctx, userID, currency)
+	return funds.Bonus, err
+}
+
+// GrantBonus credits amount to the bonus balance, creating the wallet if
+// needed, and raises the wagering requirement by amount times multiplier. A
+// multiplier of zero grants a bonus that is released by the next round.
+func (s *Service) GrantBonus(ctx context.Context, userID, currency string, amount, multiplier int64, reason, actor string) (Bonus, error) {
+	if amount <= 0 {
+		return Bonus{}, fmt.Errorf("%w: bonus must be positive (%d)", ErrInvalidAmount, amount)
+	}
+	if multiplier < 0 {
+		return Bonus{}, fmt.Errorf("%w: wagering multiplier must not be negative (%d)", ErrInvalidAmount, multiplier)
+	}
+	if reason == "" {
+		return Bonus{}, ErrReasonRequired
+	}
+	if actor == "" {
+		return Bonus{}, ErrActorRequired
+	}
+	if err := s.EnsureWalletExists(ctx, userID, currency); err != nil {
+		return Bonus{}, err
+	}
+
+	// The ledger row is written by the same statement, so the grant and its
+	// entry commit together.
+	query := `
+		WITH updated AS (
+			UPDATE wallets
+			SET bonus_balance = bonus_balance + $3,
+				wagering_required = wagering_required + $3 * $4,
+				version = version + 1, updated_at = NOW()
+			WHERE user_id = $1 AND currency = $2
+			RETURNING balance, bonus_balance, wagering_required, wagering_progress, version
+		), ledgered AS (
+			INSERT INTO wallet_ledger (user_id, currency, entry_type, amount, balance_after, bonus_after, reason, actor)
+			SELECT $1, $2, $5, $3, balance, bonus_balance, $6, $7 FROM updated
+		)
+		SELECT balance, bonus_balance, wagering_required, wagering_progress, version FROM updated;
+	`
+	var funds Funds
+	var version int64
+	b := &funds.Bonus
+	err := s.dbpool.QueryRow(ctx, query, userID, currency, amount, multiplier, constants.LedgerEntryBonusGrant, reason, actor).
+		Scan(&funds.Balance, &b.Balance, &b.WageringRequired, &b.WageringProgress, &version)
+	if errors.Is(err, pgx.ErrNoRows) {
+		s.known.forget(userID, currency)
+		return Bonus{}, ErrWalletNotFound
+	}
+	if err != nil {
+		log.Printf("Error granting bonus (user: %s): %v", userID, err)
+		return Bonus{}, fmt.Errorf("db error granting bonus: %w", err)
+	}
+	s.cache.Set(ctx, userID, currency, funds, version)
+
+	log.Printf("LEDGER: %s of %d %s for user %s by %s (reason: %q), bonus %d, wagering %d/%d",
+		constants.LedgerEntryBonusGrant, amount, currency, userID, actor, reason, b.Balance, b.WageringProgress, b.WageringRequired)
+	return funds.Bonus, nil
+}
@@ -0,0 +1,206 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/BrunoSena97/dice_game_backend/internal/constants"
+	redisPlatform "github.com/BrunoSena97/dice_game_backend/internal/platform/redis"
+	"github.com/go-redis/redis/v8"
+)
+
+// Funds is a wallet's cash balance and bonus state, as cached together.
+type Funds struct {
+	Balance int64
+	Bonus   Bonus
+}
+
+// BalanceCache keeps recent wallet funds so hot reads skip Postgres. Each
+// entry carries the wallet version it was read or written at, and an entry is
+// only replaced by a newer version, so a delayed write can never resurrect an
+// old balance. Implementations swallow their own errors: a failing cache only
+// costs a database read.
+type BalanceCache interface {
+	Get(ctx context.Context, userID, currency string) (funds Funds, ok bool)
+	// Set stores funds as of version.
+	Set(ctx context.Context, userID, currency string, funds Funds, version int64)
+	// Invalidate drops the cached funds for every version up to and including version.
+	Invalidate(ctx context.Context, userID, currency string, version int64)
+}
+
+// noCache is used when no cache is configured.
+type noCache struct{}
+
+func (noCache) Get(context.Context, string, string) (Funds, bool) { return Funds{}, false }
+func (noCache) Set(context.Context, string, string, Funds, int64) {}
+func (noCache) Invalidate(context.Context, string, string, int64) {}
+
+// setIfNewer stores ARGV[2] under KEYS[1], prefixed with the version ARGV[1],
+// unless the key already holds a newer version. An invalidation (ARGV[4] = 1)
+// also wins ties, so the write it follows cannot be read back. ARGV[3] is the
+// TTL in milliseconds.
+var setIfNewer = redis.NewScript(`
+local current = redis.call('GET', KEYS[1])
+if current then
+	local version = tonumber(string.match(current, '^(%d+):'))
+	local incoming = tonumber(ARGV[1])
+	if version and (version > incoming or (version == incoming and ARGV[4] ~= '1')) then
+		return 0
+	end
+end
+redis.call('SET', KEYS[1], ARGV[1] .. ':' .. ARGV[2], 'PX', ARGV[3])
+return 1
+`)
+
+// RedisBalanceCache is a BalanceCache shared by every replica. Entries are
+// stored as "version:writtenAtMs:balance:bonus:required:progress", with
+// nothing after writtenAtMs once invalidated. It is bypassed while health
+// reports Redis down.
+type RedisBalanceCache struct {
+	client redis.UniversalClient
+	ttl    time.Duration
+	health *redisPlatform.Health
+	// distrustBefore is the Unix millisecond time of the last write-through this
+	// process skipped or lost. Entries written before it may be older than the
+	// wallet and are treated as misses.
+	distrustBefore atomic.Int64
+}
+
+// NewRedisBalanceCache caches balances for ttl after their last change.
+func NewRedisBalanceCache(client redis.UniversalClient, ttl time.Duration, health *redisPlatform.Health) *RedisBalanceCache {
+	if client == nil || health == nil {
+		log.Fatal("client and health are required in wallet.NewRedisBalanceCache")
+	}
+	return &RedisBalanceCache{client: client, ttl: ttl, health: health}
+}
+
+func balanceKey(userID, currency string) string {
+	return constants.RedisKeyPrefixBalance + userID + ":" + currency
+}
+
+func (c *RedisBalanceCache) Get(ctx context.Context, userID, currency string) (Funds, bool) {
+	if !c.health.Healthy() {
+		return Funds{}, false
+	}
+	raw, err := c.client.Get(ctx, balanceKey(userID, currency)).Result()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			c.failed("read", userID, currency, err)
+		}
+		return Funds{}, false
+	}
+
+	parts := strings.SplitN(raw, ":", 3)
+	if len(parts) != 3 {
+		log.Printf("WARN: Ignoring malformed cached balance %q for user %s in %s", raw, userID, currency)
+		return Funds{}, false
+	}
+	if parts[2] == "" {
+		return Funds{}, false // invalidated
+	}
+	writtenAt, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		log.Printf("WARN: Ignoring malformed cached balance %q for user %s in %s", raw, userID, currency)
+		return Funds{}, false
+	}
+	if writtenAt < c.distrustBefore.Load() {
+		return Funds{}, false
+	}
+	fields := strings.Split(parts[2], ":")
+	values := make([]int64, len(fields))
+	for i, field := range fields {
+		values[i], err = strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			break
+		}
+	}
+	if err != nil || len(values) != 4 {
+		log.Printf("WARN: Ignoring malformed cached balance %q for user %s in %s", raw, userID, currency)
+		return Funds{}, false
+	}
+	return Funds{
+		Balance: values[0],
+		Bonus:   Bonus{Balance: values[1], WageringRequired: values[2], WageringProgress: values[3]},
+	}, true
+}
+
+func (c *RedisBalanceCache) Set(ctx context.Context, userID, currency string, funds Funds, version int64) {
+	b := funds.Bonus
+	value := fmt.Sprintf("%d:%d:%d:%d", funds.Balance, b.Balance, b.WageringRequired, b.WageringProgress)
+	c.store(ctx, userID, currency, version, value, false)
+}
+
+func (c *RedisBalanceCache) Invalidate(ctx context.Context, userID, currency string, version int64) {
+	c.store(ctx, userID, currency, version, "", true)
+}
+
+func (c *RedisBalanceCache) store(ctx context.Context, userID, currency string, version int64, funds string, invalidate bool) {
+	now := time.Now().UnixMilli()
+	if !c.health.Healthy() {
+		c.distrustBefore.Store(now)
+		return
+	}
+	flag := "0"
+	if invalidate {
+		flag = "1"
+	}
+	value := strconv.FormatInt(now, 10) + ":" + funds
+	err := setIfNewer.Run(ctx, c.client, []string{balanceKey(userID, currency)}, version, value, c.ttl.Milliseconds(), flag).Err()
+	if err != nil {
+		c.distrustBefore.Store(now)
+		c.failed("write", userID, currency, err)
+	}
+}
+
+// failed logs a cache error and, unless the caller simply ran out of time,
+// reports Redis down so later calls go straight to Postgres.
+func (c *RedisBalanceCache) failed(op, userID, currency string, err error) {
+	log.Printf("WARN: Balance cache %s failed for user %s in %s: %v", op, userID, currency, err)
+	if !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
+		c.health.MarkDown(err)
+	}
+}
+
+// maxKnownWallets bounds the known wallet set; it is cleared when full.
+const maxKnownWallets = 100_000
+
+// knownWallets remembers wallets this process has seen exist, so
+// EnsureWalletExists can skip the insert. Wallets are never deleted, so an
+// entry only goes stale if the schema is reset underneath a running server.
+type knownWallets struct {
+	mu      sync.Mutex
+	wallets map[string]struct{}
+}
+
+func newKnownWallets() *knownWallets {
+	return &knownWallets{wallets: make(map[string]struct{})}
+}
+
+func (k *knownWallets) has(userID, currency string) bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	_, ok := k.wallets[userID+":"+currency]
+	return ok
+}
+
+func (k *knownWallets) add(userID, currency string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if len(k.wallets) >= maxKnownWallets {
+		k.wallets = make(map[string]struct{})
+	}
+	k.wallets[userID+":"+currency] = struct{}{}
+}
+
+func (k *knownWallets) forget(userID, currency string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	delete(k.wallets, userID+":"+currency)
+}
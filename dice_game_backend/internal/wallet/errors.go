@@ -4,7 +4,14 @@ import "errors"
 
 // Define specific error types.
 var (
-	ErrWalletNotFound    = errors.New("wallet not found")
-	ErrInsufficientFunds = errors.New("insufficient funds")
-	ErrUpdateFailed      = errors.New("wallet balance update failed unexpectedly")
+	ErrWalletNotFound      = errors.New("wallet not found")
+	ErrInsufficientFunds   = errors.New("insufficient funds")
+	ErrUpdateFailed        = errors.New("wallet balance update failed unexpectedly")
+	ErrUnsupportedCurrency = errors.New("unsupported currency")
+	ErrInvalidAmount       = errors.New("invalid amount")
+	ErrReasonRequired      = errors.New("a reason is required")
+	ErrActorRequired       = errors.New("an actor is required")
+	// ErrFreeRoundUnavailable is returned for a free round grant that is used
+	// up, expired, revoked or not the player's.
+	ErrFreeRoundUnavailable = errors.New("free round grant unavailable")
 )
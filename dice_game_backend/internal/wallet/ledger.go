@@ -0,0 +1,149 @@
+package wallet
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/BrunoSena97/dice_game_backend/internal/constants"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// LedgerEntry records one operator balance change or bonus movement.
+type LedgerEntry struct {
+	ID           int64
+	UserID       string
+	Currency     string
+	EntryType    string
+	Amount       int64
+	BalanceAfter int64
+	BonusAfter   int64
+	Reason       string
+	Actor        string
+	CreatedAt    time.Time
+}
+
+// Deposit credits amount to the user's wallet, creating the wallet if needed.
+func (s *Service) Deposit(ctx context.Context, userID, currency string, amount int64, actor string) (int64, error) {
+	if amount <= 0 {
+		return 0, fmt.Errorf("%w: deposit must be positive (%d)", ErrInvalidAmount, amount)
+	}
+	if err := s.EnsureWalletExists(ctx, userID, currency); err != nil {
+		return 0, err
+	}
+	return s.applyLedgerChange(ctx, LedgerEntry{
+		UserID:    userID,
+		Currency:  currency,
+		EntryType: constants.LedgerEntryDeposit,
+		Amount:    amount,
+		Actor:     actor,
+	})
+}
+
+// Withdraw debits amount from the user's wallet. It fails with ErrInsufficientFunds
+// rather than taking the balance below zero.
+func (s *Service) Withdraw(ctx context.Context, userID, currency string, amount int64, actor string) (int64, error) {
+	if amount <= 0 {
+		return 0, fmt.Errorf("%w: withdrawal must be positive (%d)", ErrInvalidAmount, amount)
+	}
+	return s.applyLedgerChange(ctx, LedgerEntry{
+		UserID:    userID,
+		Currency:  currency,
+		EntryType: constants.LedgerEntryWithdraw,
+		Amount:    -amount,
+		Actor:     actor,
+	})
+}
+
+// Adjust applies a signed correction to the user's wallet. A reason is mandatory.
+func (s *Service) Adjust(ctx context.Context, userID, currency string, amount int64, reason, actor string) (int64, error) {
+	if amount == 0 {
+		return 0, fmt.Errorf("%w: adjustment must be non-zero", ErrInvalidAmount)
+	}
+	if reason == "" {
+		return 0, ErrReasonRequired
+	}
+	return s.applyLedgerChange(ctx, LedgerEntry{
+		UserID:    userID,
+		Currency:  currency,
+		EntryType: constants.LedgerEntryAdjust,
+		Amount:    amount,
+		Reason:    reason,
+		Actor:     actor,
+	})
+}
+
+// GetLedger returns the most recent ledger entries for a wallet, newest first.
+func (s *Service) GetLedger(ctx context.Context, userID, currency string, limit int) ([]LedgerEntry, error) {
+	query := `
+		SELECT id, user_id, currency, entry_type, amount, balance_after, bonus_after, reason, actor, created_at
+		FROM wallet_ledger
+		WHERE user_id = $1 AND currency = $2
+		ORDER BY id DESC
+		LIMIT $3;
+	`
+	var entries []LedgerEntry
+	err := s.db.Read(ctx, func(db *pgxpool.Pool) error {
+		rows, err := db.Query(ctx, query, userID, currency, limit)
+		if err != nil {
+			return err
+		}
+		entries, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (LedgerEntry, error) {
+			var e LedgerEntry
+			err := row.Scan(&e.ID, &e.UserID, &e.Currency, &e.EntryType, &e.Amount, &e.BalanceAfter, &e.BonusAfter, &e.Reason, &e.Actor, &e.CreatedAt)
+			return e, err
+		})
+		return err
+	})
+	if err != nil {
+		log.Printf("Error reading ledger for user %s in %s: %v", userID, currency, err)
+		return nil, fmt.Errorf("database error reading ledger for user %s: %w", userID, err)
+	}
+	return entries, nil
+}
+
+// applyLedgerChange changes the balance and records the ledger entry atomically,
+// then invalidates the cached balance so the next read comes from Postgres.
+func (s *Service) applyLedgerChange(ctx context.Context, entry LedgerEntry) (int64, error) {
+	if _, ok := s.initialBalances[entry.Currency]; !ok {
+		return 0, fmt.Errorf("%w: %s", ErrUnsupportedCurrency, entry.Currency)
+	}
+	if entry.Actor == "" {
+		return 0, ErrActorRequired
+	}
+
+	tx, err := s.dbpool.Begin(ctx)
+	if err != nil {
+		log.Printf("Error starting transaction for %s (user: %s): %v", entry.EntryType, entry.UserID, err)
+		return 0, fmt.Errorf("failed to start db transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	funds, version, err := changeBalance(ctx, tx, entry.UserID, entry.Currency, entry.Amount)
+	if err != nil {
+		return 0, err
+	}
+	newBalance := funds.Balance
+
+	queryInsert := `
+		INSERT INTO wallet_ledger (user_id, currency, entry_type, amount, balance_after, reason, actor)
+		VALUES ($1, $2, $3, $4, $5, $6, $7);
+	`
+	_, err = tx.Exec(ctx, queryInsert, entry.UserID, entry.Currency, entry.EntryType, entry.Amount, newBalance, entry.Reason, entry.Actor)
+	if err != nil {
+		log.Printf("Error inserting %s ledger entry (user: %s): %v", entry.EntryType, entry.UserID, err)
+		return 0, fmt.Errorf("db error inserting ledger entry: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Printf("Error committing %s transaction (user: %s): %v", entry.EntryType, entry.UserID, err)
+		return 0, fmt.Errorf("failed to commit db transaction: %w", err)
+	}
+	s.cache.Invalidate(ctx, entry.UserID, entry.Currency, version)
+
+	log.Printf("LEDGER: %s of %d %s for user %s by %s (reason: %q), new balance %d",
+		entry.EntryType, entry.Amount, entry.Currency, entry.UserID, entry.Actor, entry.Reason, newBalance)
+	return newBalance, nil
+}
@@ -0,0 +1,59 @@
+package wallet
+
+import "context"
+
+// BalanceNotifier is told about every committed balance change.
+type BalanceNotifier interface {
+	NotifyBalance(ctx context.Context, userID, currency string, balance int64)
+}
+
+// NotifyingService wraps a WalletService and reports each successful balance
+// change to a BalanceNotifier.
+type NotifyingService struct {
+	WalletService
+	notifier BalanceNotifier
+}
+
+// NewNotifyingService decorates inner so that balance changes are pushed to notifier.
+func NewNotifyingService(inner WalletService, notifier BalanceNotifier) *NotifyingService {
+	return &NotifyingService{WalletService: inner, notifier: notifier}
+}
+
+func (s *NotifyingService) UpdateBalance(ctx context.Context, userID, currency string, amountChange int64) (int64, error) {
+	balance, err := s.WalletService.UpdateBalance(ctx, userID, currency, amountChange)
+	return s.notify(ctx, userID, currency, balance, err)
+}
+
+func (s *NotifyingService) SettleRound(ctx context.Context, round PlayedRound) (SettledRound, error) {
+	settled, err := s.WalletService.SettleRound(ctx, round)
+	s.notify(ctx, round.Round.UserID, round.Round.Currency, settled.Balance, err)
+	return settled, err
+}
+
+func (s *NotifyingService) SettleFreeRound(ctx context.Context, round PlayedRound) (SettledRound, error) {
+	settled, err := s.WalletService.SettleFreeRound(ctx, round)
+	s.notify(ctx, round.Round.UserID, round.Round.Currency, settled.Balance, err)
+	return settled, err
+}
+
+func (s *NotifyingService) Deposit(ctx context.Context, userID, currency string, amount int64, actor string) (int64, error) {
+	balance, err := s.WalletService.Deposit(ctx, userID, currency, amount, actor)
+	return s.notify(ctx, userID, currency, balance, err)
+}
+
+func (s *NotifyingService) Withdraw(ctx context.Context, userID, currency string, amount int64, actor string) (int64, error) {
+	balance, err := s.WalletService.Withdraw(ctx, userID, currency, amount, actor)
+	return s.notify(ctx, userID, currency, balance, err)
+}
+
+func (s *NotifyingService) Adjust(ctx context.Context, userID, currency string, amount int64, reason, actor string) (int64, error) {
+	balance, err := s.WalletService.Adjust(ctx, userID, currency, amount, reason, actor)
+	return s.notify(ctx, userID, currency, balance, err)
+}
+
+func (s *NotifyingService) notify(ctx context.Context, userID, currency string, balance int64, err error) (int64, error) {
+	if err == nil {
+		s.notifier.NotifyBalance(ctx, userID, currency, balance)
+	}
+	return balance, err
+}
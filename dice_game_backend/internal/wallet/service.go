@@ -6,114 +6,231 @@ import (
 	"fmt"
 	"log"
 
-	"github.com/BrunoSena97/dice_game_backend/internal/constants"
+	"github.com/BrunoSena97/dice_game_backend/internal/platform/database"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// WalletService manages player balances. A player holds one wallet per currency.
 type WalletService interface {
-	GetBalance(ctx context.Context, userID string) (int64, error)
-	UpdateBalance(ctx context.Context, userID string, amountChange int64) (int64, error)
-	EnsureWalletExists(ctx context.Context, userID string) error
+	GetBalance(ctx context.Context, userID, currency string) (int64, error)
+	// GetFunds returns the cash balance and bonus state together, in one cache
+	// or database read.
+	GetFunds(ctx context.Context, userID, currency string) (Funds, error)
+	GetBalances(ctx context.Context, userID string) (map[string]int64, error)
+	UpdateBalance(ctx context.Context, userID, currency string, amountChange int64) (int64, error)
+	EnsureWalletExists(ctx context.Context, userID, currency string) error
+	// SettleRound applies a played round's stake and payout across the cash and
+	// bonus balances and records the round.
+	SettleRound(ctx context.Context, round PlayedRound) (SettledRound, error)
+	// SettleFreeRound applies a round played on a free round grant, consuming one of its rounds.
+	SettleFreeRound(ctx context.Context, round PlayedRound) (SettledRound, error)
+	GetBonus(ctx context.Context, userID, currency string) (Bonus, error)
+
+	// Operator balance changes. Each one writes a ledger entry in the same transaction.
+	Deposit(ctx context.Context, userID, currency string, amount int64, actor string) (int64, error)
+	Withdraw(ctx context.Context, userID, currency string, amount int64, actor string) (int64, error)
+	Adjust(ctx context.Context, userID, currency string, amount int64, reason, actor string) (int64, error)
+	GrantBonus(ctx context.Context, userID, currency string, amount, multiplier int64, reason, actor string) (Bonus, error)
+	GetLedger(ctx context.Context, userID, currency string, limit int) ([]LedgerEntry, error)
 }
 
+// Service stores wallets in Postgres. Balance reads are served from the
+// balance cache when it holds the wallet, then from the read replica when their
+// context allows stale reads (see database.AllowStale); everything else uses
+// the primary.
 type Service struct {
-	dbpool *pgxpool.Pool
+	db              *database.Router
+	dbpool          *pgxpool.Pool
+	initialBalances map[string]int64
+	cache           BalanceCache
+	known           *knownWallets
 }
 
-func NewService(dbpool *pgxpool.Pool) *Service {
-	if dbpool == nil {
-		log.Fatal("WalletService requires a non-nil dbpool")
+// NewService creates a wallet service. initialBalances lists the supported
+// currencies and the balance a new wallet in each currency starts with. cache
+// may be nil to always read balances from Postgres.
+func NewService(db *database.Router, initialBalances map[string]int64, cache BalanceCache) *Service {
+	if db == nil {
+		log.Fatal("WalletService requires a non-nil database router")
 	}
-	return &Service{dbpool: dbpool}
+	if len(initialBalances) == 0 {
+		log.Fatal("WalletService requires at least one currency")
+	}
+	if cache == nil {
+		cache = noCache{}
+	}
+	return &Service{db: db, dbpool: db.Primary(), initialBalances: initialBalances, cache: cache, known: newKnownWallets()}
 }
 
-// EnsureWalletExists creates a wallet if it doesn't exist, using default constants.
-func (s *Service) EnsureWalletExists(ctx context.Context, userID string) error {
+// EnsureWalletExists creates the user's wallet in the given currency if it doesn't exist.
+// Wallets this process has already seen are not checked again.
+func (s *Service) EnsureWalletExists(ctx context.Context, userID, currency string) error {
+	initialBalance, ok := s.initialBalances[currency]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnsupportedCurrency, currency)
+	}
+	if s.known.has(userID, currency) {
+		return nil
+	}
+
 	query := `
 		INSERT INTO wallets (user_id, balance, currency, created_at, updated_at)
 		VALUES ($1, $2, $3, NOW(), NOW())
-		ON CONFLICT (user_id) DO NOTHING;
+		ON CONFLICT (user_id, currency) DO NOTHING;
 	`
-	_, err := s.dbpool.Exec(ctx, query, userID, constants.DefaultInitialBalance, constants.DefaultCurrency)
+	_, err := s.dbpool.Exec(ctx, query, userID, initialBalance, currency)
 	if err != nil {
-		log.Printf("Error ensuring wallet for user %s: %v", userID, err)
-		return fmt.Errorf("failed to ensure wallet for user %s: %w", userID, err)
+		log.Printf("Error ensuring %s wallet for user %s: %v", currency, userID, err)
+		return fmt.Errorf("failed to ensure %s wallet for user %s: %w", currency, userID, err)
 	}
-	log.Printf("Wallet ensured for user %s (created if didn't exist)", userID)
+	s.known.add(userID, currency)
+	log.Printf("Wallet ensured for user %s in %s (created if didn't exist)", userID, currency)
 	return nil
 }
 
-func (s *Service) GetBalance(ctx context.Context, userID string) (int64, error) {
-	query := `SELECT balance FROM wallets WHERE user_id = $1;`
-	var balance int64
+func (s *Service) GetBalance(ctx context.Context, userID, currency string) (int64, error) {
+	funds, err := s.GetFunds(ctx, userID, currency)
+	return funds.Balance, err
+}
 
-	err := s.dbpool.QueryRow(ctx, query, userID).Scan(&balance)
+// GetFunds returns the cash balance and bonus state of a wallet, from the
+// cache when it holds them.
+func (s *Service) GetFunds(ctx context.Context, userID, currency string) (Funds, error) {
+	if funds, ok := s.cache.Get(ctx, userID, currency); ok {
+		return funds, nil
+	}
+
+	query := `
+		SELECT balance, bonus_balance, wagering_required, wagering_progress, version
+		FROM wallets
+		WHERE user_id = $1 AND currency = $2;
+	`
+	var funds Funds
+	var version int64
+	var fromPrimary bool
+
+	err := s.db.Read(ctx, func(db *pgxpool.Pool) error {
+		fromPrimary = db == s.dbpool
+		b := &funds.Bonus
+		return db.QueryRow(ctx, query, userID, currency).
+			Scan(&funds.Balance, &b.Balance, &b.WageringRequired, &b.WageringProgress, &version)
+	})
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			log.Printf("Wallet not found for user %s during GetBalance", userID)
-			return 0, ErrWalletNotFound
+			log.Printf("Wallet not found for user %s in %s during GetBalance", userID, currency)
+			return Funds{}, ErrWalletNotFound
 		}
-		log.Printf("Error getting balance for user %s: %v", userID, err)
-		return 0, fmt.Errorf("database error getting balance for user %s: %w", userID, err)
+		log.Printf("Error getting %s balance for user %s: %v", currency, userID, err)
+		return Funds{}, fmt.Errorf("database error getting balance for user %s: %w", userID, err)
 	}
 
-	return balance, nil
+	s.known.add(userID, currency)
+	// A lagging replica could cache a balance older than one whose write-through
+	// was lost, so only primary reads fill the cache.
+	if fromPrimary {
+		s.cache.Set(ctx, userID, currency, funds, version)
+	}
+	return funds, nil
 }
 
-// UpdateBalance updates the user's balance within a transaction.
-// It returns balance on success.
-func (s *Service) UpdateBalance(ctx context.Context, userID string, amountChange int64) (int64, error) {
-	var newBalance int64
+// GetBalances returns every wallet balance the user holds, keyed by currency.
+func (s *Service) GetBalances(ctx context.Context, userID string) (map[string]int64, error) {
+	query := `SELECT currency, balance FROM wallets WHERE user_id = $1;`
 
-	tx, err := s.dbpool.Begin(ctx)
+	var balances map[string]int64
+	err := s.db.Read(ctx, func(db *pgxpool.Pool) error {
+		rows, err := db.Query(ctx, query, userID)
+		if err != nil {
+			return err
+		}
+		balances = make(map[string]int64)
+		var currency string
+		var balance int64
+		_, err = pgx.ForEachRow(rows, []any{&currency, &balance}, func() error {
+			balances[currency] = balance
+			return nil
+		})
+		if err == nil && len(balances) == 0 {
+			// A replica may not have the first wallet yet; let Read retry on the primary.
+			err = pgx.ErrNoRows
+		}
+		return err
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return map[string]int64{}, nil
+	}
 	if err != nil {
-		log.Printf("Error starting transaction for UpdateBalance (user: %s): %v", userID, err)
-		return 0, fmt.Errorf("failed to start db transaction: %w", err)
+		log.Printf("Error getting balances for user %s: %v", userID, err)
+		return nil, fmt.Errorf("database error getting balances for user %s: %w", userID, err)
 	}
-	defer tx.Rollback(ctx)
 
-	querySelect := `SELECT balance FROM wallets WHERE user_id = $1 FOR UPDATE;`
-	var currentBalance int64
-	err = tx.QueryRow(ctx, querySelect, userID).Scan(&currentBalance)
+	return balances, nil
+}
+
+// UpdateBalance applies amountChange in a single conditional statement and
+// writes the new balance through to the cache. It returns balance on success.
+func (s *Service) UpdateBalance(ctx context.Context, userID, currency string, amountChange int64) (int64, error) {
+	funds, version, err := changeBalance(ctx, s.dbpool, userID, currency, amountChange)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			log.Printf("Wallet not found for user %s during UpdateBalance transaction", userID)
-			return 0, ErrWalletNotFound
+		if errors.Is(err, ErrWalletNotFound) {
+			s.known.forget(userID, currency)
 		}
-		log.Printf("Error selecting balance in transaction (user: %s): %v", userID, err)
-		return 0, fmt.Errorf("db error selecting balance for update: %w", err)
+		return 0, err
 	}
+	s.cache.Set(ctx, userID, currency, funds, version)
 
-	potentialNewBalance := currentBalance + amountChange
-	if potentialNewBalance < 0 {
-		log.Printf("Insufficient funds for user %s (current: %d, change: %d)", userID, currentBalance, amountChange)
-		return 0, ErrInsufficientFunds
-	}
+	log.Printf("User %s %s balance updated by %d to %d", userID, currency, amountChange, funds.Balance)
+	return funds.Balance, nil
+}
 
-	queryUpdate := `
+// querier is satisfied by both the pool and a transaction.
+type querier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// changeBalanceQuery applies a change only if it keeps the balance non-negative,
+// in one round trip. The row lock taken by the UPDATE serialises concurrent
+// changes, and Postgres re-checks the condition against the latest committed
+// balance before applying it. When nothing was updated, the wallet lookup
+// (from the statement's snapshot) tells a missing wallet from a short one.
+const changeBalanceQuery = `
+	WITH updated AS (
 		UPDATE wallets
-		SET balance = $1, updated_at = NOW()
-		WHERE user_id = $2;
-	`
-	cmdTag, err := tx.Exec(ctx, queryUpdate, potentialNewBalance, userID)
+		SET balance = balance + $1, version = version + 1, updated_at = NOW()
+		WHERE user_id = $2 AND currency = $3 AND balance + $1 >= 0
+		RETURNING balance, bonus_balance, wagering_required, wagering_progress, version
+	)
+	SELECT updated.balance, updated.bonus_balance, updated.wagering_required, updated.wagering_progress,
+		updated.version, existing.balance
+	FROM (SELECT 1) AS one
+	LEFT JOIN updated ON TRUE
+	LEFT JOIN wallets AS existing ON existing.user_id = $2 AND existing.currency = $3;
+`
+
+// changeBalance applies amountChange and returns the new funds and wallet
+// version. On the pool it commits on its own; inside a transaction the caller
+// commits.
+func changeBalance(ctx context.Context, db querier, userID, currency string, amountChange int64) (Funds, int64, error) {
+	var newBalance, bonus, required, progress, version, currentBalance *int64
+	err := db.QueryRow(ctx, changeBalanceQuery, amountChange, userID, currency).
+		Scan(&newBalance, &bonus, &required, &progress, &version, &currentBalance)
 	if err != nil {
-		log.Printf("Error updating balance in transaction (user: %s): %v", userID, err)
-		return 0, fmt.Errorf("db error updating balance: %w", err)
-	}
-
-	if cmdTag.RowsAffected() != 1 {
-		log.Printf("Unexpected number of rows affected (%d) during balance update for user %s", cmdTag.RowsAffected(), userID)
-		return 0, ErrUpdateFailed
+		log.Printf("Error updating balance (user: %s): %v", userID, err)
+		return Funds{}, 0, fmt.Errorf("db error updating balance: %w", err)
 	}
 
-	err = tx.Commit(ctx)
-	if err != nil {
-		log.Printf("Error committing transaction for UpdateBalance (user: %s): %v", userID, err)
-		return 0, fmt.Errorf("failed to commit db transaction: %w", err)
+	switch {
+	case newBalance != nil:
+		return Funds{
+			Balance: *newBalance,
+			Bonus:   Bonus{Balance: *bonus, WageringRequired: *required, WageringProgress: *progress},
+		}, *version, nil
+	case currentBalance == nil:
+		log.Printf("Wallet not found for user %s in %s during balance update", userID, currency)
+		return Funds{}, 0, ErrWalletNotFound
+	default:
+		log.Printf("Insufficient %s funds for user %s (current: %d, change: %d)", currency, userID, *currentBalance, amountChange)
+		return Funds{}, 0, ErrInsufficientFunds
 	}
-
-	newBalance = potentialNewBalance
-	log.Printf("User %s balance updated by %d to %d", userID, amountChange, newBalance)
-	return newBalance, nil
 }
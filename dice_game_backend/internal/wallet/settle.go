@@ -0,0 +1,363 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/BrunoSena97/dice_game_backend/internal/constants"
+	"github.com/BrunoSena97/dice_game_backend/internal/limits"
+	"github.com/BrunoSena97/dice_game_backend/internal/rounds"
+	"github.com/jackc/pgx/v5"
+)
+
+// Stake orders decide which balance a stake is drawn from first when a wallet
+// holds both cash and bonus.
+const (
+	StakeCashFirst  = "cash_first"
+	StakeBonusFirst = "bonus_first"
+)
+
+var StakeOrders = []string{StakeCashFirst, StakeBonusFirst}
+
+// settleActor is recorded on ledger entries written by round settlement.
+const settleActor = "system"
+
+// PlayedRound is a round whose result is known but not yet applied to the wallet.
+type PlayedRound struct {
+	// Round.BetAmount is the stake; Round.BonusStake is ignored and set on settling.
+	Round      rounds.Round
+	StakeOrder string
+	// Jackpot is the round's part in its currency's jackpot; zero without one.
+	Jackpot JackpotPlay
+}
+
+// JackpotPlay is what a round does to its currency's jackpot pool. It is
+// applied in the statement that settles the round.
+type JackpotPlay struct {
+	// Contribution is added to the pool.
+	Contribution int64
+	// Wins pays the whole pool, including Contribution, to the player, and
+	// resets the pool to Seed.
+	Wins bool
+	Seed int64
+}
+
+// payout is what the round pays back: the stake and winnings on a win.
+func (p PlayedRound) payout() int64 {
+	if p.Round.Outcome == constants.OutcomeWin {
+		return p.Round.BetAmount + p.Round.Winnings
+	}
+	return 0
+}
+
+// SettledRound is a round applied to its wallet and recorded.
+type SettledRound struct {
+	// Round is the recorded round, with its ID, timestamp and bonus stake set.
+	Round rounds.Round
+	// Balance is the cash balance right after the round.
+	Balance int64
+	// Bonus is the bonus state right after the round.
+	Bonus Bonus
+	// BonusReleased is the bonus this round moved to the cash balance, if it
+	// met the wagering requirement.
+	BonusReleased int64
+	// Version is the wallet version the round committed.
+	Version int64
+	// FreeRoundsRemaining is what is left of the grant a free round was played on.
+	FreeRoundsRemaining int
+	// JackpotWon is the jackpot the round won, already included in Balance.
+	JackpotWon int64
+	// SessionReminder is the player's session reminder interval, zero when
+	// none is set. It is only read when settling paid rounds.
+	SessionReminder time.Duration
+}
+
+// settleRoundQuery applies a played round to its wallet in one statement. The
+// stake is split between cash and bonus in the requested order ($5 is true for
+// bonus first), and the payout is split in the same proportion so winnings on
+// bonus stakes stay bonus. The stake counts towards an open wagering
+// requirement; meeting it releases the bonus to cash, and running out of bonus
+// clears it. The round is recorded and a release is ledgered in the same
+// statement. A wallet that cannot cover the stake is left untouched, and the
+// final lookup tells it from a missing one.
+//
+// The round is first checked against the player's responsible gaming limits,
+// read as of $21 like limits.Service.CheckPlay does: a self-excluded player, a
+// stake above the max stake or one that could take losses since the period
+// starts ($22-$24) past a loss limit leaves the wallet untouched too. The
+// player's standing is returned with the result, so a paid round costs a
+// single round trip. Rounds summed for the loss limits include those settled
+// earlier in the same batch.
+//
+// The round's jackpot contribution ($14) is inserted into the jackpot ledger
+// unpooled, for the jackpot service to roll into the pool, so rounds never
+// queue on the pool row. A round that wins the jackpot ($15) locks the pool,
+// credits its value and the round's own contribution to the cash balance, and
+// resets it to the seed ($16); contributions not yet rolled in fund the next
+// pool.
+const settleRoundQuery = `
+	WITH effective AS (
+		SELECT kind,
+			CASE WHEN pending_value IS NOT NULL AND pending_effective_at <= $21 THEN pending_value ELSE value END AS value
+		FROM player_limits
+		WHERE user_id = $1 AND currency IN ($2, '')
+	), standing AS (
+		SELECT
+			(SELECT excluded_until FROM self_exclusions WHERE user_id = $1 AND excluded_until > $21) AS excluded_until,
+			COALESCE(MAX(value) FILTER (WHERE kind = $25), 0) AS max_stake,
+			COALESCE(MAX(value) FILTER (WHERE kind = $26), 0) AS daily,
+			COALESCE(MAX(value) FILTER (WHERE kind = $27), 0) AS weekly,
+			COALESCE(MAX(value) FILTER (WHERE kind = $28), 0) AS monthly,
+			COALESCE(MAX(value) FILTER (WHERE kind = $29), 0) AS reminder
+		FROM effective
+	), losses AS (
+		SELECT
+			COALESCE(-SUM(net_amount) FILTER (WHERE played_at >= $22), 0) AS daily,
+			COALESCE(-SUM(net_amount) FILTER (WHERE played_at >= $23), 0) AS weekly,
+			COALESCE(-SUM(net_amount) FILTER (WHERE played_at >= $24), 0) AS monthly
+		FROM rounds, standing AS l
+		WHERE (l.daily > 0 OR l.weekly > 0 OR l.monthly > 0)
+			AND user_id = $1 AND currency = $2
+			AND played_at >= LEAST($22::timestamptz, $23::timestamptz, $24::timestamptz)
+	), allowed AS (
+		SELECT l.excluded_until IS NULL
+			AND (l.max_stake = 0 OR $3 <= l.max_stake)
+			AND (l.daily = 0 OR x.daily + $3 <= l.daily)
+			AND (l.weekly = 0 OR x.weekly + $3 <= l.weekly)
+			AND (l.monthly = 0 OR x.monthly + $3 <= l.monthly) AS ok
+		FROM standing AS l, losses AS x
+	), wallet AS (
+		SELECT balance, bonus_balance, wagering_required, wagering_progress,
+			CASE WHEN $5 THEN GREATEST($3 - bonus_balance, 0) ELSE LEAST(balance, $3) END AS cash_stake
+		FROM wallets
+		WHERE user_id = $1 AND currency = $2 AND balance + bonus_balance >= $3 AND (SELECT ok FROM allowed)
+		FOR UPDATE
+	), pool AS (
+		SELECT amount + $14 AS won
+		FROM jackpots
+		WHERE currency = $2 AND $15 AND EXISTS (SELECT 1 FROM wallet)
+		FOR UPDATE
+	), reset AS (
+		UPDATE jackpots SET amount = $16, updated_at = NOW()
+		FROM pool
+		WHERE currency = $2
+	), jackpot AS (
+		SELECT COALESCE((SELECT won FROM pool), 0)::bigint AS won
+	), played AS (
+		SELECT balance - cash_stake + $4 * cash_stake / $3 AS balance,
+			bonus_balance - ($3 - cash_stake) + ($4 - $4 * cash_stake / $3) AS bonus_balance,
+			$3 - cash_stake AS bonus_stake,
+			wagering_required,
+			CASE WHEN wagering_required > 0 THEN wagering_progress + $3 ELSE wagering_progress END AS wagering_progress
+		FROM wallet
+	), resolved AS (
+		SELECT played.*,
+			wagering_required > 0 AND wagering_progress >= wagering_required AS met,
+			wagering_required > 0 AND (bonus_balance = 0 OR wagering_progress >= wagering_required) AS cleared
+		FROM played
+	), updated AS (
+		UPDATE wallets AS w
+		SET balance = r.balance + CASE WHEN r.met THEN r.bonus_balance ELSE 0 END + jackpot.won,
+			bonus_balance = CASE WHEN r.met THEN 0 ELSE r.bonus_balance END,
+			wagering_required = CASE WHEN r.cleared THEN 0 ELSE r.wagering_required END,
+			wagering_progress = CASE WHEN r.cleared THEN 0 ELSE r.wagering_progress END,
+			version = w.version + 1, updated_at = NOW()
+		FROM resolved AS r, jackpot
+		WHERE w.user_id = $1 AND w.currency = $2
+		RETURNING w.balance, w.bonus_balance, w.wagering_required, w.wagering_progress, w.version,
+			r.bonus_stake, CASE WHEN r.met THEN r.bonus_balance ELSE 0 END AS released, jackpot.won
+	), recorded AS (
+		INSERT INTO rounds (user_id, currency, bet_type, bet_amount, bonus_stake, die1, die2, outcome, winnings, net_amount)
+		SELECT $1, $2, $6, $3, bonus_stake, $7, $8, $9, $10, $11 FROM updated
+		RETURNING id, played_at
+	), ledgered AS (
+		INSERT INTO wallet_ledger (user_id, currency, entry_type, amount, balance_after, bonus_after, reason, actor)
+		SELECT $1, $2, e.entry_type, e.amount, balance, bonus_balance, e.reason, $13
+		FROM updated, recorded CROSS JOIN LATERAL (VALUES
+			($12::varchar, released, 'wagering requirement met'),
+			($20, won, 'jackpot won on round ' || recorded.id)
+		) AS e(entry_type, amount, reason)
+		WHERE e.amount > 0
+	), jackpot_ledgered AS (
+		INSERT INTO jackpot_ledger (currency, entry_type, amount, pool_after, user_id, round_id, pooled_at)
+		SELECT $2, e.entry_type, e.amount, e.pool_after, e.user_id, e.round_id, e.pooled_at
+		FROM recorded CROSS JOIN LATERAL (VALUES
+			($17::varchar, $14::bigint, (SELECT won FROM pool), $1::varchar, recorded.id,
+				CASE WHEN EXISTS (SELECT 1 FROM pool) THEN NOW() END),
+			($18, -(SELECT won FROM jackpot), 0, $1, recorded.id, NOW()),
+			($19, CASE WHEN EXISTS (SELECT 1 FROM pool) THEN $16 ELSE 0 END, $16, NULL, NULL, NOW())
+		) AS e(entry_type, amount, pool_after, user_id, round_id, pooled_at)
+		WHERE e.amount <> 0
+	)
+	SELECT updated.balance, updated.bonus_balance, updated.wagering_required, updated.wagering_progress,
+		updated.version, updated.bonus_stake, updated.released, updated.won, recorded.id, recorded.played_at,
+		existing.balance + existing.bonus_balance,
+		standing.excluded_until, standing.max_stake, standing.daily, standing.weekly, standing.monthly,
+		standing.reminder, losses.daily, losses.weekly, losses.monthly
+	FROM standing, losses
+	LEFT JOIN updated ON TRUE
+	LEFT JOIN recorded ON TRUE
+	LEFT JOIN wallets AS existing ON existing.user_id = $1 AND existing.currency = $2;
+`
+
+// QueueSettleRound adds the statement settling p to a batch. Statements of one
+// batch run in order, so several rounds of one wallet see each other's changes.
+// Read each result with ScanSettledRound.
+func QueueSettleRound(b *pgx.Batch, p PlayedRound) {
+	b.Queue(settleRoundQuery, settleRoundArgs(p)...)
+}
+
+func settleRoundArgs(p PlayedRound) []any {
+	r := p.Round
+	now := time.Now()
+	dayStart, weekStart, monthStart := limits.PeriodStarts(now)
+	return []any{r.UserID, r.Currency, r.BetAmount, p.payout(), p.StakeOrder == StakeBonusFirst,
+		r.BetType, r.Die1, r.Die2, r.Outcome, r.Winnings, r.Net(), constants.LedgerEntryBonusRelease, settleActor,
+		p.Jackpot.Contribution, p.Jackpot.Wins, p.Jackpot.Seed,
+		constants.JackpotEntryContribution, constants.JackpotEntryPayout, constants.JackpotEntrySeed, constants.LedgerEntryJackpot,
+		now, dayStart, weekStart, monthStart,
+		limits.KindMaxStake, limits.KindDailyLoss, limits.KindWeeklyLoss, limits.KindMonthlyLoss, limits.KindSessionReminder}
+}
+
+// ScanSettledRound reads the result of settling p. It returns a
+// *limits.BreachError, ErrWalletNotFound or ErrInsufficientFunds when the round
+// was refused; any other error is a database error.
+func ScanSettledRound(row pgx.Row, p PlayedRound) (SettledRound, error) {
+	var balance, bonus, required, progress, version, bonusStake, released, won, id, available *int64
+	var playedAt *time.Time
+	var st limits.Standing
+	err := row.Scan(&balance, &bonus, &required, &progress, &version, &bonusStake, &released, &won, &id, &playedAt, &available,
+		&st.ExcludedUntil, &st.MaxStake, &st.LossLimits[0], &st.LossLimits[1], &st.LossLimits[2], &st.ReminderMinutes,
+		&st.Losses[0], &st.Losses[1], &st.Losses[2])
+	if err != nil {
+		return SettledRound{}, fmt.Errorf("db error settling round: %w", err)
+	}
+
+	r := p.Round
+	reminder, breach := st.Check(r.BetAmount)
+	switch {
+	case balance != nil:
+		r.ID, r.PlayedAt, r.BonusStake = *id, *playedAt, *bonusStake
+		return SettledRound{
+			Round:           r,
+			Balance:         *balance,
+			Bonus:           Bonus{Balance: *bonus, WageringRequired: *required, WageringProgress: *progress},
+			BonusReleased:   *released,
+			Version:         *version,
+			JackpotWon:      *won,
+			SessionReminder: reminder,
+		}, nil
+	case breach != nil:
+		return SettledRound{}, breach
+	case available == nil:
+		log.Printf("Wallet not found for user %s in %s during round settlement", r.UserID, r.Currency)
+		return SettledRound{}, ErrWalletNotFound
+	default:
+		log.Printf("Insufficient %s funds for user %s (cash and bonus: %d, stake: %d)", r.Currency, r.UserID, *available, r.BetAmount)
+		return SettledRound{}, ErrInsufficientFunds
+	}
+}
+
+// IsRefused reports whether err, from settling a round, means the round was
+// refused and nothing was charged or recorded, rather than a database error.
+func IsRefused(err error) bool {
+	var breach *limits.BreachError
+	return errors.Is(err, ErrWalletNotFound) || errors.Is(err, ErrInsufficientFunds) || errors.As(err, &breach)
+}
+
+// SettleRound charges the stake, pays out and records a played round in one
+// statement, then writes the new balance through to the cache.
+func (s *Service) SettleRound(ctx context.Context, p PlayedRound) (SettledRound, error) {
+	userID, currency := p.Round.UserID, p.Round.Currency
+	settled, err := ScanSettledRound(s.dbpool.QueryRow(ctx, settleRoundQuery, settleRoundArgs(p)...), p)
+	if err != nil {
+		if errors.Is(err, ErrWalletNotFound) {
+			s.known.forget(userID, currency)
+		} else if !IsRefused(err) {
+			log.Printf("Error settling round (user: %s): %v", userID, err)
+		}
+		return SettledRound{}, err
+	}
+	s.cache.Set(ctx, userID, currency, Funds{Balance: settled.Balance, Bonus: settled.Bonus}, settled.Version)
+
+	log.Printf("User %s %s round settled: stake %d (bonus %d), balance %d, bonus %d, wagering %d/%d",
+		userID, currency, p.Round.BetAmount, settled.Round.BonusStake, settled.Balance,
+		settled.Bonus.Balance, settled.Bonus.WageringProgress, settled.Bonus.WageringRequired)
+	if settled.BonusReleased > 0 {
+		log.Printf("LEDGER: %s of %d %s for user %s, new balance %d",
+			constants.LedgerEntryBonusRelease, settled.BonusReleased, currency, userID, settled.Balance)
+	}
+	if settled.JackpotWon > 0 {
+		log.Printf("LEDGER: %s of %d %s for user %s on round %d, new balance %d",
+			constants.LedgerEntryJackpot, settled.JackpotWon, currency, userID, settled.Round.ID, settled.Balance)
+	}
+	return settled, nil
+}
+
+// settleFreeRoundQuery consumes one round of a grant and applies the round in
+// one statement. The grant must belong to the player, match the wallet's
+// currency and still be active; concurrent plays of one grant queue on its row
+// and re-check rounds_remaining, so it can never be overspent. The stake is
+// not charged: winnings are credited to the cash balance and ledgered as
+// promo, and the round is recorded against the grant.
+const settleFreeRoundQuery = `
+	WITH consumed AS (
+		UPDATE free_round_grants
+		SET rounds_remaining = rounds_remaining - 1, updated_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND currency = $3
+			AND rounds_remaining > 0 AND revoked_at IS NULL AND expires_at > NOW()
+			AND EXISTS (SELECT 1 FROM wallets WHERE user_id = $2 AND currency = $3)
+		RETURNING rounds_remaining, stake, bet_type
+	), updated AS (
+		UPDATE wallets
+		SET balance = balance + $4, version = version + 1, updated_at = NOW()
+		FROM consumed
+		WHERE user_id = $2 AND currency = $3
+		RETURNING balance, bonus_balance, wagering_required, wagering_progress, version
+	), recorded AS (
+		INSERT INTO rounds (user_id, currency, bet_type, bet_amount, free_round_grant_id, die1, die2, outcome, winnings, net_amount)
+		SELECT $2, $3, consumed.bet_type, consumed.stake, $1, $5, $6, $7, $8, $4 FROM consumed, updated
+		RETURNING id, played_at
+	), ledgered AS (
+		INSERT INTO wallet_ledger (user_id, currency, entry_type, amount, balance_after, bonus_after, reason, actor)
+		SELECT $2, $3, $9, $4, balance, bonus_balance, 'free round grant ' || $1, $10
+		FROM updated WHERE $4 > 0
+	)
+	SELECT consumed.rounds_remaining, updated.balance, updated.bonus_balance, updated.wagering_required,
+		updated.wagering_progress, updated.version, recorded.id, recorded.played_at
+	FROM consumed, updated, recorded;
+`
+
+// SettleFreeRound applies a round played on the free round grant
+// p.Round.FreeRoundGrantID. The round's stake and bet type must be the
+// grant's. It returns ErrFreeRoundUnavailable, without recording anything,
+// when the grant cannot be played.
+func (s *Service) SettleFreeRound(ctx context.Context, p PlayedRound) (SettledRound, error) {
+	r := p.Round
+	if r.FreeRoundGrantID == 0 {
+		return SettledRound{}, fmt.Errorf("%w: no grant given", ErrFreeRoundUnavailable)
+	}
+
+	settled := SettledRound{Round: r}
+	b := &settled.Bonus
+	err := s.dbpool.QueryRow(ctx, settleFreeRoundQuery, r.FreeRoundGrantID, r.UserID, r.Currency, r.Net(),
+		r.Die1, r.Die2, r.Outcome, r.Winnings, constants.LedgerEntryPromo, settleActor).
+		Scan(&settled.FreeRoundsRemaining, &settled.Balance, &b.Balance, &b.WageringRequired, &b.WageringProgress,
+			&settled.Version, &settled.Round.ID, &settled.Round.PlayedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		log.Printf("Free round grant %d unavailable for user %s in %s", r.FreeRoundGrantID, r.UserID, r.Currency)
+		return SettledRound{}, ErrFreeRoundUnavailable
+	}
+	if err != nil {
+		log.Printf("Error settling free round (user: %s, grant: %d): %v", r.UserID, r.FreeRoundGrantID, err)
+		return SettledRound{}, fmt.Errorf("db error settling free round: %w", err)
+	}
+	s.cache.Set(ctx, r.UserID, r.Currency, Funds{Balance: settled.Balance, Bonus: settled.Bonus}, settled.Version)
+
+	log.Printf("User %s %s free round settled on grant %d (%d left): winnings %d, balance %d",
+		r.UserID, r.Currency, r.FreeRoundGrantID, settled.FreeRoundsRemaining, r.Winnings, settled.Balance)
+	return settled, nil
+}
@@ -0,0 +1,221 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/BrunoSena97/dice_game_backend/internal/constants"
+	"github.com/BrunoSena97/dice_game_backend/internal/limits"
+	"github.com/BrunoSena97/dice_game_backend/internal/platform/database"
+	"github.com/BrunoSena97/dice_game_backend/internal/rounds"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// walletState is the part of a wallet round settlement reads and changes.
+type walletState struct {
+	balance, bonus, required, progress int64
+}
+
+func setWallet(t *testing.T, pool *pgxpool.Pool, userID string, w walletState) {
+	t.Helper()
+	_, err := pool.Exec(context.Background(), `
+		INSERT INTO wallets (user_id, currency, balance, bonus_balance, wagering_required, wagering_progress)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_id, currency) DO UPDATE
+		SET balance = EXCLUDED.balance, bonus_balance = EXCLUDED.bonus_balance,
+			wagering_required = EXCLUDED.wagering_required, wagering_progress = EXCLUDED.wagering_progress;
+	`, userID, benchCurrency, w.balance, w.bonus, w.required, w.progress)
+	if err != nil {
+		t.Fatalf("failed to prepare wallet %s: %v", userID, err)
+	}
+}
+
+func playedRound(userID string, stake, winnings int64, stakeOrder string) PlayedRound {
+	r := rounds.Round{UserID: userID, Currency: benchCurrency, BetType: constants.BetTypeGt7, BetAmount: stake}
+	if winnings > 0 {
+		r.Die1, r.Die2, r.Outcome, r.Winnings = 6, 5, constants.OutcomeWin, winnings
+	} else {
+		r.Die1, r.Die2, r.Outcome = 1, 2, constants.OutcomeLose
+	}
+	return PlayedRound{Round: r, StakeOrder: stakeOrder}
+}
+
+func TestSettleRoundBonus(t *testing.T) {
+	pool := benchPool(t)
+	svc := NewService(database.NewRouter(pool, nil, 0), map[string]int64{benchCurrency: benchBalance}, nil)
+
+	cases := []struct {
+		name       string
+		before     walletState
+		stake      int64
+		winnings   int64
+		stakeOrder string
+		after      walletState
+		bonusStake int64
+		released   int64
+	}{
+		{
+			name:   "cash first stakes cash before bonus",
+			before: walletState{balance: 100, bonus: 30}, stake: 50, stakeOrder: StakeCashFirst,
+			after: walletState{balance: 50, bonus: 30}, bonusStake: 0,
+		},
+		{
+			name:   "cash first stakes bonus once cash runs out",
+			before: walletState{balance: 20, bonus: 30}, stake: 40, stakeOrder: StakeCashFirst,
+			after: walletState{balance: 0, bonus: 10}, bonusStake: 20,
+		},
+		{
+			name:   "bonus first stakes bonus before cash",
+			before: walletState{balance: 100, bonus: 30}, stake: 50, stakeOrder: StakeBonusFirst,
+			after: walletState{balance: 80, bonus: 0}, bonusStake: 30,
+		},
+		{
+			name:   "winnings are split like the stake",
+			before: walletState{balance: 20, bonus: 30}, stake: 40, winnings: 40, stakeOrder: StakeCashFirst,
+			after: walletState{balance: 40, bonus: 50}, bonusStake: 20,
+		},
+		{
+			name:   "stakes count towards wagering",
+			before: walletState{balance: 1000, bonus: 100, required: 300, progress: 100}, stake: 50, stakeOrder: StakeCashFirst,
+			after: walletState{balance: 950, bonus: 100, required: 300, progress: 150},
+		},
+		{
+			name:   "bonus stakes count towards wagering",
+			before: walletState{balance: 1000, bonus: 100, required: 300, progress: 100}, stake: 50, stakeOrder: StakeBonusFirst,
+			after: walletState{balance: 1000, bonus: 50, required: 300, progress: 150}, bonusStake: 50,
+		},
+		{
+			name:   "no wagering progress without a requirement",
+			before: walletState{balance: 1000, bonus: 100}, stake: 50, stakeOrder: StakeCashFirst,
+			after: walletState{balance: 950, bonus: 100},
+		},
+		{
+			name:   "meeting the requirement releases the bonus to cash",
+			before: walletState{balance: 1000, bonus: 100, required: 300, progress: 280}, stake: 50, stakeOrder: StakeCashFirst,
+			after: walletState{balance: 1050}, released: 100,
+		},
+		{
+			name:   "a winning bonus stake releases its winnings too",
+			before: walletState{balance: 0, bonus: 100, required: 300, progress: 280}, stake: 50, winnings: 50, stakeOrder: StakeBonusFirst,
+			after: walletState{balance: 150}, bonusStake: 50, released: 150,
+		},
+		{
+			name:   "losing the whole bonus clears the requirement",
+			before: walletState{balance: 1000, bonus: 30, required: 300, progress: 100}, stake: 30, stakeOrder: StakeBonusFirst,
+			after: walletState{balance: 1000}, bonusStake: 30,
+		},
+	}
+	for i, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ctx := context.Background()
+			userID := fmt.Sprintf("settle_bonus_%d", i)
+			setWallet(t, pool, userID, c.before)
+
+			settled, err := svc.SettleRound(ctx, playedRound(userID, c.stake, c.winnings, c.stakeOrder))
+			if err != nil {
+				t.Fatalf("SettleRound: %v", err)
+			}
+			got := walletState{settled.Balance, settled.Bonus.Balance, settled.Bonus.WageringRequired, settled.Bonus.WageringProgress}
+			if got != c.after {
+				t.Errorf("wallet after the round = %+v, want %+v", got, c.after)
+			}
+			if settled.Round.BonusStake != c.bonusStake {
+				t.Errorf("bonus stake = %d, want %d", settled.Round.BonusStake, c.bonusStake)
+			}
+			if settled.BonusReleased != c.released {
+				t.Errorf("bonus released = %d, want %d", settled.BonusReleased, c.released)
+			}
+
+			var ledgered int64
+			err = pool.QueryRow(ctx, `
+				SELECT COALESCE(SUM(amount), 0) FROM wallet_ledger
+				WHERE user_id = $1 AND currency = $2 AND entry_type = $3 AND created_at >= $4;
+			`, userID, benchCurrency, constants.LedgerEntryBonusRelease, settled.Round.PlayedAt).Scan(&ledgered)
+			if err != nil {
+				t.Fatalf("failed to read ledger: %v", err)
+			}
+			if ledgered != c.released {
+				t.Errorf("ledgered release = %d, want %d", ledgered, c.released)
+			}
+		})
+	}
+}
+
+func TestSettleRoundLimits(t *testing.T) {
+	pool := benchPool(t)
+	svc := NewService(database.NewRouter(pool, nil, 0), map[string]int64{benchCurrency: benchBalance}, nil)
+	ctx := context.Background()
+	// Losses are summed over every round the player has played, so each run
+	// needs players of its own.
+	run := time.Now().UnixNano()
+
+	setLimit := func(userID, kind, currency string, value int64) {
+		t.Helper()
+		_, err := pool.Exec(ctx, `INSERT INTO player_limits (user_id, kind, currency, value) VALUES ($1, $2, $3, $4);`,
+			userID, kind, currency, value)
+		if err != nil {
+			t.Fatalf("failed to set %s limit: %v", kind, err)
+		}
+	}
+
+	t.Run("max stake", func(t *testing.T) {
+		userID := fmt.Sprintf("settle_limits_stake_%d", run)
+		setWallet(t, pool, userID, walletState{balance: 1000})
+		setLimit(userID, limits.KindMaxStake, benchCurrency, 50)
+		setLimit(userID, limits.KindSessionReminder, "", 30)
+
+		settled, err := svc.SettleRound(ctx, playedRound(userID, 50, 0, StakeCashFirst))
+		if err != nil {
+			t.Fatalf("SettleRound at the max stake: %v", err)
+		}
+		if settled.SessionReminder != 30*time.Minute {
+			t.Errorf("session reminder = %v, want 30m", settled.SessionReminder)
+		}
+		_, err = svc.SettleRound(ctx, playedRound(userID, 51, 0, StakeCashFirst))
+		if !errors.Is(err, limits.ErrStakeLimitExceeded) {
+			t.Fatalf("SettleRound above the max stake: err = %v, want %v", err, limits.ErrStakeLimitExceeded)
+		}
+	})
+
+	t.Run("loss limit counts earlier rounds", func(t *testing.T) {
+		userID := fmt.Sprintf("settle_limits_loss_%d", run)
+		setWallet(t, pool, userID, walletState{balance: 1000})
+		setLimit(userID, limits.KindDailyLoss, benchCurrency, 100)
+
+		if _, err := svc.SettleRound(ctx, playedRound(userID, 60, 0, StakeCashFirst)); err != nil {
+			t.Fatalf("first round: %v", err)
+		}
+		_, err := svc.SettleRound(ctx, playedRound(userID, 50, 0, StakeCashFirst))
+		var breach *limits.BreachError
+		if !errors.As(err, &breach) || breach.Kind != limits.KindDailyLoss {
+			t.Fatalf("round past the daily loss limit: err = %v, want a %s breach", err, limits.KindDailyLoss)
+		}
+		if _, err := svc.SettleRound(ctx, playedRound(userID, 40, 0, StakeCashFirst)); err != nil {
+			t.Fatalf("round up to the daily loss limit: %v", err)
+		}
+	})
+
+	t.Run("self-exclusion leaves the wallet untouched", func(t *testing.T) {
+		userID := fmt.Sprintf("settle_limits_excluded_%d", run)
+		setWallet(t, pool, userID, walletState{balance: 1000})
+		_, err := pool.Exec(ctx, `INSERT INTO self_exclusions (user_id, excluded_until) VALUES ($1, NOW() + INTERVAL '1 day');`, userID)
+		if err != nil {
+			t.Fatalf("failed to self-exclude: %v", err)
+		}
+
+		_, err = svc.SettleRound(ctx, playedRound(userID, 10, 0, StakeCashFirst))
+		if !errors.Is(err, limits.ErrSelfExcluded) {
+			t.Fatalf("SettleRound while self-excluded: err = %v, want %v", err, limits.ErrSelfExcluded)
+		}
+		var balance int64
+		if err := pool.QueryRow(ctx, `SELECT balance FROM wallets WHERE user_id = $1 AND currency = $2;`, userID, benchCurrency).Scan(&balance); err != nil {
+			t.Fatalf("failed to read wallet: %v", err)
+		}
+		if balance != 1000 {
+			t.Errorf("balance = %d, want 1000", balance)
+		}
+	})
+}
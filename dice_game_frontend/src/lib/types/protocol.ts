@@ -0,0 +1,339 @@
+// Code generated by cmd/protogen. DO NOT EDIT.
+// Source of truth: dice_game_backend/internal/handler.
+
+export const PROTOCOL_VERSION = 1;
+
+export const MessageType = {
+	Hello: 'hello',
+	Play: 'play',
+	GetBalance: 'get_balance',
+	EndPlay: 'end_play',
+	AutoplayStart: 'autoplay_start',
+	AutoplayStop: 'autoplay_stop',
+	GetLeaderboard: 'get_leaderboard',
+	GetLimits: 'get_limits',
+	SetLimit: 'set_limit',
+	SelfExclude: 'self_exclude',
+	SubscribeFeed: 'subscribe_feed',
+	UnsubscribeFeed: 'unsubscribe_feed',
+	GetFreeRounds: 'get_free_rounds',
+	HelloAck: 'hello_ack',
+	PlayResult: 'play_result',
+	BalanceUpdate: 'balance_update',
+	PlayEnded: 'play_ended',
+	AutoplayStarted: 'autoplay_started',
+	AutoplayStopped: 'autoplay_stopped',
+	Leaderboard: 'leaderboard',
+	Limits: 'limits',
+	SessionReminder: 'session_reminder',
+	FeedSnapshot: 'feed_snapshot',
+	FeedRound: 'feed_round',
+	FreeRounds: 'free_rounds',
+	JackpotUpdate: 'jackpot_update',
+	Error: 'error',
+} as const;
+
+export type MessageType = (typeof MessageType)[keyof typeof MessageType];
+
+export type BetType = 'lt7' | 'gt7';
+
+export type Outcome = 'win' | 'lose';
+
+export type AutoplayStrategy = 'flat' | 'martingale' | 'dalembert';
+
+export type AutoplayStopReason = 'completed' | 'stopped' | 'stop_on_win' | 'stop_on_loss' | 'error';
+
+export type LeaderboardWindow = 'daily' | 'weekly' | 'all_time';
+
+export type LeaderboardMetric = 'net' | 'biggest_win' | 'rounds';
+
+export type LimitKind = 'daily_loss' | 'weekly_loss' | 'monthly_loss' | 'max_stake' | 'session_reminder';
+
+export type ErrorCode =
+	| 'BAD_REQUEST'
+	| 'INTERNAL_ERROR'
+	| 'ACTIVE_PLAY_EXISTS'
+	| 'INVALID_BET'
+	| 'BET_TOO_LOW'
+	| 'BET_TOO_HIGH'
+	| 'INVALID_BET_TYPE'
+	| 'INSUFFICIENT_FUNDS'
+	| 'WALLET_NOT_FOUND'
+	| 'UNKNOWN_TYPE'
+	| 'FAILED_LOCK_RELEASE'
+	| 'UNSUPPORTED_VERSION'
+	| 'INVALID_CURRENCY'
+	| 'FEED_DROPPED'
+	| 'SELF_EXCLUDED'
+	| 'STAKE_LIMIT_EXCEEDED'
+	| 'LOSS_LIMIT_REACHED'
+	| 'INVALID_LIMIT'
+	| 'AUTOPLAY_ACTIVE'
+	| 'INVALID_AUTOPLAY'
+	| 'RATE_LIMITED'
+	| 'FREE_ROUND_UNAVAILABLE'
+	| 'SHUTTING_DOWN'
+	| 'HELLO_REQUIRED';
+
+export interface HelloPayload {
+	clientId: string;
+	protocolVersion: number;
+}
+
+export interface PlayPayload {
+	clientId: string;
+	betAmount: number;
+	betType: BetType;
+	currency?: string;
+	freeRoundGrantId?: number;
+}
+
+export interface GetBalancePayload {
+	clientId: string;
+	currency?: string;
+}
+
+export interface EndPlayPayload {
+	clientId: string;
+}
+
+export interface AutoplayStartPayload {
+	clientId: string;
+	betAmount: number;
+	betType: BetType;
+	currency?: string;
+	rounds: number;
+	strategy?: AutoplayStrategy;
+	maxBetAmount?: number;
+	stopOnWin?: number;
+	stopOnLoss?: number;
+}
+
+export interface AutoplayStopPayload {
+	clientId: string;
+}
+
+export interface GetLeaderboardPayload {
+	clientId: string;
+	window?: LeaderboardWindow;
+	metric?: LeaderboardMetric;
+	currency?: string;
+	limit?: number;
+}
+
+export interface GetLimitsPayload {
+	clientId: string;
+}
+
+export interface SetLimitPayload {
+	clientId: string;
+	kind: LimitKind;
+	currency?: string;
+	value: number;
+}
+
+export interface SelfExcludePayload {
+	clientId: string;
+	days: number;
+}
+
+export interface SubscribeFeedPayload {
+	clientId: string;
+	bigWinsOnly?: boolean;
+}
+
+export interface UnsubscribeFeedPayload {
+	clientId: string;
+}
+
+export interface GetFreeRoundsPayload {
+	clientId: string;
+}
+
+export interface HelloAckPayload {
+	clientId: string;
+	protocolVersion: number;
+	supportedVersions: number[];
+	betTypes: BetType[];
+	maxBetAmount: number;
+	currency: string;
+	currencies: CurrencyInfo[];
+	features: string[];
+}
+
+export interface CurrencyInfo {
+	code: string;
+	minBetAmount: number;
+	maxBetAmount: number;
+}
+
+export interface PlayResultPayload {
+	clientId: string;
+	die1: number;
+	die2: number;
+	outcome: Outcome;
+	betAmount: number;
+	winnings: number;
+	currency: string;
+	freeRoundGrantId?: number;
+	freeRoundsRemaining?: number | null;
+	jackpotWin?: number;
+}
+
+export interface BalanceUpdatePayload {
+	clientId: string;
+	balance: number;
+	currency: string;
+	bonus?: BonusPayload | null;
+}
+
+export interface BonusPayload {
+	balance: number;
+	wageringRequired: number;
+	wageringProgress: number;
+}
+
+export interface PlayEndedPayload {
+	clientId: string;
+	finalBalance: number;
+	currency: string;
+	balances?: Record<string, number>;
+}
+
+export interface AutoplayStartedPayload {
+	clientId: string;
+	rounds: number;
+	strategy: AutoplayStrategy;
+	maxBetAmount: number;
+	currency: string;
+}
+
+export interface AutoplayStoppedPayload {
+	clientId: string;
+	reason: AutoplayStopReason;
+	roundsPlayed: number;
+	net: number;
+	currency: string;
+}
+
+export interface LeaderboardPayload {
+	clientId: string;
+	window: LeaderboardWindow;
+	metric: LeaderboardMetric;
+	currency: string;
+	period: string;
+	entries: LeaderboardEntryPayload[];
+	self?: LeaderboardEntryPayload | null;
+}
+
+export interface LeaderboardEntryPayload {
+	rank: number;
+	player: string;
+	score: number;
+}
+
+export interface LimitsPayload {
+	clientId: string;
+	limits: LimitPayload[];
+	selfExcludedUntil?: string | null;
+}
+
+export interface LimitPayload {
+	kind: LimitKind;
+	currency?: string;
+	value: number;
+	pendingValue?: number | null;
+	pendingEffectiveAt?: string | null;
+}
+
+export interface SessionReminderPayload {
+	clientId: string;
+	sessionMinutes: number;
+}
+
+export interface FeedSnapshotPayload {
+	rounds: FeedRoundPayload[];
+}
+
+export interface FeedRoundPayload {
+	player: string;
+	betType: BetType;
+	betAmount: number;
+	currency: string;
+	die1: number;
+	die2: number;
+	outcome: Outcome;
+	winnings: number;
+	bigWin: boolean;
+	playedAt: string;
+}
+
+export interface FreeRoundsPayload {
+	clientId: string;
+	grants: FreeRoundGrantPayload[];
+}
+
+export interface FreeRoundGrantPayload {
+	id: number;
+	currency: string;
+	betType: BetType;
+	stake: number;
+	roundsGranted: number;
+	roundsRemaining: number;
+	expiresAt: string;
+}
+
+export interface JackpotUpdatePayload {
+	pools: JackpotPoolPayload[];
+}
+
+export interface JackpotPoolPayload {
+	currency: string;
+	amount: number;
+}
+
+export interface ErrorPayload {
+	code: ErrorCode;
+	message: string;
+}
+
+export interface BaseWsMessage {
+	type: string;
+	payload: unknown;
+}
+
+export interface BaseServerMessage {
+	type: string;
+	payload: unknown;
+}
+
+export type ClientMessage =
+	| { type: 'autoplay_start'; payload: AutoplayStartPayload }
+	| { type: 'autoplay_stop'; payload: AutoplayStopPayload }
+	| { type: 'end_play'; payload: EndPlayPayload }
+	| { type: 'get_balance'; payload: GetBalancePayload }
+	| { type: 'get_free_rounds'; payload: GetFreeRoundsPayload }
+	| { type: 'get_leaderboard'; payload: GetLeaderboardPayload }
+	| { type: 'get_limits'; payload: GetLimitsPayload }
+	| { type: 'hello'; payload: HelloPayload }
+	| { type: 'play'; payload: PlayPayload }
+	| { type: 'self_exclude'; payload: SelfExcludePayload }
+	| { type: 'set_limit'; payload: SetLimitPayload }
+	| { type: 'subscribe_feed'; payload: SubscribeFeedPayload }
+	| { type: 'unsubscribe_feed'; payload: UnsubscribeFeedPayload };
+
+export type ServerMessage =
+	| { type: 'autoplay_started'; payload: AutoplayStartedPayload }
+	| { type: 'autoplay_stopped'; payload: AutoplayStoppedPayload }
+	| { type: 'balance_update'; payload: BalanceUpdatePayload }
+	| { type: 'error'; payload: ErrorPayload }
+	| { type: 'feed_round'; payload: FeedRoundPayload }
+	| { type: 'feed_snapshot'; payload: FeedSnapshotPayload }
+	| { type: 'free_rounds'; payload: FreeRoundsPayload }
+	| { type: 'hello_ack'; payload: HelloAckPayload }
+	| { type: 'jackpot_update'; payload: JackpotUpdatePayload }
+	| { type: 'leaderboard'; payload: LeaderboardPayload }
+	| { type: 'limits'; payload: LimitsPayload }
+	| { type: 'play_ended'; payload: PlayEndedPayload }
+	| { type: 'play_result'; payload: PlayResultPayload }
+	| { type: 'session_reminder'; payload: SessionReminderPayload };